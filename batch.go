@@ -0,0 +1,82 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+)
+
+// BatchConfig controls how GenerateBatch handles failing items
+type BatchConfig struct {
+	// ContinueOnError keeps going after a failure and reports it instead of stopping
+	ContinueOnError bool
+	// MaxFailures aborts a ContinueOnError run once more items than this failed, 0 for no limit
+	MaxFailures int
+}
+
+// BatchError is an item of a batch that could not be generated
+type BatchError struct {
+	Index int
+	Input []byte
+	Err   error
+}
+
+func (e BatchError) Error() string {
+	return fmt.Sprintf("wavatar: batch item %d (%q): %v", e.Index, e.Input, e.Err)
+}
+
+func (e BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchReport lists the items of a batch that failed, in input order
+type BatchReport struct {
+	Failed []BatchError
+}
+
+// GenerateBatch creates an avatar for every input with the default
+// Generator, applying the given options after its own. The images are
+// returned in input order, with nil for items that failed.
+//
+// By default the first failure stops the batch and is returned as a
+// BatchError. With ContinueOnError failures are collected in the report
+// instead, and an error is only returned when MaxFailures is exceeded.
+func GenerateBatch(inputs [][]byte, cfg BatchConfig, opts ...Option) ([]image.Image, BatchReport, error) {
+	return Default().GenerateBatch(inputs, cfg, opts...)
+}
+
+// GenerateBatch creates an avatar for every input, applying the given options
+func (g *Generator) GenerateBatch(inputs [][]byte, cfg BatchConfig, opts ...Option) ([]image.Image, BatchReport, error) {
+	o, err := g.options(opts)
+	if err != nil {
+		return nil, BatchReport{}, err
+	}
+	return generateBatch(inputs, cfg, o)
+}
+
+// generateBatch renders every input with o according to cfg
+func generateBatch(inputs [][]byte, cfg BatchConfig, o *options) ([]image.Image, BatchReport, error) {
+	if cfg.MaxFailures < 0 {
+		return nil, BatchReport{}, fmt.Errorf("wavatar: max failures %d must not be negative", cfg.MaxFailures)
+	}
+
+	var report BatchReport
+	images := make([]image.Image, len(inputs))
+	for i, input := range inputs {
+		img, err := generateHash(input, o)
+		if err == nil {
+			images[i] = img
+			continue
+		}
+
+		failure := BatchError{Index: i, Input: input, Err: err}
+		if !cfg.ContinueOnError {
+			return images, report, failure
+		}
+		report.Failed = append(report.Failed, failure)
+		if cfg.MaxFailures > 0 && len(report.Failed) > cfg.MaxFailures {
+			return images, report, fmt.Errorf("wavatar: batch aborted after %d failures, last: %w", len(report.Failed), failure)
+		}
+	}
+
+	return images, report, nil
+}
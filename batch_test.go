@@ -0,0 +1,128 @@
+package wavatar
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// faultyGenerator returns a Generator whose mouth3 part is corrupt
+func faultyGenerator(t *testing.T) *Generator {
+	t.Helper()
+
+	pack := fstest.MapFS{}
+	err := fs.WalkDir(os.DirFS("parts"), ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(os.DirFS("parts"), path)
+		pack[path] = &fstest.MapFile{Data: data}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Failed to copy parts: %v", err)
+	}
+	pack["mouth3.png"] = &fstest.MapFile{Data: []byte("corrupt")}
+
+	g, err := NewGenerator(pack)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	return g
+}
+
+// batchInputs returns n inputs and the indices of those that use mouth3
+func batchInputs(n int) (inputs [][]byte, faulty []int) {
+	for i := 0; i < n; i++ {
+		input := []byte(fmt.Sprintf("user%d@example.com", i))
+		if Describe(input).Mouth == 3 {
+			faulty = append(faulty, i)
+		}
+		inputs = append(inputs, input)
+	}
+	return inputs, faulty
+}
+
+func TestGenerateBatchContinueOnError(t *testing.T) {
+	g := faultyGenerator(t)
+	inputs, faulty := batchInputs(100)
+	if len(faulty) < 2 {
+		t.Fatalf("Expected several inputs using mouth3, got %d", len(faulty))
+	}
+
+	images, report, err := g.GenerateBatch(inputs, BatchConfig{ContinueOnError: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if len(report.Failed) != len(faulty) {
+		t.Fatalf("Expected %d failures, got %d", len(faulty), len(report.Failed))
+	}
+	for i, failure := range report.Failed {
+		if failure.Index != faulty[i] || string(failure.Input) != string(inputs[faulty[i]]) {
+			t.Errorf("Expected failure %d at index %d, got index %d (%s)", i, faulty[i], failure.Index, failure.Input)
+		}
+		if failure.Err == nil {
+			t.Errorf("Expected failure %d to wrap the render error", i)
+		}
+		if images[failure.Index] != nil {
+			t.Errorf("Expected no image for failed index %d", failure.Index)
+		}
+	}
+	if succeeded := len(inputs) - len(faulty); countImages(images) != succeeded {
+		t.Errorf("Expected %d images, got %d", succeeded, countImages(images))
+	}
+}
+
+// countImages returns the number of non-nil images
+func countImages(images []image.Image) int {
+	n := 0
+	for _, img := range images {
+		if img != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func TestGenerateBatchThreshold(t *testing.T) {
+	g := faultyGenerator(t)
+	inputs, faulty := batchInputs(100)
+
+	_, report, err := g.GenerateBatch(inputs, BatchConfig{ContinueOnError: true, MaxFailures: 1})
+	if err == nil {
+		t.Fatal("Expected the batch to abort")
+	}
+	if len(report.Failed) != 2 {
+		t.Errorf("Expected the abort on the second failure, got %d failures", len(report.Failed))
+	}
+	var failure BatchError
+	if !errors.As(err, &failure) || failure.Index != faulty[1] {
+		t.Errorf("Expected the abort to name index %d, got %v", faulty[1], err)
+	}
+}
+
+func TestGenerateBatchStopsOnError(t *testing.T) {
+	g := faultyGenerator(t)
+	inputs, faulty := batchInputs(100)
+
+	images, report, err := g.GenerateBatch(inputs, BatchConfig{})
+	var failure BatchError
+	if !errors.As(err, &failure) || failure.Index != faulty[0] {
+		t.Fatalf("Expected a BatchError at index %d, got %v", faulty[0], err)
+	}
+	if len(report.Failed) != 0 {
+		t.Errorf("Expected an empty report, got %d failures", len(report.Failed))
+	}
+	if countImages(images) != faulty[0] {
+		t.Errorf("Expected %d images before the failure, got %d", faulty[0], countImages(images))
+	}
+
+	if _, _, err := GenerateBatch(inputs[:5], BatchConfig{}); err != nil {
+		t.Errorf("Expected the embedded parts to render, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// specBinarySize is the length of the MarshalBinary encoding of a Spec
+const specBinarySize = 5
+
+// specBits lists the fields of a Spec in encoding order with their bit widths
+func specBits(s *Spec) []struct {
+	field *int
+	bits  uint
+} {
+	return []struct {
+		field *int
+		bits  uint
+	}{
+		{&s.Face, 4},
+		{&s.Background, 8},
+		{&s.Fade, 2},
+		{&s.WaveColor, 8},
+		{&s.Brow, 3},
+		{&s.Eyes, 4},
+		{&s.Pupil, 4},
+		{&s.Mouth, 5},
+	}
+}
+
+// MarshalBinary packs s into a compact fixed-width token suitable for URLs and QR codes
+func (s Spec) MarshalBinary() ([]byte, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if s.BackgroundRGBA != (color.RGBA{}) || s.WaveRGBA != (color.RGBA{}) {
+		return nil, fmt.Errorf("wavatar: spec with explicit colors has no binary encoding")
+	}
+	if s.Fade == 0 || s.Brow == 0 || s.Eyes == 0 || s.Pupil == 0 || s.Mouth == 0 {
+		return nil, fmt.Errorf("wavatar: spec with absent layers has no binary encoding")
+	}
+
+	var packed uint64
+	var used uint
+	for _, f := range specBits(&s) {
+		packed = packed<<f.bits | uint64(*f.field-1)
+		used += f.bits
+	}
+	packed <<= 8*specBinarySize - used
+
+	data := make([]byte, specBinarySize)
+	for i := range data {
+		data[i] = byte(packed >> (8 * (specBinarySize - 1 - i)))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary unpacks a token created by MarshalBinary into s
+func (s *Spec) UnmarshalBinary(data []byte) error {
+	if len(data) != specBinarySize {
+		return fmt.Errorf("wavatar: spec token must be %d bytes, got %d", specBinarySize, len(data))
+	}
+
+	var packed uint64
+	for _, b := range data {
+		packed = packed<<8 | uint64(b)
+	}
+
+	var decoded Spec
+	shift := uint(8 * specBinarySize)
+	for _, f := range specBits(&decoded) {
+		shift -= f.bits
+		*f.field = int(packed>>shift&(1<<f.bits-1)) + 1
+	}
+	if packed&(1<<shift-1) != 0 {
+		return fmt.Errorf("wavatar: spec token has trailing bits set")
+	}
+	if err := decoded.Validate(); err != nil {
+		return err
+	}
+
+	*s = decoded
+	return nil
+}
+
+// NewFromBinary creates a new Wavatar from a token created by
+// Spec.MarshalBinary with the default Generator, see SetDefault
+func NewFromBinary(data []byte) (image.Image, error) {
+	var s Spec
+	if err := s.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return Default().GenerateFromSpec(s)
+}
@@ -0,0 +1,77 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+)
+
+func TestSpecBinaryRoundTrip(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		spec := Describe(hash)
+
+		data, err := spec.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Failed to marshal %+v: %v", spec, err)
+		}
+		if len(data) > 5 {
+			t.Errorf("Expected a token of at most 5 bytes, got %d", len(data))
+		}
+
+		var decoded Spec
+		if err = decoded.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Failed to unmarshal %x: %v", data, err)
+		}
+		if decoded != spec {
+			t.Fatalf("Expected %+v after round trip, got %+v", spec, decoded)
+		}
+
+		img, err := NewFromBinary(data)
+		if err != nil {
+			t.Fatalf("Failed to render from token: %v", err)
+		}
+		if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+			t.Errorf("Render from token differs from New for %q", hash)
+		}
+	}
+}
+
+func TestSpecBinaryExtremes(t *testing.T) {
+	for _, spec := range []Spec{
+		{Face: 1, Background: 1, Fade: 1, WaveColor: 1, Brow: 1, Eyes: 1, Pupil: 1, Mouth: 1},
+		{Face: FaceCount, Background: 240, Fade: BgCount, WaveColor: 240, Brow: BrowCount, Eyes: EyeCount, Pupil: PupilCount, Mouth: MouthCount},
+	} {
+		data, err := spec.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Failed to marshal %+v: %v", spec, err)
+		}
+		var decoded Spec
+		if err = decoded.UnmarshalBinary(data); err != nil || decoded != spec {
+			t.Errorf("Expected %+v after round trip, got %+v (%v)", spec, decoded, err)
+		}
+	}
+}
+
+func TestSpecBinaryInvalid(t *testing.T) {
+	if _, err := (Spec{}).MarshalBinary(); err == nil {
+		t.Error("Expected an error marshaling an invalid spec")
+	}
+
+	var s Spec
+	for _, data := range [][]byte{
+		nil,
+		{0, 0, 0, 0},
+		{0, 0, 0, 0, 0, 0},
+		{0xff, 0, 0, 0, 0}, // face 16
+		{0, 0, 0, 0, 1},    // trailing bit
+	} {
+		if err := s.UnmarshalBinary(data); err == nil {
+			t.Errorf("Expected an error unmarshaling %x", data)
+		}
+	}
+	if _, err := NewFromBinary([]byte{1, 2}); err == nil {
+		t.Error("Expected NewFromBinary to reject a short token")
+	}
+}
@@ -0,0 +1,143 @@
+package wavatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"slices"
+	"strings"
+)
+
+// ErrOverBudget is returned when an avatar can't be encoded within the requested byte budget
+var ErrOverBudget = errors.New("wavatar: avatar does not fit the byte budget")
+
+// budgetSizes are the dimensions EncodeUnderBudget tries after the size the
+// avatar renders at, largest first, skipping those not below it
+var budgetSizes = []int{AvatarSize, 64, 48, 40, 32, 24, 16, 8}
+
+// budgetPalettes are the palette sizes tried after full color, 0 meaning full color
+var budgetPalettes = []int{0, 256, 64, 16, 4}
+
+// budgetWebPQualities are the EncodeWebP qualities tried at every size,
+// lossless first
+var budgetWebPQualities = []float32{-1, 90, 75, 50, 0}
+
+// budgetStep encodes img to w one way, each step of a format smaller and
+// coarser than the one before
+type budgetStep func(w io.Writer, img image.Image) error
+
+// EncodeUnderBudget encodes the avatar for hash into w using at most maxBytes.
+// It keeps the largest dimensions possible, starting at the size the
+// options render at, reducing the quality before shrinking the image, and
+// returns the chosen size in pixels, the longer side for a sticker, whose
+// aspect ratio is kept.
+// format is "png", which reduces the palette, or "webp", which starts
+// lossless and then lowers the quality of EncodeWebP, in any case.
+// Options apply to the avatar, and WithDithering to the reduced palettes.
+func EncodeUnderBudget(w io.Writer, hash []byte, maxBytes int, format string, opts ...Option) (int, error) {
+	f := Format(strings.ToLower(format))
+	if f != FormatPNG && f != FormatWebP {
+		return 0, fmt.Errorf("wavatar: unsupported format %q", format)
+	}
+
+	o, err := Default().options(opts)
+	if err != nil {
+		return 0, err
+	}
+	img, err := generateHash(hash, o)
+	if err != nil {
+		return 0, err
+	}
+	steps := budgetSteps(f, o.dither)
+	var buf bytes.Buffer
+
+	b := img.Bounds()
+	full := max(b.Dx(), b.Dy())
+	sizes := []int{full}
+	for _, size := range budgetSizes {
+		if size < full {
+			sizes = append(sizes, size)
+		}
+	}
+	for _, size := range sizes {
+		var scaled image.Image = img
+		if size != full {
+			dx, dy := fitSize(b, size)
+			scaled = resampleTo(img, dx, dy)
+		}
+
+		for _, step := range steps {
+			buf.Reset()
+			if err := step(&buf, scaled); err != nil {
+				return 0, err
+			}
+			if buf.Len() <= maxBytes {
+				_, err := w.Write(buf.Bytes())
+				o.lap(stageEncode)
+				o.stopTiming()
+				return size, err
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("%w: smallest encoding is over %d bytes", ErrOverBudget, maxBytes)
+}
+
+// budgetSteps returns the ways EncodeUnderBudget encodes every size in format
+func budgetSteps(format Format, mode DitherMode) []budgetStep {
+	var steps []budgetStep
+	switch format {
+	case FormatWebP:
+		for _, quality := range budgetWebPQualities {
+			steps = append(steps, func(w io.Writer, img image.Image) error {
+				return EncodeWebP(w, img, quality)
+			})
+		}
+	default:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		for _, colors := range budgetPalettes {
+			steps = append(steps, func(w io.Writer, img image.Image) error {
+				if colors > 0 {
+					img = quantize(img, colors, mode)
+				}
+				return enc.Encode(w, img)
+			})
+		}
+	}
+	return steps
+}
+
+// quantize maps img onto a palette of at most n of its most frequent colors, dithered with mode
+func quantize(img image.Image, n int, mode DitherMode) *image.Paletted {
+	rgba := toRGBA(img)
+
+	counts := make(map[color.RGBA]int)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		c := color.RGBA{R: rgba.Pix[i], G: rgba.Pix[i+1], B: rgba.Pix[i+2], A: rgba.Pix[i+3]}
+		counts[c]++
+	}
+
+	popular := make([]color.RGBA, 0, len(counts))
+	for c := range counts {
+		popular = append(popular, c)
+	}
+	// Most frequent first, ties broken by value so the palette is deterministic
+	slices.SortFunc(popular, func(a, b color.RGBA) int {
+		if counts[a] != counts[b] {
+			return counts[b] - counts[a]
+		}
+		return int(a.R)<<24 | int(a.G)<<16 | int(a.B)<<8 | int(a.A) -
+			(int(b.R)<<24 | int(b.G)<<16 | int(b.B)<<8 | int(b.A))
+	})
+
+	pal := make(color.Palette, 0, n)
+	for _, c := range popular[:min(n, len(popular))] {
+		pal = append(pal, c)
+	}
+
+	return dither(rgba, pal, mode)
+}
@@ -0,0 +1,174 @@
+package wavatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestEncodeUnderBudget(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	var full bytes.Buffer
+	if err := png.Encode(&full, New(hash)); err != nil {
+		t.Fatalf("Failed to encode avatar: %v", err)
+	}
+
+	budget := full.Len() / 2
+	var buf bytes.Buffer
+	size, err := EncodeUnderBudget(&buf, hash, budget, "png")
+	if err != nil {
+		t.Fatalf("Failed to encode under budget %d: %v", budget, err)
+	}
+	if buf.Len() > budget {
+		t.Errorf("Expected at most %d bytes, got %d", budget, buf.Len())
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Output is not a valid PNG: %v", err)
+	}
+	if img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+		t.Errorf("Expected reported size %d to match image bounds %v", size, img.Bounds())
+	}
+}
+
+func TestEncodeUnderBudgetKeepsSizeWhenItFits(t *testing.T) {
+	size, err := EncodeUnderBudget(new(bytes.Buffer), []byte("test@example.com"), 1<<20, "PNG")
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if size != AvatarSize {
+		t.Errorf("Expected full size %d for a generous budget, got %d", AvatarSize, size)
+	}
+}
+
+func TestEncodeUnderBudgetWithSize(t *testing.T) {
+	hash := []byte("test@example.com")
+	var buf bytes.Buffer
+	size, err := EncodeUnderBudget(&buf, hash, 1<<20, "png", WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Output is not a valid PNG: %v", err)
+	}
+	if size != 256 || img.Bounds().Dx() != 256 || img.Bounds().Dy() != 256 {
+		t.Errorf("Expected size 256 for a generous budget, got %d with bounds %v", size, img.Bounds())
+	}
+
+	// A tight budget shrinks it to the sizes below
+	buf.Reset()
+	size, err = EncodeUnderBudget(&buf, hash, 1000, "png", WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to encode under budget 1000: %v", err)
+	}
+	if buf.Len() > 1000 {
+		t.Errorf("Expected at most 1000 bytes, got %d", buf.Len())
+	}
+	if img, err = png.Decode(&buf); err != nil {
+		t.Fatalf("Output is not a valid PNG: %v", err)
+	}
+	if size >= 256 || img.Bounds().Dx() != size {
+		t.Errorf("Expected a smaller size matching the bounds, got %d with bounds %v", size, img.Bounds())
+	}
+}
+
+func TestEncodeUnderBudgetWebP(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	// A generous budget keeps the avatar lossless
+	var buf bytes.Buffer
+	size, err := EncodeUnderBudget(&buf, hash, 1<<20, "WebP")
+	if err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	img, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Output is not a valid WebP: %v", err)
+	}
+	if size != AvatarSize || !bytes.Equal(toRGBA(img).Pix, toRGBA(New(hash)).Pix) {
+		t.Errorf("Expected the lossless avatar at size %d, got size %d", AvatarSize, size)
+	}
+
+	// A tight one lowers the quality first, then the size
+	var lossless bytes.Buffer
+	if err := EncodeWebP(&lossless, New(hash), -1); err != nil {
+		t.Fatalf("Failed to encode avatar: %v", err)
+	}
+	for _, budget := range []int{lossless.Len() - 1, 300} {
+		buf.Reset()
+		size, err := EncodeUnderBudget(&buf, hash, budget, "webp")
+		if err != nil {
+			t.Fatalf("Failed to encode under budget %d: %v", budget, err)
+		}
+		if buf.Len() > budget {
+			t.Errorf("Expected at most %d bytes, got %d", budget, buf.Len())
+		}
+		img, err := webp.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Output is not a valid WebP: %v", err)
+		}
+		if img.Bounds().Dx() != size || img.Bounds().Dy() != size {
+			t.Errorf("Expected reported size %d to match image bounds %v", size, img.Bounds())
+		}
+	}
+}
+
+func TestEncodeUnderBudgetSticker(t *testing.T) {
+	hash := []byte("test@example.com")
+	sticker := WithSticker(2, color.White)
+	native, err := Generate(hash, sticker)
+	if err != nil {
+		t.Fatalf("Failed to generate sticker: %v", err)
+	}
+	nb := native.Bounds()
+	if nb.Dx() == nb.Dy() {
+		t.Fatalf("Expected a sticker that is not square, got %v", nb)
+	}
+
+	for _, budget := range []int{1 << 20, 400} {
+		var buf bytes.Buffer
+		size, err := EncodeUnderBudget(&buf, hash, budget, "png", sticker)
+		if err != nil {
+			t.Fatalf("Failed to encode under budget %d: %v", budget, err)
+		}
+		img, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Output is not a valid PNG: %v", err)
+		}
+		dx, dy := fitSize(nb, size)
+		if want := image.Rect(0, 0, dx, dy); img.Bounds() != want || size != max(dx, dy) {
+			t.Errorf("Budget %d: expected size %d with bounds %v, got %d with %v", budget, max(dx, dy), want, size, img.Bounds())
+		}
+		if budget == 1<<20 && img.Bounds() != nb {
+			t.Errorf("Expected the native sticker bounds %v for a generous budget, got %v", nb, img.Bounds())
+		}
+		if budget < 1<<20 && size >= max(nb.Dx(), nb.Dy()) {
+			t.Errorf("Expected budget %d to shrink the sticker, got size %d", budget, size)
+		}
+	}
+}
+
+func TestEncodeUnderBudgetImpossible(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := EncodeUnderBudget(&buf, []byte("test@example.com"), 10, "png")
+	if !errors.Is(err, ErrOverBudget) {
+		t.Errorf("Expected ErrOverBudget, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Error("Nothing should be written when the budget can't be met")
+	}
+}
+
+func TestEncodeUnderBudgetUnsupportedFormat(t *testing.T) {
+	if _, err := EncodeUnderBudget(new(bytes.Buffer), []byte("test@example.com"), 1<<20, "bmp"); err == nil {
+		t.Error("Expected an error for an unsupported format")
+	}
+}
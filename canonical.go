@@ -0,0 +1,149 @@
+package wavatar
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+)
+
+// InputKind is what Canonicalize found an input to be
+type InputKind int
+
+const (
+	// InputOpaque is any input not recognized as one of the other kinds,
+	// used as it is
+	InputOpaque InputKind = iota
+	// InputMD5, InputSHA1 and InputSHA256 are raw digests of 16, 20 and 32 bytes
+	InputMD5
+	InputSHA1
+	InputSHA256
+	// InputHexMD5, InputHexSHA1 and InputHexSHA256 are those digests in hex,
+	// 32, 40 and 64 digits
+	InputHexMD5
+	InputHexSHA1
+	InputHexSHA256
+	// InputEmail is an email address, hashed the way Gravatar does
+	InputEmail
+)
+
+// String returns the name of the kind
+func (k InputKind) String() string {
+	switch k {
+	case InputOpaque:
+		return "opaque"
+	case InputMD5:
+		return "md5"
+	case InputSHA1:
+		return "sha1"
+	case InputSHA256:
+		return "sha256"
+	case InputHexMD5:
+		return "hex-md5"
+	case InputHexSHA1:
+		return "hex-sha1"
+	case InputHexSHA256:
+		return "hex-sha256"
+	case InputEmail:
+		return "email"
+	default:
+		return fmt.Sprintf("InputKind(%d)", int(k))
+	}
+}
+
+// Canonicalize turns the forms the same user tends to arrive in from
+// different services into the same bytes, so they get the same avatar, and
+// reports which form input was. The rules are tried in order:
+//
+//   - 32, 40 or 64 hex digits, all lowercase or all uppercase, are a digest
+//     in hex and decoded to its raw bytes. Mixed case and surrounding
+//     whitespace are not accepted, since digests are printed in one case.
+//     A 32 byte input of hex digits is taken as a hex MD5 rather than a raw
+//     SHA-256, which is all hex digits for fewer than 1 in 10^33 inputs.
+//   - Printable ASCII with surrounding whitespace trimmed, exactly one '@'
+//     and a dot inside the part after it is an email address, replaced by
+//     the MD5 of its lowercase form like NewFromString does. Addresses with
+//     other characters, such as internationalized ones, are left opaque.
+//   - 16, 20 or 32 bytes with any outside printable ASCII are a raw MD5,
+//     SHA-1 or SHA-256 and kept as they are. Printable ones are more likely
+//     text, and fewer than 1 in 10^6 digests are printable throughout.
+//   - Anything else is opaque and kept as it is.
+//
+// So a digest, its hex form in either case and, for MD5, the email address
+// it was taken of all canonicalize to the same bytes. Inputs kept as they
+// are are returned themselves, not copied.
+func Canonicalize(input []byte) ([]byte, InputKind) {
+	if kind, ok := hexDigestKinds[len(input)]; ok && isHexDigest(input) {
+		raw := make([]byte, len(input)/2)
+		hex.Decode(raw, input)
+		return raw, kind
+	}
+	if email := bytes.TrimSpace(input); isEmail(email) {
+		hash := md5.Sum(bytes.ToLower(email))
+		return hash[:], InputEmail
+	}
+	if kind, ok := rawDigestKinds[len(input)]; ok && !isPrintable(input) {
+		return input, kind
+	}
+	return input, InputOpaque
+}
+
+// hexDigestKinds and rawDigestKinds are the kinds of digests by their length
+// in hex and in bytes
+var (
+	hexDigestKinds = map[int]InputKind{32: InputHexMD5, 40: InputHexSHA1, 64: InputHexSHA256}
+	rawDigestKinds = map[int]InputKind{16: InputMD5, 20: InputSHA1, 32: InputSHA256}
+)
+
+// isHexDigest reports whether b is hex digits of a single case
+func isHexDigest(b []byte) bool {
+	lower, upper := false, false
+	for _, c := range b {
+		switch {
+		case '0' <= c && c <= '9':
+		case 'a' <= c && c <= 'f':
+			lower = true
+		case 'A' <= c && c <= 'F':
+			upper = true
+		default:
+			return false
+		}
+	}
+	return !(lower && upper)
+}
+
+// isPrintable reports whether b is printable ASCII, spaces included
+func isPrintable(b []byte) bool {
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// isEmail reports whether b is printable ASCII without spaces with one '@'
+// between a local part and a domain that has a dot between two labels
+func isEmail(b []byte) bool {
+	if !isPrintable(b) || bytes.IndexByte(b, ' ') >= 0 {
+		return false
+	}
+	local, domain, ok := bytes.Cut(b, []byte("@"))
+	if !ok || len(local) == 0 || bytes.IndexByte(domain, '@') >= 0 {
+		return false
+	}
+	dot := bytes.IndexByte(domain, '.')
+	return dot > 0 && domain[len(domain)-1] != '.'
+}
+
+// WithCanonicalization canonicalizes every hash before describing it, see
+// Canonicalize, so a digest and its hex form render the same avatar. It is
+// off by default because it changes the avatar of every input in hex and of
+// email addresses passed without hashing. WithDomainHue still sees the input
+// as it was passed.
+func WithCanonicalization() Option {
+	return func(o *options) error {
+		o.canonicalize = true
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package wavatar
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	md5Sum := md5.Sum([]byte("jane@example.com"))
+	sha1Sum := sha1.Sum([]byte("jane@example.com"))
+	sha256Sum := sha256.Sum256([]byte("jane@example.com"))
+	// A raw SHA-256 whose bytes are all hex digits reads as a hex MD5
+	hexBytes := []byte(strings.Repeat("0123456789abcdef", 2))
+	hexBytesRaw, _ := hex.DecodeString(string(hexBytes))
+
+	tests := []struct {
+		name  string
+		input []byte
+		want  []byte
+		kind  InputKind
+	}{
+		{"raw md5", md5Sum[:], md5Sum[:], InputMD5},
+		{"raw sha1", sha1Sum[:], sha1Sum[:], InputSHA1},
+		{"raw sha256", sha256Sum[:], sha256Sum[:], InputSHA256},
+		{"hex md5", []byte(hex.EncodeToString(md5Sum[:])), md5Sum[:], InputHexMD5},
+		{"hex sha1", []byte(hex.EncodeToString(sha1Sum[:])), sha1Sum[:], InputHexSHA1},
+		{"hex sha256", []byte(hex.EncodeToString(sha256Sum[:])), sha256Sum[:], InputHexSHA256},
+		{"uppercase hex md5", []byte(strings.ToUpper(hex.EncodeToString(md5Sum[:]))), md5Sum[:], InputHexMD5},
+		{"email", []byte("jane@example.com"), md5Sum[:], InputEmail},
+		{"email like Gravatar", []byte("  Jane@Example.COM\n"), md5Sum[:], InputEmail},
+		{"raw sha256 of hex digits", hexBytes, hexBytesRaw, InputHexMD5},
+
+		// Ambiguous and malformed inputs stay opaque
+		{"mixed case hex md5", []byte("55502F40dc8b7c769880b10874abc9d0"), []byte("55502F40dc8b7c769880b10874abc9d0"), InputOpaque},
+		{"hex md5 with newline", []byte("55502f40dc8b7c769880b10874abc9d0\n"), []byte("55502f40dc8b7c769880b10874abc9d0\n"), InputOpaque},
+		{"odd hex length", []byte("55502f40dc8b7c769880b10874abc9d"), []byte("55502f40dc8b7c769880b10874abc9d"), InputOpaque},
+		{"hex of other length", []byte("55502f40dc8b7c76"), []byte("55502f40dc8b7c76"), InputOpaque},
+		{"email without dot", []byte("jane@localhost"), []byte("jane@localhost"), InputOpaque},
+		{"email without local part", []byte("@example.com"), []byte("@example.com"), InputOpaque},
+		{"two at signs", []byte("jane@doe@example.com"), []byte("jane@doe@example.com"), InputOpaque},
+		{"email with space", []byte("jane doe@example.com"), []byte("jane doe@example.com"), InputOpaque},
+		{"domain ending in dot", []byte("jane@example."), []byte("jane@example."), InputOpaque},
+		{"internationalized email", []byte("jäne@example.com"), []byte("jäne@example.com"), InputOpaque},
+		{"empty", []byte{}, []byte{}, InputOpaque},
+		{"text", []byte("not an email"), []byte("not an email"), InputOpaque},
+	}
+	for _, tt := range tests {
+		got, kind := Canonicalize(tt.input)
+		if kind != tt.kind {
+			t.Errorf("%s: expected kind %v, got %v", tt.name, tt.kind, kind)
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: expected %x, got %x", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestInputKindString(t *testing.T) {
+	if got := InputHexSHA256.String(); got != "hex-sha256" {
+		t.Errorf("Expected hex-sha256, got %s", got)
+	}
+	if got := InputKind(99).String(); got != "InputKind(99)" {
+		t.Errorf("Expected InputKind(99), got %s", got)
+	}
+}
+
+func TestWithCanonicalization(t *testing.T) {
+	md5Sum := md5.Sum([]byte("jane@example.com"))
+	sha256Sum := sha256.Sum256([]byte("jane@example.com"))
+	for _, raw := range [][]byte{md5Sum[:], sha256Sum[:]} {
+		want := New(raw)
+		inputs := []string{hex.EncodeToString(raw), strings.ToUpper(hex.EncodeToString(raw))}
+		if len(raw) == md5.Size {
+			inputs = append(inputs, "Jane@Example.com")
+		}
+		for _, input := range append(inputs, string(raw)) {
+			got, err := Generate([]byte(input), WithCanonicalization())
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			if _, stats, _ := DiffImage(want, got); stats.Changed != 0 {
+				t.Errorf("Expected %q to render like %x, got %d changed pixels", input, raw, stats.Changed)
+			}
+		}
+
+		// Without the option the hex form is a different input
+		plain, err := Generate([]byte(hex.EncodeToString(raw)))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(want, plain); stats.Changed == 0 {
+			t.Errorf("Expected the hex form of %x to render differently without canonicalization", raw)
+		}
+	}
+}
+
+func TestCanonicalizationConfig(t *testing.T) {
+	opts, err := OptionsFromJSON([]byte(`{"canonicalize": true}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+	if !o.canonicalize {
+		t.Errorf("Expected canonicalize to enable canonicalization")
+	}
+}
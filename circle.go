@@ -0,0 +1,46 @@
+package wavatar
+
+import (
+	"image"
+	"math"
+)
+
+// circleFeather is the width in pixels over which the circle fades out
+const circleFeather = 1.5
+
+// WithCircleMask makes everything outside the circle inscribed in the avatar
+// transparent, for clients that cannot clip avatars to a circle themselves.
+// The edge fades out over a pixel and a half of the final image, so it stays
+// smooth at any WithSize.
+func WithCircleMask() Option {
+	return func(o *options) error {
+		o.circle = true
+		return nil
+	}
+}
+
+// circleMask scales every pixel of img by how much of it lies inside the
+// inscribed circle. The canvas is premultiplied, so all channels scale alike.
+func circleMask(img *image.RGBA) {
+	b := img.Bounds()
+	cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+	r := float64(min(b.Dx(), b.Dy())) / 2
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			// Distance of the pixel center from the edge, inside positive
+			d := r - math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy)
+			coverage := d/circleFeather + 0.5
+			if coverage >= 1 {
+				continue
+			}
+			i := img.PixOffset(x, y)
+			if coverage <= 0 {
+				clear(img.Pix[i : i+4])
+				continue
+			}
+			for c := i; c < i+4; c++ {
+				img.Pix[c] = uint8(float64(img.Pix[c])*coverage + 0.5)
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package wavatar
+
+import (
+	"image"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/weavatar/wavatar/wavatartest"
+)
+
+func TestCircleMask(t *testing.T) {
+	hash := []byte("test@example.com")
+	for _, size := range []int{AvatarSize, 512} {
+		square, err := Generate(hash, WithSize(size))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		img, err := Generate(hash, WithSize(size), WithCircleMask())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		sq, circle := square.(*image.RGBA), img.(*image.RGBA)
+
+		b := circle.Rect
+		r := float64(b.Dx()) / 2
+		feathered := 0
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				d := r - math.Hypot(float64(x)+0.5-r, float64(y)+0.5-r)
+				a := circle.RGBAAt(x, y).A
+				switch {
+				case d >= circleFeather/2:
+					if circle.RGBAAt(x, y) != sq.RGBAAt(x, y) {
+						t.Fatalf("Size %d: expected (%d,%d) inside the circle unchanged", size, x, y)
+					}
+				case d <= -circleFeather/2:
+					if a != 0 {
+						t.Fatalf("Size %d: expected (%d,%d) outside the circle transparent, got alpha %d", size, x, y, a)
+					}
+				default:
+					if a > 0 && a < 255 {
+						feathered++
+					}
+				}
+			}
+		}
+		// The edge fades along the whole circumference, whatever the size
+		if circumference := 2 * math.Pi * r; float64(feathered) < circumference {
+			t.Errorf("Size %d: expected at least %.0f feathered pixels, got %d", size, circumference, feathered)
+		}
+	}
+}
+
+func TestCircleMaskGolden(t *testing.T) {
+	img, err := Generate([]byte("test@example.com"), WithCircleMask(), WithSize(160))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	// PNG stores unpremultiplied colors, so the feathered edge rounds by one
+	path := filepath.Join("testdata", "golden", "circle.png")
+	wavatartest.SaveGolden(t, path, img)
+	if err := wavatartest.CompareImages(img, wavatartest.LoadGolden(t, path), 1, 0); err != nil {
+		t.Errorf("Golden circle mismatch: %v", err)
+	}
+}
+
+func TestCircleMaskFromJSON(t *testing.T) {
+	opts, err := OptionsFromJSON([]byte(`{"circle_mask": true}`))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	img, err := Generate([]byte("test@example.com"), opts...)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("Expected a transparent corner, got alpha %d", a)
+	}
+}
@@ -0,0 +1,118 @@
+// Command wavatar writes the avatar for an email address or hash as a PNG.
+//
+//	wavatar -email test@example.com -out avatar.png -size 256
+//	wavatar -hash 55502f40dc8b7c769880b10874abc9d0 -out - > avatar.png
+package main
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"image/png"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/weavatar/wavatar"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// config is the parsed command line
+type config struct {
+	hash []byte
+	out  string
+	size int
+}
+
+// parseFlags parses args into a config, reporting usage errors
+func parseFlags(args []string, stderr io.Writer) (*config, error) {
+	fs := flag.NewFlagSet("wavatar", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	email := fs.String("email", "", "email `address` to hash with MD5 like Gravatar")
+	hashHex := fs.String("hash", "", "hash in `hex`, used as is")
+	out := fs.String("out", "avatar.png", "output `file`, - for stdout")
+	size := fs.Int("size", wavatar.AvatarSize, "side of the avatar in `pixels`")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	cfg := &config{out: *out, size: *size}
+	switch {
+	case *email != "" && *hashHex != "":
+		return nil, errors.New("-email and -hash are mutually exclusive")
+	case *email != "":
+		sum := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(*email))))
+		cfg.hash = sum[:]
+	case *hashHex != "":
+		hash, err := hex.DecodeString(*hashHex)
+		if err != nil {
+			return nil, fmt.Errorf("-hash %q is not hex", *hashHex)
+		}
+		cfg.hash = hash
+	default:
+		return nil, errors.New("one of -email or -hash is required")
+	}
+	if cfg.out == "" {
+		return nil, errors.New("-out must not be empty")
+	}
+	return cfg, nil
+}
+
+// run executes the command with args and returns its exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	cfg, err := parseFlags(args, stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		report(stderr, err)
+		return 2
+	}
+	if err := write(cfg, stdout); err != nil {
+		report(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// report prints err to stderr, naming the command unless the package already did
+func report(stderr io.Writer, err error) {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, "wavatar: ") {
+		msg = "wavatar: " + msg
+	}
+	fmt.Fprintln(stderr, msg)
+}
+
+// write renders the avatar of cfg and writes it to its output
+func write(cfg *config, stdout io.Writer) error {
+	var opts []wavatar.Option
+	if cfg.size != wavatar.AvatarSize {
+		opts = append(opts, wavatar.WithSize(cfg.size))
+	}
+	img, err := wavatar.Generate(cfg.hash, opts...)
+	if err != nil {
+		return err
+	}
+
+	if cfg.out == "-" {
+		return png.Encode(stdout, img)
+	}
+	f, err := os.Create(cfg.out)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,139 @@
+//go:build !wavatar_noembed
+
+// The CLI renders with the embedded parts, so its tests need them
+
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weavatar/wavatar"
+)
+
+func TestParseFlags(t *testing.T) {
+	sum := md5.Sum([]byte("test@example.com"))
+	tests := []struct {
+		args []string
+		hash []byte
+		out  string
+		size int
+	}{
+		{[]string{"-email", " Test@Example.com"}, sum[:], "avatar.png", wavatar.AvatarSize},
+		{[]string{"-hash", "74657374", "-out", "-", "-size", "256"}, []byte("test"), "-", 256},
+		{[]string{"-hash=74657374", "-out=a.png"}, []byte("test"), "a.png", wavatar.AvatarSize},
+	}
+	for _, tt := range tests {
+		cfg, err := parseFlags(tt.args, &bytes.Buffer{})
+		if err != nil {
+			t.Errorf("%q: failed to parse: %v", tt.args, err)
+			continue
+		}
+		if !bytes.Equal(cfg.hash, tt.hash) || cfg.out != tt.out || cfg.size != tt.size {
+			t.Errorf("%q: expected %x %s %d, got %x %s %d", tt.args, tt.hash, tt.out, tt.size, cfg.hash, cfg.out, cfg.size)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"-email", "a@b.c", "-hash", "00"},
+		{"-hash", "xyz"},
+		{"-hash", "abc"},
+		{"-email", "a@b.c", "-out", ""},
+		{"-email", "a@b.c", "extra"},
+		{"-size", "big"},
+		{"-unknown"},
+	} {
+		if _, err := parseFlags(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%q: expected an error", args)
+		}
+	}
+}
+
+func TestRunWritesFile(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "avatar.png")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-email", "test@example.com", "-out", out, "-size", "256"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+
+	f, err := os.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	img, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("Failed to decode output: %v", err)
+	}
+	want, err := wavatar.Generate(md5Of("test@example.com"), wavatar.WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := wavatar.DiffImage(want, img); stats.Changed != 0 {
+		t.Errorf("Expected the file to hold the avatar, %d pixels differ", stats.Changed)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("Expected nothing on stdout, got %d bytes", stdout.Len())
+	}
+}
+
+func TestRunWritesStdout(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"-hash", "74657374", "-out", "-"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stderr.String())
+	}
+	img, err := png.Decode(&stdout)
+	if err != nil {
+		t.Fatalf("Failed to decode stdout: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, wavatar.AvatarSize, wavatar.AvatarSize) {
+		t.Errorf("Expected a native size avatar, got %v", img.Bounds())
+	}
+	if _, stats, _ := wavatar.DiffImage(wavatar.New([]byte("test")), img); stats.Changed != 0 {
+		t.Errorf("Expected the raw hash to be used as is, %d pixels differ", stats.Changed)
+	}
+}
+
+func TestRunErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		args []string
+		code int
+		msg  string
+	}{
+		{[]string{"-hash", "zz"}, 2, `wavatar: -hash "zz" is not hex`},
+		{[]string{}, 2, "wavatar: one of -email or -hash is required"},
+		{[]string{"-hash", "00", "-out", filepath.Join(dir, "missing", "a.png")}, 1, "no such file or directory"},
+		{[]string{"-hash", "00", "-out", dir}, 1, "is a directory"},
+		{[]string{"-hash", "00", "-size", "1"}, 1, "wavatar: size 1 out of range"},
+	}
+	for _, tt := range tests {
+		var stderr bytes.Buffer
+		if code := run(tt.args, &bytes.Buffer{}, &stderr); code != tt.code {
+			t.Errorf("%q: expected exit code %d, got %d", tt.args, tt.code, code)
+		}
+		if !strings.Contains(stderr.String(), tt.msg) {
+			t.Errorf("%q: expected %q in %q", tt.args, tt.msg, stderr.String())
+		}
+		if strings.Contains(stderr.String(), "wavatar: wavatar:") {
+			t.Errorf("%q: expected the command named once, got %q", tt.args, stderr.String())
+		}
+	}
+
+	if code := run([]string{"-h"}, &bytes.Buffer{}, &bytes.Buffer{}); code != 0 {
+		t.Errorf("Expected -h to exit 0, got %d", code)
+	}
+}
+
+// md5Of returns the MD5 of s as the command hashes an email
+func md5Of(s string) []byte {
+	sum := md5.Sum([]byte(s))
+	return sum[:]
+}
@@ -0,0 +1,125 @@
+package wavatar
+
+import (
+	"fmt"
+	"image/color"
+)
+
+// Deficiency is a color vision deficiency that WithColorblindSafe keeps avatars distinguishable for
+type Deficiency int
+
+const (
+	// ColorblindGeneral is safe for all three dichromacies at once, with fewer colors
+	ColorblindGeneral Deficiency = iota
+	Deuteranopia
+	Protanopia
+	Tritanopia
+)
+
+// safePalette lists the background and wave colors allowed for a deficiency
+type safePalette struct {
+	background []color.RGBA
+	wave       []color.RGBA
+}
+
+// safePalettes are subsets of published colorblind-friendly schemes, picked so
+// that every pair stays far apart after simulating the deficiency with the
+// Machado, Oliveira & Fernandes (2009) matrices, see colorblind_test.go.
+//
+// Wave colors come from the Okabe & Ito "Color Universal Design" palette
+// (https://jfly.uni-koeln.de/color/) and Paul Tol's "light" scheme.
+// Backgrounds come from Paul Tol's "dark", "muted" and "bright" schemes
+// (https://personal.sron.nl/~pault/).
+var safePalettes = map[Deficiency]safePalette{
+	ColorblindGeneral: {
+		background: []color.RGBA{
+			{0x22, 0x22, 0x55, 0xff}, // Tol dark blue
+			{0x66, 0x66, 0x33, 0xff}, // Tol dark yellow
+			{0x99, 0x99, 0x33, 0xff}, // Tol muted olive
+			{0xcc, 0x66, 0x77, 0xff}, // Tol muted rose
+			{0x44, 0x77, 0xaa, 0xff}, // Tol bright blue
+			{0xaa, 0x33, 0x77, 0xff}, // Tol bright purple
+		},
+		wave: []color.RGBA{
+			{0xf0, 0xe4, 0x42, 0xff}, // Okabe-Ito yellow
+			{0x00, 0x72, 0xb2, 0xff}, // Okabe-Ito blue
+			{0xd5, 0x5e, 0x00, 0xff}, // Okabe-Ito vermillion
+			{0x77, 0xaa, 0xdd, 0xff}, // Tol light blue
+			{0xee, 0x88, 0x66, 0xff}, // Tol light orange
+			{0x99, 0xdd, 0xff, 0xff}, // Tol light cyan
+		},
+	},
+	Deuteranopia: {
+		background: []color.RGBA{
+			{0x22, 0x22, 0x55, 0xff}, // Tol dark blue
+			{0x22, 0x55, 0x55, 0xff}, // Tol dark cyan
+			{0x66, 0x66, 0x33, 0xff}, // Tol dark yellow
+			{0x44, 0xaa, 0x99, 0xff}, // Tol muted teal
+			{0x99, 0x99, 0x33, 0xff}, // Tol muted olive
+			{0xaa, 0x44, 0x99, 0xff}, // Tol muted purple
+		},
+		wave: []color.RGBA{
+			{0x56, 0xb4, 0xe9, 0xff}, // Okabe-Ito sky blue
+			{0x00, 0x9e, 0x73, 0xff}, // Okabe-Ito bluish green
+			{0xf0, 0xe4, 0x42, 0xff}, // Okabe-Ito yellow
+			{0x00, 0x72, 0xb2, 0xff}, // Okabe-Ito blue
+			{0xd5, 0x5e, 0x00, 0xff}, // Okabe-Ito vermillion
+			{0xdd, 0xdd, 0xdd, 0xff}, // Tol light grey
+		},
+	},
+	Protanopia: {
+		background: []color.RGBA{
+			{0x66, 0x66, 0x33, 0xff}, // Tol dark yellow
+			{0x33, 0x22, 0x88, 0xff}, // Tol muted indigo
+			{0x88, 0x22, 0x55, 0xff}, // Tol muted wine
+			{0x44, 0xaa, 0x99, 0xff}, // Tol muted teal
+			{0x99, 0x99, 0x33, 0xff}, // Tol muted olive
+			{0xaa, 0x44, 0x99, 0xff}, // Tol muted purple
+		},
+		wave: []color.RGBA{
+			{0x56, 0xb4, 0xe9, 0xff}, // Okabe-Ito sky blue
+			{0x00, 0x72, 0xb2, 0xff}, // Okabe-Ito blue
+			{0xd5, 0x5e, 0x00, 0xff}, // Okabe-Ito vermillion
+			{0xee, 0x88, 0x66, 0xff}, // Tol light orange
+			{0xee, 0xdd, 0x88, 0xff}, // Tol light yellow
+			{0xbb, 0xcc, 0x33, 0xff}, // Tol light pear
+		},
+	},
+	Tritanopia: {
+		background: []color.RGBA{
+			{0x22, 0x22, 0x55, 0xff}, // Tol dark blue
+			{0x66, 0x66, 0x33, 0xff}, // Tol dark yellow
+			{0x11, 0x77, 0x33, 0xff}, // Tol muted green
+			{0x88, 0x22, 0x55, 0xff}, // Tol muted wine
+			{0x44, 0xaa, 0x99, 0xff}, // Tol muted teal
+			{0x99, 0x99, 0x33, 0xff}, // Tol muted olive
+		},
+		wave: []color.RGBA{
+			{0xe6, 0x9f, 0x00, 0xff}, // Okabe-Ito orange
+			{0x56, 0xb4, 0xe9, 0xff}, // Okabe-Ito sky blue
+			{0xf0, 0xe4, 0x42, 0xff}, // Okabe-Ito yellow
+			{0x00, 0x72, 0xb2, 0xff}, // Okabe-Ito blue
+			{0xd5, 0x5e, 0x00, 0xff}, // Okabe-Ito vermillion
+			{0x99, 0xdd, 0xff, 0xff}, // Tol light cyan
+		},
+	},
+}
+
+// WithColorblindSafe restricts background and wave colors to a palette that
+// stays distinguishable for the given deficiency. The hash-derived hues are
+// mapped onto the palette, so every hash still gets a stable color.
+func WithColorblindSafe(kind Deficiency) Option {
+	return func(o *options) error {
+		p, ok := safePalettes[kind]
+		if !ok {
+			return fmt.Errorf("wavatar: unknown color deficiency %d", kind)
+		}
+		o.palette = &p
+		return nil
+	}
+}
+
+// paletteColor maps a hue in the 1-240 range evenly onto colors
+func paletteColor(colors []color.RGBA, hue int) color.RGBA {
+	return colors[(hue-1)*len(colors)/240]
+}
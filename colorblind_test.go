@@ -0,0 +1,122 @@
+package wavatar
+
+import (
+	"image/color"
+	"math"
+	"slices"
+	"testing"
+)
+
+// machado holds the full severity dichromacy simulation matrices from
+// Machado, Oliveira & Fernandes, "A Physiologically-based Model for Simulation
+// of Color Vision Deficiency" (2009), applied to linear RGB
+var machado = map[Deficiency][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// simulate returns c as seen with the deficiency m, in sRGB 0-255
+func simulate(c color.RGBA, m [3][3]float64) [3]float64 {
+	toLinear := func(v uint8) float64 {
+		f := float64(v) / 255
+		if f <= 0.04045 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+	fromLinear := func(f float64) float64 {
+		f = math.Max(0, math.Min(1, f))
+		if f <= 0.0031308 {
+			return 255 * 12.92 * f
+		}
+		return 255 * (1.055*math.Pow(f, 1/2.4) - 0.055)
+	}
+
+	lin := [3]float64{toLinear(c.R), toLinear(c.G), toLinear(c.B)}
+	var out [3]float64
+	for i := range out {
+		out[i] = fromLinear(m[i][0]*lin[0] + m[i][1]*lin[1] + m[i][2]*lin[2])
+	}
+	return out
+}
+
+func TestColorblindPaletteDistances(t *testing.T) {
+	for kind, p := range safePalettes {
+		simulations := []Deficiency{kind}
+		if kind == ColorblindGeneral {
+			simulations = []Deficiency{Protanopia, Deuteranopia, Tritanopia}
+		}
+
+		for _, set := range []struct {
+			name      string
+			colors    []color.RGBA
+			threshold float64
+		}{
+			// Backgrounds are darker, which compresses their distances
+			{"background", p.background, 45},
+			{"wave", p.wave, 70},
+		} {
+			for _, sim := range simulations {
+				for i, a := range set.colors {
+					for _, b := range set.colors[i+1:] {
+						sa, sb := simulate(a, machado[sim]), simulate(b, machado[sim])
+						d := math.Sqrt((sa[0]-sb[0])*(sa[0]-sb[0]) + (sa[1]-sb[1])*(sa[1]-sb[1]) + (sa[2]-sb[2])*(sa[2]-sb[2]))
+						if d < set.threshold {
+							t.Errorf("Palette %d %s colors %v and %v are %.1f apart under deficiency %d, expected at least %.0f",
+								kind, set.name, a, b, d, sim, set.threshold)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestColorblindSafeColors(t *testing.T) {
+	o, err := newOptions([]Option{WithColorblindSafe(Deuteranopia)})
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+	p := safePalettes[Deuteranopia]
+
+	waves := make(map[color.RGBA]bool)
+	backgrounds := make(map[color.RGBA]bool)
+	for i := 0; i < 500; i++ {
+		s := Describe([]byte{byte(i), byte(i >> 8)})
+		// The face center is covered by features, so check the filled face alone
+		wave := mustRenderFace(t, s, o).RGBAAt(AvatarSize/2, AvatarSize/2)
+		if !slices.Contains(p.wave, wave) {
+			t.Errorf("Expected the face color to come from the palette, got %v", wave)
+		}
+		waves[wave] = true
+		backgrounds[o.backgroundColor(s)] = true
+	}
+	if len(waves) != len(p.wave) {
+		t.Errorf("Expected all %d wave colors to be used, got %d", len(p.wave), len(waves))
+	}
+	for c := range backgrounds {
+		if !slices.Contains(p.background, c) {
+			t.Errorf("Expected the background to come from the palette, got %v", c)
+		}
+	}
+	if len(backgrounds) != len(p.background) {
+		t.Errorf("Expected all %d backgrounds to be used, got %d", len(p.background), len(backgrounds))
+	}
+
+	if _, err := Generate(nil, WithColorblindSafe(Deficiency(42))); err == nil {
+		t.Error("Expected an error for an unknown deficiency")
+	}
+}
@@ -0,0 +1,19 @@
+package wavatar
+
+import "image/color"
+
+// ColorFor returns a stable color for s in the color language of the avatars,
+// for tags, project names and the like. See ColorForBytes.
+func ColorFor(s string) color.RGBA {
+	return ColorForBytes([]byte(s))
+}
+
+// ColorForBytes returns the background color that New renders for the hash
+// b with the default Generator, see SetDefault. With the built-in options it
+// is the hue Describe selects for b at the full saturation and lightness of
+// 50 on the 0-240 scale. Like the backgrounds it comes from a small set of
+// dark colors, so distinct inputs often share one.
+func ColorForBytes(b []byte) color.RGBA {
+	o, s := Default().mustDescribe(b)
+	return o.backgroundColor(s)
+}
@@ -0,0 +1,70 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestColorForMatchesBackground(t *testing.T) {
+	for i := range 200 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		s, err := Resolve(hash)
+		if err != nil {
+			t.Fatalf("Failed to resolve: %v", err)
+		}
+		if got := ColorForBytes(hash); got != s.BackgroundRGBA {
+			t.Errorf("%s: expected %v, got %v", hash, s.BackgroundRGBA, got)
+		}
+		if got := ColorFor(string(hash)); got != s.BackgroundRGBA {
+			t.Errorf("%s: expected ColorFor to match ColorForBytes, got %v", hash, got)
+		}
+	}
+}
+
+func TestColorForRender(t *testing.T) {
+	// Where neither the fade nor the mask cover the canvas, the render shows the background as is
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+	fade := mustLoadPart(t, "fade", s.Fade)
+	mask := mustLoadPart(t, "mask", s.Face)
+	img := New(hash).(*image.RGBA)
+	found := false
+	for y := 0; y < AvatarSize && !found; y++ {
+		for x := 0; x < AvatarSize && !found; x++ {
+			if _, _, _, a := fade.At(x, y).RGBA(); a != 0 {
+				continue
+			}
+			if _, _, _, a := mask.At(x, y).RGBA(); a != 0 {
+				continue
+			}
+			found = true
+			if got, want := img.RGBAAt(x, y), ColorForBytes(hash); got != want {
+				t.Errorf("Expected the background at (%d,%d) to be %v, got %v", x, y, want, got)
+			}
+		}
+	}
+	if !found {
+		t.Skip("No uncovered background pixel")
+	}
+}
+
+func TestColorForGolden(t *testing.T) {
+	tests := []struct {
+		input string
+		want  color.RGBA
+	}{
+		{"", color.RGBA{R: 100, A: 255}},
+		{"backend", color.RGBA{R: 100, A: 255}},
+		{"design", color.RGBA{G: 100, A: 255}},
+		{"frontend", color.RGBA{B: 100, A: 255}},
+		{"docs", color.RGBA{R: 100, B: 100, A: 255}},
+		{"test@example.com", color.RGBA{R: 100, A: 255}},
+	}
+	for _, tt := range tests {
+		if got := ColorFor(tt.input); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package wavatar
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHSLRoundTrip(t *testing.T) {
+	for r := 0; r <= 255; r += 51 {
+		for g := 0; g <= 255; g += 51 {
+			for b := 0; b <= 255; b += 51 {
+				h, s, l := rgbToHSL(float64(r)/255, float64(g)/255, float64(b)/255)
+				gr, gg, gb := hslToRGB(h, s, l)
+				if math.Abs(gr*255-float64(r)) > 1e-6 || math.Abs(gg*255-float64(g)) > 1e-6 || math.Abs(gb*255-float64(b)) > 1e-6 {
+					t.Errorf("RGB(%d,%d,%d) round tripped to (%f,%f,%f)", r, g, b, gr*255, gg*255, gb*255)
+				}
+			}
+		}
+	}
+}
+
+func TestRGBToHSLKnownValues(t *testing.T) {
+	tests := []struct {
+		r, g, b float64
+		h, s, l float64
+	}{
+		{1, 0, 0, 0, 1, 0.5},
+		{0, 1, 0, 120, 1, 0.5},
+		{0, 0, 1, 240, 1, 0.5},
+		{0.5, 0.5, 0.5, 0, 0, 0.5},
+		{1, 1, 0, 60, 1, 0.5},
+	}
+
+	for _, tt := range tests {
+		h, s, l := rgbToHSL(tt.r, tt.g, tt.b)
+		if math.Abs(h-tt.h) > 1e-9 || math.Abs(s-tt.s) > 1e-9 || math.Abs(l-tt.l) > 1e-9 {
+			t.Errorf("rgbToHSL(%v,%v,%v) = (%v,%v,%v), expected (%v,%v,%v)", tt.r, tt.g, tt.b, h, s, l, tt.h, tt.s, tt.l)
+		}
+	}
+}
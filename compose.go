@@ -0,0 +1,88 @@
+package wavatar
+
+import (
+	"image"
+	"math"
+)
+
+// premulPart is a part converted once to 16-bit premultiplied color, cropped
+// to its visible pixels, so compositing it needs no per-pixel interface calls
+type premulPart struct {
+	rect image.Rectangle
+	// pix holds r, g, b, a for every pixel of rect, row by row
+	pix []uint16
+}
+
+// newPremulPart converts img, keeping only the bounding box of its non-transparent pixels
+func newPremulPart(img image.Image) *premulPart {
+	b := img.Bounds()
+	rect := image.Rectangle{}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0 {
+				rect = rect.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	p := &premulPart{rect: rect, pix: make([]uint16, 4*rect.Dx()*rect.Dy())}
+	i := 0
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			p.pix[i], p.pix[i+1], p.pix[i+2], p.pix[i+3] = uint16(r), uint16(g), uint16(b), uint16(a)
+			i += 4
+		}
+	}
+	return p
+}
+
+// over composites p onto dst with its origin at dst.Rect.Min, producing
+// exactly what draw.Draw with draw.Over does
+func (p *premulPart) over(dst *image.RGBA) {
+	const m = 1<<16 - 1
+
+	r := p.rect.Add(dst.Rect.Min).Intersect(dst.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		si := 4 * ((y-dst.Rect.Min.Y-p.rect.Min.Y)*p.rect.Dx() + r.Min.X - dst.Rect.Min.X - p.rect.Min.X)
+		di := dst.PixOffset(r.Min.X, y)
+		for x := r.Min.X; x < r.Max.X; x, si, di = x+1, si+4, di+4 {
+			s := p.pix[si : si+4 : si+4]
+			sa := uint32(s[3])
+			if sa == 0 {
+				continue
+			}
+			d := dst.Pix[di : di+4 : di+4]
+			if sa == m {
+				d[0], d[1], d[2], d[3] = uint8(s[0]>>8), uint8(s[1]>>8), uint8(s[2]>>8), 0xff
+				continue
+			}
+
+			a := (m - sa) * 0x101
+			d[0] = uint8((uint32(d[0])*a/m + uint32(s[0])) >> 8)
+			d[1] = uint8((uint32(d[1])*a/m + uint32(s[1])) >> 8)
+			d[2] = uint8((uint32(d[2])*a/m + uint32(s[2])) >> 8)
+			d[3] = uint8((uint32(d[3])*a/m + sa) >> 8)
+		}
+	}
+}
+
+// scaleAlpha returns a copy of p with its alpha multiplied by f and capped at
+// opaque, keeping the unpremultiplied color of every pixel
+func (p *premulPart) scaleAlpha(f float64) *premulPart {
+	const m = 1<<16 - 1
+
+	scaled := &premulPart{rect: p.rect, pix: make([]uint16, len(p.pix))}
+	for i := 0; i < len(p.pix); i += 4 {
+		a := float64(p.pix[i+3])
+		if a == 0 {
+			continue
+		}
+		na := math.Min(m, a*f)
+		for ch := 0; ch < 3; ch++ {
+			scaled.pix[i+ch] = uint16(math.Round(float64(p.pix[i+ch]) * na / a))
+		}
+		scaled.pix[i+3] = uint16(math.Round(na))
+	}
+	return scaled
+}
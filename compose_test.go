@@ -0,0 +1,111 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// noisyCanvas returns an avatar sized canvas with varied, partly transparent pixels
+func noisyCanvas() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := uint8(i * 7)
+		img.Pix[i] = uint8(i*13) % (a | 1)
+		img.Pix[i+1] = uint8(i*29) % (a | 1)
+		img.Pix[i+2] = uint8(i*31) % (a | 1)
+		img.Pix[i+3] = a
+	}
+	return img
+}
+
+func TestPremulOverMatchesDraw(t *testing.T) {
+	for _, c := range partCounts {
+		for num := 1; num <= c.count; num++ {
+			part := mustLoadPart(t, c.part, num)
+
+			want := noisyCanvas()
+			draw.Draw(want, want.Bounds(), part, image.Point{}, draw.Over)
+			got := noisyCanvas()
+			newPremulPart(part).over(got)
+
+			if !bytes.Equal(got.Pix, want.Pix) {
+				t.Errorf("Compositing %s%d differs from draw.Draw", c.part, num)
+			}
+		}
+	}
+}
+
+func TestPremulOverOtherImageTypes(t *testing.T) {
+	pal := image.NewPaletted(image.Rect(0, 0, 40, 30), color.Palette{
+		color.NRGBA{},
+		color.NRGBA{R: 200, G: 10, B: 90, A: 128},
+		color.RGBA{R: 10, G: 20, B: 30, A: 255},
+	})
+	for i := range pal.Pix {
+		pal.Pix[i] = uint8(i % 3)
+	}
+
+	// The destination is offset to check the part is placed at its origin
+	want := noisyCanvas()
+	want.Rect = want.Rect.Add(image.Pt(5, 5))
+	draw.Draw(want, want.Bounds(), pal, image.Point{}, draw.Over)
+	got := noisyCanvas()
+	got.Rect = got.Rect.Add(image.Pt(5, 5))
+	newPremulPart(pal).over(got)
+
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Error("Compositing a paletted part differs from draw.Draw")
+	}
+
+	empty := newPremulPart(image.NewNRGBA(image.Rect(0, 0, 10, 10)))
+	if !empty.rect.Empty() || len(empty.pix) != 0 {
+		t.Errorf("Expected a transparent part to keep no pixels, got %v", empty.rect)
+	}
+}
+
+func BenchmarkComposite(b *testing.B) {
+	part, err := defaultParts.load("mouth", 1)
+	if err != nil {
+		b.Fatal(err)
+	}
+	premul := newPremulPart(part)
+	dst := noisyCanvas()
+
+	b.Run("draw", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			draw.Draw(dst, dst.Bounds(), part, image.Point{}, draw.Over)
+		}
+	})
+	b.Run("premul", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			premul.over(dst)
+		}
+	})
+}
+
+func BenchmarkNew(b *testing.B) {
+	if err := PreloadAll(); err != nil {
+		b.Fatal(err)
+	}
+	b.Run("cached", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			New([]byte(fmt.Sprint(i)))
+		}
+	})
+	// Decoding every part again on each call, as before parts were cached
+	b.Run("uncached", func(b *testing.B) {
+		b.ReportAllocs()
+		o := defaultOptions()
+		for i := 0; i < b.N; i++ {
+			o.parts = embeddedParts()
+			if _, err := generateHash([]byte(fmt.Sprint(i)), o); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package wavatar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// formatJSON is the Spec of an avatar as JSON, which only the handler serves
+const formatJSON Format = "json"
+
+// compressibleTypes are the media types whose responses shrink when gzipped;
+// PNG and WebP are compressed already
+var compressibleTypes = map[string]bool{
+	"image/svg+xml":    true,
+	"application/json": true,
+	"text/css":         true,
+}
+
+// compressible reports whether responses in format shrink when gzipped
+func (f Format) compressible() bool {
+	return compressibleTypes[f.contentType()]
+}
+
+// contentType is the media type of format
+func (f Format) contentType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case formatJSON:
+		return "application/json"
+	case FormatWebP:
+		return "image/webp"
+	default:
+		return "image/png"
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip with a
+// nonzero quality, by name or else by *
+func acceptsGzip(header string) bool {
+	star := false
+	for _, coding := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		name = strings.TrimSpace(name)
+		accepted := true
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			v, err := strconv.ParseFloat(q, 64)
+			accepted = err == nil && v > 0
+		}
+		switch {
+		case strings.EqualFold(name, "gzip"):
+			return accepted
+		case name == "*":
+			star = accepted
+		}
+	}
+	return star
+}
+
+// gzipWriters are reused between responses, each holding its compression state
+var gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+
+// writeGzip appends data gzipped to buf
+func writeGzip(buf *bytes.Buffer, data []byte) error {
+	zw := gzipWriters.Get().(*gzip.Writer)
+	defer gzipWriters.Put(zw)
+	zw.Reset(buf)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
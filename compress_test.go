@@ -0,0 +1,49 @@
+package wavatar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                   false,
+		"gzip":               true,
+		"deflate, GZIP":      true,
+		"gzip;q=0.5":         true,
+		"gzip;q=0":           false,
+		"*":                  true,
+		"gzip;q=0, *":        false,
+		"br;q=1.0, *;q=0":    false,
+		"identity, deflate":  false,
+		"x-gzip, br, *;q=.1": true,
+	} {
+		if got := acceptsGzip(header); got != want {
+			t.Errorf("Accept-Encoding %q: expected %v, got %v", header, want, got)
+		}
+	}
+}
+
+func TestWriteGzip(t *testing.T) {
+	// Pooled writers are reset between bodies, so each decodes on its own
+	for _, body := range []string{`<svg xmlns="http://www.w3.org/2000/svg"/>`, strings.Repeat(`{"Face":1}`, 100), ""} {
+		var buf bytes.Buffer
+		if err := writeGzip(&buf, []byte(body)); err != nil {
+			t.Fatalf("Failed to gzip: %v", err)
+		}
+		zr, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatalf("Failed to read gzip header: %v", err)
+		}
+		got, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("Failed to gunzip: %v", err)
+		}
+		if string(got) != body {
+			t.Errorf("Expected %q back, got %q", body, got)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package wavatar
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// OptionsConfig is the JSON form of a set of options, for services configured
+// from a file. Every field is optional and maps to the option named in its
+// comment; zero values keep the defaults.
+type OptionsConfig struct {
+	// Theme is one of ThemeNames, such as "light" or "dark", see WithTheme
+	Theme string `json:"theme,omitempty"`
+	// Style is "full" or "lineart", see WithLineArt
+	Style string `json:"style,omitempty"`
+	// LineArtThreshold snaps line art to black and white at this luma, see WithLineArtThreshold
+	LineArtThreshold *int `json:"line_art_threshold,omitempty"`
+	// Saturation and Lightness of the background and wave colors, see WithSaturation and WithLightness
+	Saturation *ColorLevels `json:"saturation,omitempty"`
+	Lightness  *ColorLevels `json:"lightness,omitempty"`
+	// HueRanges limits the hues, as objects like {"min": 0, "max": 40}, see WithHueRange
+	HueRanges []HueRange `json:"hue_ranges,omitempty"`
+	// Version is the algorithm that describes hashes and colors them, 1 to 3, see WithVersion
+	Version int `json:"version,omitempty"`
+	// Seasonal is "none", "snow", "hearts" or "confetti", see WithSeasonal
+	Seasonal string `json:"seasonal,omitempty"`
+	// FeatureOutline is the width of the feature outline, see WithFeatureOutline
+	FeatureOutline int `json:"feature_outline,omitempty"`
+	// TransparentBackground leaves out the background and fade, see WithTransparentBackground
+	TransparentBackground bool `json:"transparent_background,omitempty"`
+	// PreciseColors computes colors in floating point, see WithPreciseColors
+	PreciseColors bool `json:"precise_colors,omitempty"`
+	// IntegerColors keeps the integer colors of V1 and V2, see WithIntegerColors
+	IntegerColors bool `json:"integer_colors,omitempty"`
+	// LegacyColors computes colors like the original PHP Wavatar, see WithLegacyColors
+	LegacyColors bool `json:"legacy_colors,omitempty"`
+	// Canonicalize renders a digest and its hex form alike, see WithCanonicalization
+	Canonicalize bool `json:"canonicalize,omitempty"`
+	// CircleMask clips the avatar to a circle, see WithCircleMask
+	CircleMask bool `json:"circle_mask,omitempty"`
+	// AlphaFill fills the face beneath the mask, see WithAlphaFill
+	AlphaFill bool `json:"alpha_fill,omitempty"`
+	// ShineIntensity scales the shine, see WithShineIntensity
+	ShineIntensity *float64 `json:"shine_intensity,omitempty"`
+	// FaceCrop crops to the face with this margin, see WithFaceCrop
+	FaceCrop *int `json:"face_crop,omitempty"`
+	// Size is the side of the avatar in pixels, see WithSize
+	Size int `json:"size,omitempty"`
+	// Dithering is "none", "ordered" or "floyd-steinberg", see WithDithering
+	Dithering string `json:"dithering,omitempty"`
+	// RenderCache is the number of avatars a Generator caches, see WithRenderCache
+	RenderCache int `json:"render_cache,omitempty"`
+	// MemoryBudget caps the bytes a Generator caches, see WithMemoryBudget
+	MemoryBudget int64 `json:"memory_budget,omitempty"`
+	// EncodeConcurrency is how many sizes are encoded at once, see WithEncodeConcurrency
+	EncodeConcurrency int `json:"encode_concurrency,omitempty"`
+}
+
+// ColorLevels is a saturation or lightness for the background and wave colors on the 0-240 scale
+type ColorLevels struct {
+	Background int `json:"background"`
+	Wave       int `json:"wave"`
+}
+
+// configOption is the option a config field maps to, or the error that prevented it
+type configOption struct {
+	field string
+	opt   Option
+	err   error
+}
+
+// OptionsFromJSON parses an OptionsConfig and returns the options it
+// describes, in the order of its fields. Unknown fields are rejected, and
+// every invalid field is reported by its JSON name in one joined error.
+func OptionsFromJSON(data []byte) ([]Option, error) {
+	var cfg OptionsConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("wavatar: config: %w", err)
+	}
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return nil, fmt.Errorf("wavatar: config: unexpected data after the object")
+	}
+	return cfg.Options()
+}
+
+// Options returns the options cfg describes, see OptionsFromJSON
+func (cfg OptionsConfig) Options() ([]Option, error) {
+	var fields []configOption
+	add := func(field string, opt Option) {
+		fields = append(fields, configOption{field: field, opt: opt})
+	}
+	fail := func(field, format string, args ...any) {
+		fields = append(fields, configOption{field: field, err: fmt.Errorf(format, args...)})
+	}
+
+	if cfg.Theme != "" {
+		add("theme", WithTheme(cfg.Theme))
+	}
+	switch cfg.Style {
+	case "", "full":
+	case "lineart":
+		add("style", WithLineArt())
+	default:
+		fail("style", "unknown style %q", cfg.Style)
+	}
+	if t := cfg.LineArtThreshold; t != nil {
+		if *t < 0 || *t > 255 {
+			fail("line_art_threshold", "threshold %d out of range 0-255", *t)
+		} else {
+			add("line_art_threshold", WithLineArtThreshold(uint8(*t)))
+		}
+	}
+	if l := cfg.Saturation; l != nil {
+		add("saturation", WithSaturation(l.Background, l.Wave))
+	}
+	if l := cfg.Lightness; l != nil {
+		add("lightness", WithLightness(l.Background, l.Wave))
+	}
+	if cfg.HueRanges != nil {
+		add("hue_ranges", WithHueRange(cfg.HueRanges...))
+	}
+	if cfg.Version != 0 {
+		add("version", WithVersion(Version(cfg.Version)))
+	}
+	if kind, ok := map[string]SeasonalKind{"": SeasonalNone, "none": SeasonalNone, "snow": SeasonalSnow, "hearts": SeasonalHearts, "confetti": SeasonalConfetti}[cfg.Seasonal]; !ok {
+		fail("seasonal", "unknown seasonal overlay %q", cfg.Seasonal)
+	} else if kind != SeasonalNone {
+		add("seasonal", WithSeasonal(kind))
+	}
+	if cfg.FeatureOutline != 0 {
+		add("feature_outline", WithFeatureOutline(cfg.FeatureOutline))
+	}
+	if cfg.TransparentBackground {
+		add("transparent_background", WithTransparentBackground())
+	}
+	if cfg.PreciseColors {
+		add("precise_colors", WithPreciseColors())
+	}
+	if cfg.IntegerColors {
+		add("integer_colors", WithIntegerColors())
+	}
+	if cfg.LegacyColors {
+		add("legacy_colors", WithLegacyColors())
+	}
+	if cfg.Canonicalize {
+		add("canonicalize", WithCanonicalization())
+	}
+	if cfg.CircleMask {
+		add("circle_mask", WithCircleMask())
+	}
+	if cfg.AlphaFill {
+		add("alpha_fill", WithAlphaFill())
+	}
+	if cfg.ShineIntensity != nil {
+		add("shine_intensity", WithShineIntensity(*cfg.ShineIntensity))
+	}
+	if cfg.FaceCrop != nil {
+		add("face_crop", WithFaceCrop(*cfg.FaceCrop))
+	}
+	if cfg.Size != 0 {
+		add("size", WithSize(cfg.Size))
+	}
+	if mode, ok := map[string]DitherMode{"": DitherNone, "none": DitherNone, "ordered": DitherOrdered, "floyd-steinberg": DitherFloydSteinberg}[cfg.Dithering]; !ok {
+		fail("dithering", "unknown dithering mode %q", cfg.Dithering)
+	} else if mode != DitherNone {
+		add("dithering", WithDithering(mode))
+	}
+	if cfg.RenderCache != 0 {
+		add("render_cache", WithRenderCache(cfg.RenderCache))
+	}
+	if cfg.MemoryBudget != 0 {
+		add("memory_budget", WithMemoryBudget(cfg.MemoryBudget))
+	}
+	if cfg.EncodeConcurrency != 0 {
+		add("encode_concurrency", WithEncodeConcurrency(cfg.EncodeConcurrency))
+	}
+
+	// Options validate their arguments when applied, so try each on its own
+	var opts []Option
+	var errs []error
+	for _, f := range fields {
+		err := f.err
+		if err == nil {
+			err = f.opt(defaultOptions())
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("wavatar: config field %s: %s", f.field, strings.TrimPrefix(err.Error(), "wavatar: ")))
+			continue
+		}
+		opts = append(opts, f.opt)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return opts, nil
+}
@@ -0,0 +1,92 @@
+package wavatar
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptionsFromJSONMatchesOptions(t *testing.T) {
+	config := `{
+		"theme": "dark",
+		"saturation": {"background": 200, "wave": 180},
+		"lightness": {"background": 40, "wave": 160},
+		"hue_ranges": [{"min": 100, "max": 160}, {"min": 200, "max": 230}],
+		"version": 2,
+		"seasonal": "snow",
+		"feature_outline": 1,
+		"shine_intensity": 1.5,
+		"render_cache": 16,
+		"memory_budget": 1048576
+	}`
+	opts, err := OptionsFromJSON([]byte(config))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+	fromJSON, err := NewGenerator(nil, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	byHand, err := NewGenerator(nil,
+		WithDarkTheme(),
+		WithSaturation(200, 180),
+		WithLightness(40, 160),
+		WithHueRange(HueRange{Min: 100, Max: 160}, HueRange{Min: 200, Max: 230}),
+		WithVersion(V2),
+		WithSeasonal(SeasonalSnow),
+		WithFeatureOutline(1),
+		WithShineIntensity(1.5),
+		WithRenderCache(16),
+		WithMemoryBudget(1<<20),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	for name, hash := range map[string]string{"email": "test@example.com", "user1": "user1@example.com"} {
+		got, err := fromJSON.Generate([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		want, err := byHand.Generate([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(want, got); stats.Changed != 0 {
+			t.Errorf("%s: expected the config to render like the options, %d pixels differ", hash, stats.Changed)
+		}
+		checkGolden(t, "config-"+name, got)
+	}
+}
+
+func TestOptionsFromJSONUnknownField(t *testing.T) {
+	if _, err := OptionsFromJSON([]byte(`{"theme": "dark", "sizee": 80}`)); err == nil || !strings.Contains(err.Error(), "sizee") {
+		t.Errorf("Expected an error naming the unknown field, got %v", err)
+	}
+	if _, err := OptionsFromJSON([]byte(`{} {}`)); err == nil {
+		t.Error("Expected an error for trailing data")
+	}
+	if opts, err := OptionsFromJSON([]byte(`{}`)); err != nil || len(opts) != 0 {
+		t.Errorf("Expected no options for an empty config, got %d (%v)", len(opts), err)
+	}
+}
+
+func TestOptionsFromJSONAggregatesErrors(t *testing.T) {
+	config := `{
+		"theme": "sepia",
+		"saturation": {"background": 300, "wave": 10},
+		"hue_ranges": [{"min": 50, "max": 10}],
+		"seasonal": "easter",
+		"shine_intensity": 3,
+		"render_cache": -1
+	}`
+	_, err := OptionsFromJSON([]byte(config))
+	if err == nil {
+		t.Fatal("Expected an error for an invalid config")
+	}
+	for _, field := range []string{"theme", "saturation", "hue_ranges", "seasonal", "shine_intensity", "render_cache"} {
+		if !strings.Contains(err.Error(), "config field "+field+":") {
+			t.Errorf("Expected the error to name %s, got %v", field, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package wavatar
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// CountPolicy decides how a Generator handles a part pack whose number of
+// parts per layer differs from the embedded parts
+type CountPolicy int
+
+const (
+	// CountStrict rejects packs that do not have exactly as many parts per layer as the embedded parts
+	CountStrict CountPolicy = iota + 1
+	// CountExtend selects parts among all parts the pack has, so extra parts
+	// are used and missing ones never are. Hashes then select differently than
+	// with the embedded parts, and Specs must fit the pack.
+	CountExtend
+	// CountWrap selects parts as with the embedded parts and takes indices
+	// beyond the pack modulo its count, so Specs made for another pack still render
+	CountWrap
+)
+
+// layerCounts is the number of parts available for every Layer
+type layerCounts [LayerMouth + 1]int
+
+// defaultCounts are the part counts of the embedded parts
+var defaultCounts = layerCounts{
+	LayerFace:   FaceCount,
+	LayerFade:   BgCount,
+	LayerBrow:   BrowCount,
+	LayerEyes:   EyeCount,
+	LayerPupils: PupilCount,
+	LayerMouth:  MouthCount,
+}
+
+// WithCountPolicy sets how a Generator handles a part pack with other part
+// counts than the embedded parts. Without it a Generator assumes the embedded
+// counts and reports missing parts when they are first rendered.
+// It only takes effect when passed to NewGenerator.
+func WithCountPolicy(p CountPolicy) Option {
+	return func(o *options) error {
+		if p < CountStrict || p > CountWrap {
+			return fmt.Errorf("wavatar: unknown count policy %d", p)
+		}
+		o.countPolicy = p
+		return nil
+	}
+}
+
+// countParts returns how many consecutively numbered parts of every layer
+// fsys holds. A face needs both its mask and its shine.
+func countParts(fsys fs.FS) (layerCounts, error) {
+	count := func(part string) int {
+		n := 0
+		for {
+			if _, err := fs.Stat(fsys, fmt.Sprintf("%s%d.png", part, n+1)); err != nil {
+				return n
+			}
+			n++
+		}
+	}
+
+	var c layerCounts
+	for l := range c {
+		if Layer(l) == LayerFace {
+			c[l] = min(count("mask"), count("shine"))
+		} else {
+			c[l] = count(Layer(l).String())
+		}
+		if c[l] == 0 {
+			return c, fmt.Errorf("wavatar: part pack has no %s parts", Layer(l))
+		}
+	}
+	return c, nil
+}
+
+// checkCounts reports an error if the pack counts c are not allowed under policy p
+func checkCounts(p CountPolicy, c layerCounts) error {
+	if p != CountStrict {
+		return nil
+	}
+	for l, n := range c {
+		if n != defaultCounts[l] {
+			return fmt.Errorf("wavatar: part pack has %d %s parts, expected %d", n, Layer(l), defaultCounts[l])
+		}
+	}
+	return nil
+}
+
+// selectionCounts returns the part counts hashes select from
+func (o *options) selectionCounts() layerCounts {
+	if o.countPolicy == CountExtend {
+		return o.counts
+	}
+	return defaultCounts
+}
+
+// fitSpec checks that s selects parts that exist, first wrapping its indices into range under CountWrap
+func (o *options) fitSpec(s Spec) (Spec, error) {
+	if o.countPolicy == CountWrap {
+		for l := range o.counts {
+			if index, _, ok := s.layer(Layer(l)); ok && *index > 0 {
+				*index = (*index-1)%o.counts[l] + 1
+			}
+		}
+	}
+	return s, s.validate(o.counts)
+}
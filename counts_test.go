@@ -0,0 +1,129 @@
+package wavatar
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+// mouthPack returns the embedded parts as a pack with the given number of mouths,
+// repeating the existing mouths beyond MouthCount
+func mouthPack(t *testing.T, mouths int) fstest.MapFS {
+	t.Helper()
+
+	pack := fstest.MapFS{}
+	for _, c := range partCounts {
+		n := c.count
+		if c.part == "mouth" {
+			n = mouths
+		}
+		for num := 1; num <= n; num++ {
+			data, err := os.ReadFile(fmt.Sprintf("parts/%s%d.png", c.part, (num-1)%c.count+1))
+			if err != nil {
+				t.Fatalf("Failed to read part: %v", err)
+			}
+			pack[fmt.Sprintf("%s%d.png", c.part, num)] = &fstest.MapFile{Data: data}
+		}
+	}
+	return pack
+}
+
+func TestCountPolicyStrict(t *testing.T) {
+	if _, err := NewGenerator(mouthPack(t, MouthCount), WithCountPolicy(CountStrict)); err != nil {
+		t.Errorf("Expected a pack with the default counts to be accepted, got %v", err)
+	}
+	for _, mouths := range []int{14, 25} {
+		if _, err := NewGenerator(mouthPack(t, mouths), WithCountPolicy(CountStrict)); err == nil {
+			t.Errorf("Expected a pack with %d mouths to be rejected", mouths)
+		}
+	}
+}
+
+func TestCountPolicyExtend(t *testing.T) {
+	for _, mouths := range []int{14, 25} {
+		g, err := NewGenerator(mouthPack(t, mouths), WithCountPolicy(CountExtend))
+		if err != nil {
+			t.Fatalf("Failed to create generator: %v", err)
+		}
+		o, err := g.options(nil)
+		if err != nil {
+			t.Fatalf("Failed to apply options: %v", err)
+		}
+
+		// Hashes select among every mouth of the pack and no others
+		highest := 0
+		for i := range 500 {
+			s, err := describeVersion([]byte(fmt.Sprintf("user%d@example.com", i)), V1, o.selectionCounts())
+			if err != nil {
+				t.Fatalf("Failed to describe: %v", err)
+			}
+			highest = max(highest, s.Mouth)
+		}
+		if highest != mouths {
+			t.Errorf("Expected mouths up to %d to be selected, got up to %d", mouths, highest)
+		}
+
+		s := Describe([]byte("test@example.com"))
+		s.Mouth = mouths
+		if _, err := g.GenerateFromSpec(s); err != nil {
+			t.Errorf("Expected mouth %d of the pack to render, got %v", mouths, err)
+		}
+		s.Mouth = mouths + 1
+		if _, err := g.GenerateFromSpec(s); err == nil {
+			t.Errorf("Expected an error for mouth %d beyond the pack", mouths+1)
+		}
+	}
+}
+
+func TestCountPolicyWrap(t *testing.T) {
+	small, err := NewGenerator(mouthPack(t, 14), WithCountPolicy(CountWrap))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	// A Spec made for the default parts renders with its mouth wrapped into the pack
+	s := Describe([]byte("test@example.com"))
+	s.Mouth = 19
+	got, err := small.GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Expected a wrapped mouth to render, got %v", err)
+	}
+	s.Mouth = 5
+	if _, stats, _ := DiffImage(NewFromSpec(s), got); stats.Changed != 0 {
+		t.Error("Expected mouth 19 to wrap to mouth 5 in a pack of 14")
+	}
+
+	// Hashes select as with the default parts, so a larger pack changes nothing
+	large, err := NewGenerator(mouthPack(t, 25), WithCountPolicy(CountWrap))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	for i := range 20 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		img, err := large.Generate(hash)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(New(hash), img); stats.Changed != 0 {
+			t.Errorf("Expected %s to render as with the default parts", hash)
+		}
+	}
+
+	// Colors are not indices and stay validated
+	s.Background = 241
+	if _, err := small.GenerateFromSpec(s); err == nil {
+		t.Error("Expected an error for an out of range color")
+	}
+}
+
+func TestCountPolicyValidation(t *testing.T) {
+	if _, err := NewGenerator(nil, WithCountPolicy(CountPolicy(9))); err == nil {
+		t.Error("Expected an error for an unknown count policy")
+	}
+	pack := mouthPack(t, MouthCount)
+	delete(pack, "brow1.png")
+	if _, err := NewGenerator(pack, WithCountPolicy(CountExtend)); err == nil {
+		t.Error("Expected an error for a pack without brows")
+	}
+}
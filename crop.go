@@ -0,0 +1,49 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+)
+
+// WithFaceCrop crops the avatar to the face plus marginPx on every side and
+// scales it back to AvatarSize, so the face appears larger. The crop is a
+// square that only depends on the face shape, so all avatars sharing a face
+// line up. It has no effect together with WithSticker, which already crops.
+func WithFaceCrop(marginPx int) Option {
+	return func(o *options) error {
+		if marginPx < 0 {
+			return fmt.Errorf("wavatar: face crop margin must not be negative, got %d", marginPx)
+		}
+		o.faceCrop = true
+		o.faceCropMargin = marginPx
+		return nil
+	}
+}
+
+// faceCropRect returns the square crop around the visible pixels of the mask
+// of face, grown by margin and kept inside the canvas
+func faceCropRect(parts *partSet, face, margin int) (image.Rectangle, error) {
+	mask, err := parts.entry("mask", face)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
+	canvas := image.Rect(0, 0, AvatarSize, AvatarSize)
+	r := mask.premul.rect.Inset(-margin)
+
+	side := min(max(r.Dx(), r.Dy()), AvatarSize)
+	center := r.Min.Add(r.Max).Div(2)
+	origin := center.Sub(image.Pt(side/2, side/2))
+	origin.X = max(canvas.Min.X, origin.X-max(0, origin.X+side-canvas.Max.X))
+	origin.Y = max(canvas.Min.Y, origin.Y-max(0, origin.Y+side-canvas.Max.Y))
+
+	return image.Rectangle{Min: origin, Max: origin.Add(image.Pt(side, side))}, nil
+}
+
+// cropFace crops img to the face crop of s and scales it back to the size of img
+func cropFace(img *image.RGBA, s Spec, o *options) (*image.RGBA, error) {
+	r, err := faceCropRect(o.parts, s.Face, o.faceCropMargin)
+	if err != nil {
+		return nil, err
+	}
+	return resample(img.SubImage(r), img.Rect.Dx()), nil
+}
@@ -0,0 +1,84 @@
+package wavatar
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFaceCropRect(t *testing.T) {
+	canvas := image.Rect(0, 0, AvatarSize, AvatarSize)
+	for face := 1; face <= FaceCount; face++ {
+		for _, margin := range []int{0, 2, 50} {
+			r, err := faceCropRect(defaultParts, face, margin)
+			if err != nil {
+				t.Fatalf("Failed to compute the crop: %v", err)
+			}
+			if r.Dx() != r.Dy() {
+				t.Errorf("Face %d margin %d: expected a square crop, got %v", face, margin, r)
+			}
+			if !r.In(canvas) {
+				t.Errorf("Face %d margin %d: expected the crop %v inside the canvas", face, margin, r)
+			}
+
+			// The face and as much of the margin as fits stay inside the crop
+			bounds := partBounds(t, "mask", face)
+			if want := bounds.Inset(-margin).Intersect(canvas); !want.In(r) {
+				t.Errorf("Face %d margin %d: expected the crop %v to contain %v", face, margin, r, want)
+			}
+		}
+	}
+
+	if r, _ := faceCropRect(defaultParts, 1, 2); r != image.Rect(7, 7, 73, 73) {
+		t.Errorf("Expected the crop of face 1 at (7,7)-(73,73), got %v", r)
+	}
+}
+
+func TestFaceCropStablePerFace(t *testing.T) {
+	o := defaultOptions()
+	o.faceCrop = true
+	o.faceCropMargin = 2
+
+	a := Describe([]byte("test@example.com"))
+	b := Describe([]byte("user1@example.com"))
+	b.Face = a.Face
+	b.Background, b.Fade, b.WaveColor = a.Background, a.Fade, a.WaveColor
+
+	// Same face and colors, different features: the face edges must line up
+	imgA, err := render(a, o)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	imgB, err := render(b, o)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	for x := 0; x < AvatarSize; x++ {
+		if imgA.RGBAAt(x, 0) != imgB.RGBAAt(x, 0) {
+			t.Fatalf("Expected identical top rows for the same face, differs at x=%d", x)
+		}
+	}
+}
+
+func TestFaceCropDimensions(t *testing.T) {
+	for _, hash := range []string{"test@example.com", "user1@example.com"} {
+		img, err := Generate([]byte(hash), WithFaceCrop(4))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if b := img.Bounds(); b != image.Rect(0, 0, AvatarSize, AvatarSize) {
+			t.Errorf("Expected %dx%d bounds, got %v", AvatarSize, AvatarSize, b)
+		}
+
+		plain, err := Generate([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(plain, img); stats.Changed == 0 {
+			t.Error("Expected the crop to change the avatar")
+		}
+	}
+
+	if _, err := Generate(nil, WithFaceCrop(-1)); err == nil {
+		t.Error("Expected an error for a negative margin")
+	}
+}
@@ -0,0 +1,159 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+const (
+	// darkBgLightness is the background lightness of the dark theme on the 0-240 scale
+	darkBgLightness = 20
+	// darkFadeIntensity scales the fade of the dark theme so it doesn't wash out the background
+	darkFadeIntensity = 0.5
+	// darkTolerance is how far, per channel, a pixel may be from the fade blend
+	// of the background to still count as background
+	darkTolerance = 6
+)
+
+// WithDarkTheme renders the background at a low lightness of the same hue
+// with a softer fade, for dark interfaces. The face is unchanged.
+func WithDarkTheme() Option {
+	return func(o *options) error {
+		o.bgLightness = darkBgLightness
+		o.fadeIntensity = darkFadeIntensity
+		return nil
+	}
+}
+
+// DarkVariant derives the WithDarkTheme look from an already rendered avatar,
+// such as a cached light one whose hash is no longer at hand. The background
+// color is estimated from the border, assuming the fully saturated colors of
+// the default backgrounds, and only pixels reachable from the border that
+// blend it with the white fade are changed, so the face and its features stay
+// untouched.
+func DarkVariant(img image.Image) (image.Image, error) {
+	dst := toRGBA(img)
+	w, h := dst.Rect.Dx(), dst.Rect.Dy()
+	if w < 1 || h < 1 {
+		return nil, fmt.Errorf("wavatar: cannot derive a dark variant of an empty image")
+	}
+
+	bg, ok := borderBackground(dst)
+	if !ok {
+		return nil, fmt.Errorf("wavatar: no opaque background found on the image border")
+	}
+	// Below lightness 120 the background channels scale linearly with lightness
+	scale := float64(darkBgLightness) / float64(defaultOptions().bgLightness)
+	var dark [3]float64
+	for ch := range dark {
+		dark[ch] = math.Floor(float64(bg[ch]) * scale)
+	}
+
+	// The channel furthest from white measures the fade most precisely
+	ref := 0
+	for ch := 1; ch < 3; ch++ {
+		if bg[ch] < bg[ref] {
+			ref = ch
+		}
+	}
+
+	// fade returns how much white the fade mixed into the pixel at i, if it is background at all
+	fade := func(i int) (float64, bool) {
+		p := dst.Pix[i : i+4 : i+4]
+		if p[3] != 255 || bg[ref] == 255 {
+			return 0, false
+		}
+		a := (float64(p[ref]) - float64(bg[ref])) / (255 - float64(bg[ref]))
+		if a < -float64(darkTolerance)/255 {
+			return 0, false
+		}
+		a = max(0, min(1, a))
+		for ch := 0; ch < 3; ch++ {
+			want := float64(bg[ch]) + a*(255-float64(bg[ch]))
+			if math.Abs(float64(p[ch])-want) > darkTolerance {
+				return 0, false
+			}
+		}
+		return a, true
+	}
+
+	// Flood the background in from the border, so matching colors inside the face are left alone
+	type point struct{ x, y int }
+	seen := make([]bool, w*h)
+	var queue []point
+	visit := func(x, y int) {
+		if x < 0 || x >= w || y < 0 || y >= h || seen[y*w+x] {
+			return
+		}
+		seen[y*w+x] = true
+		if _, ok := fade(y*dst.Stride + 4*x); ok {
+			queue = append(queue, point{x, y})
+		}
+	}
+	for x := 0; x < w; x++ {
+		visit(x, 0)
+		visit(x, h-1)
+	}
+	for y := 0; y < h; y++ {
+		visit(0, y)
+		visit(w-1, y)
+	}
+
+	for head := 0; head < len(queue); head++ {
+		p := queue[head]
+		visit(p.x+1, p.y)
+		visit(p.x-1, p.y)
+		visit(p.x, p.y+1)
+		visit(p.x, p.y-1)
+	}
+
+	for _, p := range queue {
+		i := p.y*dst.Stride + 4*p.x
+		a, _ := fade(i)
+		a *= darkFadeIntensity
+		for ch := 0; ch < 3; ch++ {
+			dst.Pix[i+ch] = uint8(math.Round(dark[ch] + a*(255-dark[ch])))
+		}
+	}
+	return dst, nil
+}
+
+// borderBackground estimates the background color of img from its darkest
+// opaque border pixel, as the fade only ever lightens the background.
+// Backgrounds are assumed fully saturated, as the hue wheel renders them.
+func borderBackground(img *image.RGBA) ([3]uint8, bool) {
+	b := img.Rect
+	var best color.RGBA
+	found := false
+	check := func(x, y int) {
+		c := img.RGBAAt(x, y)
+		if c.A != 255 {
+			return
+		}
+		if !found || int(c.R)+int(c.G)+int(c.B) < int(best.R)+int(best.G)+int(best.B) {
+			best, found = c, true
+		}
+	}
+	for x := b.Min.X; x < b.Max.X; x++ {
+		check(x, b.Min.Y)
+		check(x, b.Max.Y-1)
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		check(b.Min.X, y)
+		check(b.Max.X-1, y)
+	}
+	bg := [3]uint8{best.R, best.G, best.B}
+
+	// Where the fade covers the whole border, follow the blend with white back
+	// to the saturated color, with its smallest channel at 0, it started from
+	low := min(bg[0], bg[1], bg[2])
+	if low > 0 && low < 255 {
+		t := 255 / (255 - float64(low))
+		for ch := range bg {
+			bg[ch] = uint8(math.Round(max(0, 255-t*(255-float64(bg[ch])))))
+		}
+	}
+	return bg, found
+}
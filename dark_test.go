@@ -0,0 +1,102 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"testing"
+)
+
+// meanChannelDiff returns the mean absolute difference of the color channels of a and b
+func meanChannelDiff(a, b *image.RGBA) float64 {
+	sum := 0.0
+	for i := 0; i < len(a.Pix); i += 4 {
+		for ch := 0; ch < 3; ch++ {
+			sum += math.Abs(float64(a.Pix[i+ch]) - float64(b.Pix[i+ch]))
+		}
+	}
+	return sum / float64(3*len(a.Pix)/4)
+}
+
+func TestDarkVariantMatchesDarkTheme(t *testing.T) {
+	for i := range 40 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+
+		got, err := DarkVariant(New(hash))
+		if err != nil {
+			t.Fatalf("Failed to derive the dark variant: %v", err)
+		}
+		want, err := Generate(hash, WithDarkTheme())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+
+		// The two blend the fade with different rounding, and the anti-aliased
+		// edge of the face keeps some of the light background
+		if d := meanChannelDiff(got.(*image.RGBA), want.(*image.RGBA)); d > 3 {
+			t.Errorf("%s: expected the dark variant within 3 of the dark theme on average, got %.2f", hash, d)
+		}
+	}
+}
+
+func TestDarkVariantKeepsFace(t *testing.T) {
+	hash := []byte("test@example.com")
+	light := New(hash).(*image.RGBA)
+	dark, err := DarkVariant(light)
+	if err != nil {
+		t.Fatalf("Failed to derive the dark variant: %v", err)
+	}
+
+	mask, err := FaceMask(hash)
+	if err != nil {
+		t.Fatalf("Failed to compute the face mask: %v", err)
+	}
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			if mask.AlphaAt(x, y).A == 255 && dark.(*image.RGBA).RGBAAt(x, y) != light.RGBAAt(x, y) {
+				t.Fatalf("Expected the face pixel at %d,%d to be unchanged", x, y)
+			}
+		}
+	}
+	if corner := dark.(*image.RGBA).RGBAAt(0, 0); corner == light.RGBAAt(0, 0) {
+		t.Error("Expected the background to change")
+	}
+}
+
+func TestDarkVariantCloseHues(t *testing.T) {
+	// With the wave in the background hue, the face must not be mistaken for background
+	for _, hue := range []int{1, 60, 121, 200} {
+		s := Describe([]byte("test@example.com"))
+		s.Background, s.WaveColor = hue, hue
+
+		light, err := GenerateFromSpec(s)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		want, err := GenerateFromSpec(s, WithDarkTheme())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		got, err := DarkVariant(light)
+		if err != nil {
+			t.Fatalf("Failed to derive the dark variant: %v", err)
+		}
+
+		if d := meanChannelDiff(got.(*image.RGBA), want.(*image.RGBA)); d > 3 {
+			t.Errorf("Hue %d: expected the dark variant within 3 of the dark theme on average, got %.2f", hue, d)
+		}
+		center := image.Pt(AvatarSize/2, AvatarSize/2)
+		if got.(*image.RGBA).RGBAAt(center.X, center.Y) != light.(*image.RGBA).RGBAAt(center.X, center.Y) {
+			t.Errorf("Hue %d: expected the face to be unchanged", hue)
+		}
+	}
+}
+
+func TestDarkVariantTransparent(t *testing.T) {
+	if _, err := DarkVariant(image.NewRGBA(image.Rect(0, 0, 4, 4))); err == nil {
+		t.Error("Expected an error without an opaque background")
+	}
+	if _, err := DarkVariant(image.NewRGBA(image.Rectangle{})); err == nil {
+		t.Error("Expected an error for an empty image")
+	}
+}
@@ -0,0 +1,28 @@
+package wavatar
+
+import "sync/atomic"
+
+// builtinGenerator renders with the embedded parts and no options
+var builtinGenerator = &Generator{parts: defaultParts, counts: defaultCounts}
+
+// defaultGenerator is the Generator set with SetDefault, nil for builtinGenerator
+var defaultGenerator atomic.Pointer[Generator]
+
+// Default returns the Generator that New, Generate and the other
+// package-level functions use
+func Default() *Generator {
+	if g := defaultGenerator.Load(); g != nil {
+		return g
+	}
+	return builtinGenerator
+}
+
+// SetDefault makes New, Generate and the other package-level functions use
+// g, so options such as WithDarkTheme can be configured once at startup
+// instead of at every call. Describe and NewFromSpec follow it too, so
+// NewFromSpec(Describe(hash)) keeps rendering like New(hash). It is safe to
+// call at any time; calls already running finish with the previous Generator.
+// A nil g restores the embedded parts without options.
+func SetDefault(g *Generator) {
+	defaultGenerator.Store(g)
+}
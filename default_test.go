@@ -0,0 +1,172 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSetDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	hash := []byte("test@example.com")
+	light := New(hash).(*image.RGBA)
+	held := Default()
+
+	dark, err := NewGenerator(nil, WithDarkTheme())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(dark)
+	if Default() != dark {
+		t.Fatal("Expected Default to return the new Generator")
+	}
+
+	want, err := dark.Generate(hash)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if got := New(hash).(*image.RGBA); !bytes.Equal(got.Pix, want.(*image.RGBA).Pix) {
+		t.Error("Expected New to use the new default")
+	}
+	if got, _ := Generate(hash); !bytes.Equal(got.(*image.RGBA).Pix, want.(*image.RGBA).Pix) {
+		t.Error("Expected Generate to use the new default")
+	}
+
+	// A Generator held from before keeps its own configuration
+	if got, _ := held.Generate(hash); !bytes.Equal(got.(*image.RGBA).Pix, light.Pix) {
+		t.Error("Expected the previous default to be unaffected")
+	}
+
+	SetDefault(nil)
+	if got := New(hash).(*image.RGBA); !bytes.Equal(got.Pix, light.Pix) {
+		t.Error("Expected SetDefault(nil) to restore the built-in default")
+	}
+}
+
+func TestSetDefaultConcurrent(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	hash := []byte("test@example.com")
+	light := New(hash).(*image.RGBA).Pix
+	dark, err := NewGenerator(nil, WithDarkTheme())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	darkImg, _ := dark.Generate(hash)
+	darkPix := darkImg.(*image.RGBA).Pix
+
+	// Readers only ever see one of the two complete configurations
+	var sawLight, sawDark atomic.Bool
+	var lightOnce sync.Once
+	seenLight, swapped := make(chan struct{}), make(chan struct{})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				got := New(hash).(*image.RGBA).Pix
+				switch {
+				case bytes.Equal(got, light):
+					sawLight.Store(true)
+					lightOnce.Do(func() { close(seenLight) })
+				case bytes.Equal(got, darkPix):
+					sawDark.Store(true)
+				default:
+					t.Error("Expected either the light or the dark avatar")
+					return
+				}
+				select {
+				case <-swapped:
+					if sawDark.Load() {
+						return
+					}
+				default:
+				}
+			}
+		}()
+	}
+
+	go func() {
+		<-seenLight
+		SetDefault(dark)
+		close(swapped)
+	}()
+	wg.Wait()
+
+	if !sawLight.Load() || !sawDark.Load() {
+		t.Errorf("Expected both defaults to be observed, light %v dark %v", sawLight.Load(), sawDark.Load())
+	}
+}
+
+// Every package-level entry point renders like New once the default changes
+func TestSetDefaultEntryPoints(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	hash := []byte("test@example.com")
+	g, err := NewGenerator(nil, WithDarkTheme(), WithVersion(V2))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(g)
+	want := New(hash).(*image.RGBA)
+	if builtin, _ := builtinGenerator.Generate(hash); bytes.Equal(builtin.(*image.RGBA).Pix, want.Pix) {
+		t.Fatal("Expected the default Generator to render differently from the built-in one")
+	}
+
+	same := func(name string, img image.Image) {
+		t.Helper()
+		if _, stats, err := DiffImage(want, img); err != nil || stats.Changed != 0 {
+			t.Errorf("%s: expected the avatar of New, got %d changed pixels (%v)", name, stats.Changed, err)
+		}
+	}
+
+	s := Describe(hash)
+	if want, _ := g.Resolve(hash); s.Mouth != want.Mouth || s.Background != want.Background {
+		t.Errorf("Describe: expected %v, got %v", want, s)
+	}
+	same("NewFromSpec(Describe)", NewFromSpec(s))
+	img, err := GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Failed to render spec: %v", err)
+	}
+	same("GenerateFromSpec(Describe)", img)
+
+	resolved, err := Resolve(hash)
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	same("NewFromSpec(Resolve)", NewFromSpec(resolved))
+	if got := ColorForBytes(hash); got != resolved.BackgroundRGBA {
+		t.Errorf("ColorForBytes: expected %v, got %v", resolved.BackgroundRGBA, got)
+	}
+
+	thumbs, err := Thumbnails(hash, []int{AvatarSize})
+	if err != nil {
+		t.Fatalf("Failed to render thumbnails: %v", err)
+	}
+	same("Thumbnails", thumbs[AvatarSize])
+
+	batch, _, err := GenerateBatch([][]byte{hash}, BatchConfig{})
+	if err != nil {
+		t.Fatalf("Failed to render batch: %v", err)
+	}
+	same("GenerateBatch", batch[0])
+
+	left, _ := NewPair(hash, LayerMouth)
+	same("NewPair", left)
+
+	var buf bytes.Buffer
+	if _, err := EncodeUnderBudget(&buf, hash, 1<<20, "png"); err != nil {
+		t.Fatalf("Failed to encode under budget: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	same("EncodeUnderBudget", decoded)
+}
@@ -0,0 +1,60 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// DiffStats summarizes the differences found by DiffImage
+type DiffStats struct {
+	// Changed is the number of pixels that differ
+	Changed int
+	// MaxDelta is the largest difference seen in any single channel
+	MaxDelta uint8
+	// Bounds is the smallest rectangle containing every changed pixel, empty if none changed
+	Bounds image.Rectangle
+}
+
+// DiffImage compares two renders of the same size and returns a review image
+// where unchanged pixels are dimmed to grayscale and changed pixels are shown
+// in a heat color from yellow (small change) to red (large change)
+func DiffImage(a, b image.Image) (image.Image, DiffStats, error) {
+	var stats DiffStats
+	if a.Bounds() != b.Bounds() {
+		return nil, stats, fmt.Errorf("wavatar: cannot diff images with bounds %v and %v", a.Bounds(), b.Bounds())
+	}
+
+	ra, rb := toRGBA(a), toRGBA(b)
+	bounds := ra.Bounds()
+	dst := image.NewRGBA(bounds)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ca, cb := ra.RGBAAt(x, y), rb.RGBAAt(x, y)
+			delta := max(absDiff(ca.R, cb.R), absDiff(ca.G, cb.G), absDiff(ca.B, cb.B), absDiff(ca.A, cb.A))
+
+			if delta == 0 {
+				// Rec. 601 luma, dimmed to half brightness
+				gray := uint8((299*int(ca.R) + 587*int(ca.G) + 114*int(ca.B)) / 2000)
+				dst.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+				continue
+			}
+
+			stats.Changed++
+			stats.MaxDelta = max(stats.MaxDelta, delta)
+			stats.Bounds = stats.Bounds.Union(image.Rect(x, y, x+1, y+1))
+			dst.SetRGBA(x, y, color.RGBA{R: 255, G: 255 - delta, A: 255})
+		}
+	}
+
+	return dst, stats, nil
+}
+
+// absDiff returns the absolute difference between two channel values
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
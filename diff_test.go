@@ -0,0 +1,54 @@
+package wavatar
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDiffImageIdentical(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	diff, stats, err := DiffImage(img, img)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if stats != (DiffStats{}) {
+		t.Errorf("Expected zero stats for identical images, got %+v", stats)
+	}
+	if diff.Bounds() != img.Bounds() {
+		t.Errorf("Expected diff bounds %v, got %v", img.Bounds(), diff.Bounds())
+	}
+}
+
+func TestDiffImageLocalizesChangedLayer(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	// Draw an extra mouth on top so only the mouth area changes
+	variant := toRGBA(img)
+	mustApplyImage(t, variant, "mouth", 11)
+
+	mouthBounds := partBounds(t, "mouth", 11)
+
+	_, stats, err := DiffImage(img, variant)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if stats.Changed == 0 || stats.Bounds.Empty() {
+		t.Fatal("Expected changed pixels for a different mouth")
+	}
+	if !stats.Bounds.In(mouthBounds) {
+		t.Errorf("Expected changes within the mouth area %v, got %v", mouthBounds, stats.Bounds)
+	}
+	if stats.MaxDelta == 0 {
+		t.Error("Expected a non-zero maximum delta")
+	}
+}
+
+func TestDiffImageMismatchedBounds(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	b := image.NewRGBA(image.Rect(0, 0, 10, 11))
+
+	if _, _, err := DiffImage(a, b); err == nil {
+		t.Error("Expected an error for mismatched bounds")
+	}
+}
@@ -0,0 +1,120 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DitherMode selects how colors missing from a reduced palette are approximated
+type DitherMode int
+
+const (
+	// DitherNone maps every pixel to its closest palette color
+	DitherNone DitherMode = iota
+	// DitherOrdered offsets every pixel by a 4x4 Bayer matrix before mapping it
+	DitherOrdered
+	// DitherFloydSteinberg diffuses the error of every pixel to its unvisited neighbors
+	DitherFloydSteinberg
+)
+
+// WithDithering sets how palette-limited outputs, such as the reduced
+// palettes of EncodeUnderBudget, approximate colors. The default is DitherNone.
+func WithDithering(mode DitherMode) Option {
+	return func(o *options) error {
+		if mode < DitherNone || mode > DitherFloydSteinberg {
+			return fmt.Errorf("wavatar: unknown dithering mode %d", mode)
+		}
+		o.dither = mode
+		return nil
+	}
+}
+
+// bayer4 is the 4x4 ordered dithering matrix with thresholds 0-15
+var bayer4 = [4][4]float64{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// dither maps img onto pal using mode. Fully transparent pixels map to the
+// palette color closest to transparency and take no part in error diffusion,
+// so the error of the face never bleeds into the transparent surroundings of
+// a cutout avatar and leaves a halo there.
+func dither(img *image.RGBA, pal color.Palette, mode DitherMode) *image.Paletted {
+	dst := image.NewPaletted(img.Rect, pal)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	transparent := uint8(pal.Index(color.RGBA{}))
+	// Ordered dithering spreads pixels over about one step of a palette of this size
+	spread := 255 / math.Cbrt(float64(len(pal)))
+
+	// cur and next hold the diffused error of this and the next row, offset by one column
+	cur, next := make([][3]float64, w+2), make([][3]float64, w+2)
+	opaque := func(x, y int) bool {
+		return x >= 0 && x < w && y < h && img.Pix[y*img.Stride+4*x+3] != 0
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			p := img.Pix[y*img.Stride+4*x : y*img.Stride+4*x+4]
+			if p[3] == 0 {
+				dst.Pix[y*dst.Stride+x] = transparent
+				continue
+			}
+
+			c := [3]float64{float64(p[0]), float64(p[1]), float64(p[2])}
+			for ch := range c {
+				switch mode {
+				case DitherOrdered:
+					c[ch] += ((bayer4[y%4][x%4]+0.5)/16 - 0.5) * spread * float64(p[3]) / 255
+				case DitherFloydSteinberg:
+					c[ch] += cur[x+1][ch]
+				}
+			}
+
+			// Premultiplied channels never exceed alpha
+			target := color.RGBA{A: p[3]}
+			target.R = uint8(math.Round(math.Max(0, math.Min(float64(p[3]), c[0]))))
+			target.G = uint8(math.Round(math.Max(0, math.Min(float64(p[3]), c[1]))))
+			target.B = uint8(math.Round(math.Max(0, math.Min(float64(p[3]), c[2]))))
+			idx := pal.Index(target)
+			dst.Pix[y*dst.Stride+x] = uint8(idx)
+
+			if mode != DitherFloydSteinberg {
+				continue
+			}
+			r, g, b, _ := pal[idx].RGBA()
+			e := [3]float64{c[0] - float64(r>>8), c[1] - float64(g>>8), c[2] - float64(b>>8)}
+
+			// Only visible neighbors share the error, in the usual proportions
+			neighbors := [4]struct {
+				dx, dy int
+				weight float64
+			}{{1, 0, 7}, {-1, 1, 3}, {0, 1, 5}, {1, 1, 1}}
+			total := 0.0
+			for _, n := range neighbors {
+				if opaque(x+n.dx, y+n.dy) {
+					total += n.weight
+				}
+			}
+			for _, n := range neighbors {
+				if !opaque(x+n.dx, y+n.dy) {
+					continue
+				}
+				row := cur
+				if n.dy == 1 {
+					row = next
+				}
+				for ch := range e {
+					row[x+n.dx+1][ch] += e[ch] * n.weight / total
+				}
+			}
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+
+	return dst
+}
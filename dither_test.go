@@ -0,0 +1,113 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"testing"
+)
+
+// gradientCutout renders an avatar over a horizontal gradient with everything
+// outside a centered circle made transparent, like a round profile picture
+func gradientCutout(t *testing.T) *image.RGBA {
+	t.Helper()
+
+	gradient := WithBackgroundFunc(func(dst *image.RGBA, seed uint64) {
+		for y := 0; y < dst.Rect.Dy(); y++ {
+			for x := 0; x < dst.Rect.Dx(); x++ {
+				v := uint8(x * 255 / (dst.Rect.Dx() - 1))
+				dst.SetRGBA(x, y, color.RGBA{R: v, G: 64, B: 255 - v, A: 255})
+			}
+		}
+	})
+	circle := WithPostProcess(func(img *image.RGBA) {
+		r := float64(AvatarSize) / 2
+		for y := 0; y < AvatarSize; y++ {
+			for x := 0; x < AvatarSize; x++ {
+				if math.Hypot(float64(x)+0.5-r, float64(y)+0.5-r) > r {
+					img.SetRGBA(x, y, color.RGBA{})
+				}
+			}
+		}
+	})
+
+	img, err := Generate([]byte("test@example.com"), gradient, circle)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	return img.(*image.RGBA)
+}
+
+// perceptualError is the mean squared difference of a and b over the visible
+// pixels of a after a 5x5 box blur, which approximates how dithering patterns
+// blend together at a viewing distance
+func perceptualError(a, b *image.RGBA) float64 {
+	ba, bb := toRGBA(Blur(a, 2)), toRGBA(Blur(b, 2))
+	sum, n := 0.0, 0
+	for i := 0; i < len(a.Pix); i += 4 {
+		if a.Pix[i+3] != 255 {
+			continue
+		}
+		for ch := 0; ch < 3; ch++ {
+			d := float64(ba.Pix[i+ch]) - float64(bb.Pix[i+ch])
+			sum += d * d
+		}
+		n++
+	}
+	return sum / float64(n)
+}
+
+func TestDitherErrorOrdering(t *testing.T) {
+	src := gradientCutout(t)
+
+	errs := map[DitherMode]float64{}
+	for _, mode := range []DitherMode{DitherNone, DitherOrdered, DitherFloydSteinberg} {
+		errs[mode] = perceptualError(src, toRGBA(quantize(src, 16, mode)))
+	}
+	if !(errs[DitherFloydSteinberg] <= errs[DitherOrdered] && errs[DitherOrdered] <= errs[DitherNone]) {
+		t.Errorf("Expected Floyd-Steinberg <= ordered <= none, got %v <= %v <= %v",
+			errs[DitherFloydSteinberg], errs[DitherOrdered], errs[DitherNone])
+	}
+}
+
+func TestDitherNoHalo(t *testing.T) {
+	src := gradientCutout(t)
+
+	for _, mode := range []DitherMode{DitherNone, DitherOrdered, DitherFloydSteinberg} {
+		dst := quantize(src, 16, mode)
+		for y := 0; y < AvatarSize; y++ {
+			for x := 0; x < AvatarSize; x++ {
+				want := src.RGBAAt(x, y).A
+				_, _, _, a := dst.At(x, y).RGBA()
+				if want == 0 && a != 0 {
+					t.Fatalf("Mode %d: expected transparent pixel at %d,%d, got alpha %d", mode, x, y, a>>8)
+				}
+				if want == 255 && a != 0xffff {
+					t.Fatalf("Mode %d: expected opaque pixel at %d,%d, got alpha %d", mode, x, y, a>>8)
+				}
+			}
+		}
+	}
+}
+
+func TestDitherNoneMatchesNearest(t *testing.T) {
+	src := gradientCutout(t)
+	dst := quantize(src, 16, DitherNone)
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			if want := dst.Palette.Index(src.RGBAAt(x, y)); int(dst.ColorIndexAt(x, y)) != want {
+				t.Fatalf("Expected the nearest palette color at %d,%d", x, y)
+			}
+		}
+	}
+}
+
+func TestWithDitheringValidation(t *testing.T) {
+	if _, err := Generate(nil, WithDithering(DitherMode(7))); err == nil {
+		t.Error("Expected an error for an unknown dithering mode")
+	}
+	if _, err := EncodeUnderBudget(io.Discard, []byte("test@example.com"), 1<<20, "png", WithDithering(DitherFloydSteinberg)); err != nil {
+		t.Errorf("Expected dithered encoding to succeed, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package wavatar
+
+import (
+	"bytes"
+	"hash/fnv"
+	"strings"
+)
+
+// domainBands is the number of hue families domains are spread over
+const domainBands = 12
+
+// WithDomainHue gives every domain its own family of background hues while
+// users within it still vary. extract returns the domain of an input, or ""
+// to keep the normal background; nil uses the part after the last '@' of
+// email-shaped inputs. Only Generate sees the input, GenerateFromSpec ignores
+// this option.
+func WithDomainHue(extract func(input []byte) string) Option {
+	return func(o *options) error {
+		if extract == nil {
+			extract = emailDomain
+		}
+		o.domainHue = extract
+		return nil
+	}
+}
+
+// emailDomain returns the lowercased domain of an email address, or "" if input is not one
+func emailDomain(input []byte) string {
+	at := bytes.LastIndexByte(input, '@')
+	if at <= 0 || at == len(input)-1 {
+		return ""
+	}
+	return strings.ToLower(string(input[at+1:]))
+}
+
+// domainHue maps hue in the 1-240 range into the hue band picked by domain
+func domainHue(domain string, hue int) int {
+	if domain == "" {
+		return hue
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(domain))
+	width := 240 / domainBands
+	band := int(h.Sum32() % domainBands)
+	return band*width + (hue-1)*width/240 + 1
+}
@@ -0,0 +1,70 @@
+package wavatar
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// backgroundBand returns the domain hue band of the avatar generated for input
+func backgroundBand(t *testing.T, input string, opts ...Option) int {
+	t.Helper()
+
+	o, err := newOptions(opts)
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+	hue := domainHue(o.domainHue([]byte(input)), Describe([]byte(input)).Background)
+	return (hue - 1) / (240 / domainBands)
+}
+
+func TestDomainHueSharedBand(t *testing.T) {
+	band := backgroundBand(t, "user0@acme.com", WithDomainHue(nil))
+	for i := 1; i < 50; i++ {
+		input := fmt.Sprintf("user%d@ACME.com", i)
+		if got := backgroundBand(t, input, WithDomainHue(nil)); got != band {
+			t.Errorf("Expected %s in band %d, got %d", input, band, got)
+		}
+	}
+
+	bands := make(map[int]bool)
+	for i := 0; i < 50; i++ {
+		bands[backgroundBand(t, fmt.Sprintf("user@domain%d.com", i), WithDomainHue(nil))] = true
+	}
+	if len(bands) < domainBands/2 {
+		t.Errorf("Expected different domains to spread over the bands, got %d bands", len(bands))
+	}
+}
+
+func TestDomainHueGenerate(t *testing.T) {
+	a, err := Generate([]byte("jane@acme.com"), WithDomainHue(nil))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	b, err := Generate([]byte("jane@acme.com"), WithDomainHue(nil))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(a, b); stats.Changed != 0 {
+		t.Errorf("Expected identical avatars for the same input, got %d changed pixels", stats.Changed)
+	}
+
+	// Inputs without a domain keep the normal background
+	plain, err := Generate([]byte("not an email"), WithDomainHue(nil))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(plain, New([]byte("not an email"))); stats.Changed != 0 {
+		t.Errorf("Expected the normal avatar without a domain, got %d changed pixels", stats.Changed)
+	}
+}
+
+func TestDomainHueCustomExtract(t *testing.T) {
+	tenant := func(input []byte) string {
+		tenant, _, _ := strings.Cut(string(input), "/")
+		return tenant
+	}
+	if a, b := backgroundBand(t, "acme/jane", WithDomainHue(tenant)), backgroundBand(t, "acme/john", WithDomainHue(tenant)); a != b {
+		t.Errorf("Expected users of one tenant to share a band, got %d and %d", a, b)
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !wavatar_noembed
+
+package wavatar
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+)
+
+//go:generate go run gen_parts.go
+
+//go:embed parts.zip
+var archive []byte
+
+// embeddedParts returns the parts compiled into the package. A broken archive
+// fails every part as it is requested instead of the program at start.
+func embeddedParts() *partSet {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return newPartCache(func(string) (image.Image, error) {
+			return nil, fmt.Errorf("open embedded archive: %w", err)
+		})
+	}
+	return newArchivePartSet(zr)
+}
@@ -0,0 +1,12 @@
+//go:build wavatar_noembed
+
+package wavatar
+
+// embeddedParts returns nil, builds with the wavatar_noembed tag carry no
+// parts and need a Generator with an explicit part source
+func embeddedParts() *partSet {
+	return nil
+}
+
+// archive is empty, there are no embedded parts to fingerprint
+var archive []byte
@@ -0,0 +1,112 @@
+//go:build !wavatar_noembed
+
+package wavatar
+
+import (
+	"archive/zip"
+	"bytes"
+	"image"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// freshArchiveParts returns a partSet over the embedded archive with an empty cache
+func freshArchiveParts(t *testing.T) *partSet {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Failed to open archive: %v", err)
+	}
+	return newArchivePartSet(zr)
+}
+
+func TestArchiveMatchesParts(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("parts", "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pngBytes := 0
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pngBytes += int(info.Size())
+	}
+	t.Logf("Embedded parts: %d bytes as PNG, %d bytes as archive (%+d)", pngBytes, len(archive), len(archive)-pngBytes)
+
+	archived := freshArchiveParts(t)
+	loose := newPartSet(os.DirFS("parts"))
+	count := 0
+	for _, c := range partCounts {
+		for num := 1; num <= c.count; num++ {
+			a, err := archived.load(c.part, num)
+			if err != nil {
+				t.Fatalf("Failed to load %s%d from the archive: %v", c.part, num, err)
+			}
+			b, err := loose.load(c.part, num)
+			if err != nil {
+				t.Fatalf("Failed to load %s%d.png: %v", c.part, num, err)
+			}
+			if !bytes.Equal(a.(*image.NRGBA).Pix, toNRGBA(b).Pix) {
+				t.Errorf("Archived %s%d differs from the PNG, run go generate", c.part, num)
+			}
+			count++
+		}
+	}
+	if count != len(files) {
+		t.Errorf("Expected %d parts, found %d PNGs", count, len(files))
+	}
+}
+
+// toNRGBA converts img to *image.NRGBA
+func toNRGBA(img image.Image) *image.NRGBA {
+	if n, ok := img.(*image.NRGBA); ok {
+		return n
+	}
+	n := image.NewNRGBA(img.Bounds())
+	for y := img.Bounds().Min.Y; y < img.Bounds().Max.Y; y++ {
+		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
+			n.Set(x, y, img.At(x, y))
+		}
+	}
+	return n
+}
+
+func TestPartsConcurrentFirstUse(t *testing.T) {
+	parts := freshArchiveParts(t)
+
+	const workers = 8
+	results := make([][]image.Image, workers)
+	var wg sync.WaitGroup
+	for w := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, c := range partCounts {
+				for num := 1; num <= c.count; num++ {
+					img, err := parts.load(c.part, num)
+					if err != nil {
+						t.Errorf("Failed to load %s%d: %v", c.part, num, err)
+						return
+					}
+					results[w] = append(results[w], img)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every worker must get the single decoded instance of each part
+	for w := 1; w < workers; w++ {
+		for i := range results[0] {
+			if results[w][i] != results[0][i] {
+				t.Fatalf("Worker %d got a different image for part %d", w, i)
+			}
+		}
+	}
+}
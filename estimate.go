@@ -0,0 +1,129 @@
+package wavatar
+
+import (
+	"fmt"
+	"image/png"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Format names an encoding of avatars
+type Format string
+
+const (
+	// FormatPNG is PNG at the default compression, see WritePNG
+	FormatPNG Format = "png"
+	// FormatWebP is lossless WebP, see EncodeWebP
+	FormatWebP Format = "webp"
+	// FormatSVG is the layered SVG document of EncodeSVG
+	FormatSVG Format = "svg"
+)
+
+// estimateSeed seeds the hashes EstimateSize samples, fixed so estimates
+// compare across runs and releases
+const estimateSeed = 0x77617661746172
+
+// SizeEstimate describes the encoded sizes of a sample of avatars in bytes
+type SizeEstimate struct {
+	Samples int
+	Min     int
+	Mean    float64
+	P95     int
+	Max     int
+}
+
+// Total extrapolates the bytes n avatars take from the mean of the sample
+func (e SizeEstimate) Total(n int64) int64 {
+	return int64(math.Ceil(e.Mean * float64(n)))
+}
+
+// EstimateSize renders sampleSeeds avatars at size pixels square and
+// describes the sizes of their encodings in format, for capacity planning.
+// The sample is a fixed sequence of random hashes, the same for every call,
+// so estimates for different sizes, formats or options are comparable.
+// opts apply to every avatar, with size in place of any WithSize.
+func EstimateSize(sampleSeeds int, size int, format Format, opts ...Option) (SizeEstimate, error) {
+	if sampleSeeds < 1 {
+		return SizeEstimate{}, fmt.Errorf("wavatar: sample of %d seeds is empty", sampleSeeds)
+	}
+	encode, err := formatEncoder(format)
+	if err != nil {
+		return SizeEstimate{}, err
+	}
+	o, err := Default().options(append(slices.Clip(opts), WithSize(size)))
+	if err != nil {
+		return SizeEstimate{}, err
+	}
+
+	r := rand.New(rand.NewPCG(estimateSeed, estimateSeed))
+	sizes := make([]int, sampleSeeds)
+	for i := range sizes {
+		hash := make([]byte, 16)
+		for j := range hash {
+			hash[j] = byte(r.Uint32())
+		}
+		var w countingWriter
+		if err := encode(&w, hash, o); err != nil {
+			return SizeEstimate{}, err
+		}
+		sizes[i] = w.n
+	}
+	return estimateSizes(sizes), nil
+}
+
+// estimateSizes summarizes a non-empty sample of sizes, taking the 95th
+// percentile by the nearest rank
+func estimateSizes(sizes []int) SizeEstimate {
+	sorted := slices.Sorted(slices.Values(sizes))
+	total := 0
+	for _, n := range sorted {
+		total += n
+	}
+	rank := int(math.Ceil(0.95 * float64(len(sorted))))
+	return SizeEstimate{
+		Samples: len(sorted),
+		Min:     sorted[0],
+		Mean:    float64(total) / float64(len(sorted)),
+		P95:     sorted[rank-1],
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// formatEncoder returns the function that renders hash with o and encodes it in format
+func formatEncoder(format Format) (func(w *countingWriter, hash []byte, o *options) error, error) {
+	switch format {
+	case FormatPNG:
+		return func(w *countingWriter, hash []byte, o *options) error {
+			img, err := generateHash(hash, o)
+			if err != nil {
+				return err
+			}
+			return WritePNG(w, img, png.DefaultCompression)
+		}, nil
+	case FormatWebP:
+		return func(w *countingWriter, hash []byte, o *options) error {
+			img, err := generateHash(hash, o)
+			if err != nil {
+				return err
+			}
+			return EncodeWebP(w, img, -1)
+		}, nil
+	case FormatSVG:
+		return func(w *countingWriter, hash []byte, o *options) error {
+			return encodeSVGHash(w, hash, o)
+		}, nil
+	default:
+		return nil, fmt.Errorf("wavatar: unsupported format %q", format)
+	}
+}
+
+// countingWriter discards what is written to it, counting the bytes
+type countingWriter struct {
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
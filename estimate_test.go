@@ -0,0 +1,89 @@
+package wavatar
+
+import (
+	"testing"
+)
+
+func TestEstimateSizes(t *testing.T) {
+	// Twenty sizes, so the 95th percentile is the 19th
+	sizes := []int{500, 100, 300}
+	for i := range 17 {
+		sizes = append(sizes, 200+i)
+	}
+	got := estimateSizes(sizes)
+	mean := float64(500+100+300+17*200+16*17/2) / 20
+	want := SizeEstimate{Samples: 20, Min: 100, Mean: mean, P95: 300, Max: 500}
+	if got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	if got := estimateSizes([]int{42}); got != (SizeEstimate{Samples: 1, Min: 42, Mean: 42, P95: 42, Max: 42}) {
+		t.Errorf("Expected one sample to be every statistic, got %+v", got)
+	}
+	if got := want.Total(10_000_000); got != int64(mean*10_000_000) {
+		t.Errorf("Expected %d bytes for 10M avatars, got %d", int64(mean*10_000_000), got)
+	}
+}
+
+func TestEstimateSize(t *testing.T) {
+	png, err := EstimateSize(8, 160, FormatPNG)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	if png.Samples != 8 || png.Min <= 0 || float64(png.Min) > png.Mean || png.Mean > float64(png.Max) || png.P95 > png.Max {
+		t.Errorf("Expected consistent statistics, got %+v", png)
+	}
+
+	// The sample is the same every time
+	if again, _ := EstimateSize(8, 160, FormatPNG); again != png {
+		t.Errorf("Expected a reproducible estimate %+v, got %+v", png, again)
+	}
+
+	// Larger avatars take more bytes
+	small, err := EstimateSize(8, 40, FormatPNG)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	if small.Mean >= png.Mean {
+		t.Errorf("Expected 40px avatars below %.0f bytes, got %.0f", png.Mean, small.Mean)
+	}
+
+	// At the size of the parts lossless WebP beats PNG, and the SVG carrying
+	// every part as a PNG of its own takes the most
+	native, err := EstimateSize(8, AvatarSize, FormatPNG)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	webp, err := EstimateSize(8, AvatarSize, FormatWebP)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	svg, err := EstimateSize(8, AvatarSize, FormatSVG)
+	if err != nil {
+		t.Fatalf("Failed to estimate: %v", err)
+	}
+	if !(webp.Mean < native.Mean && native.Mean < svg.Mean) {
+		t.Errorf("Expected webp %.0f < png %.0f < svg %.0f bytes", webp.Mean, native.Mean, svg.Mean)
+	}
+	// SVG only changes the size it is shown at, a digit more in width and height
+	if scaled, _ := EstimateSize(8, 160, FormatSVG); scaled.Min != svg.Min+2 || scaled.Max != svg.Max+2 {
+		t.Errorf("Expected the SVG estimate at 160 to be 2 bytes over %+v, got %+v", svg, scaled)
+	}
+}
+
+func TestEstimateSizeInvalid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		samples int
+		size    int
+		format  Format
+	}{
+		{"no samples", 0, 80, FormatPNG},
+		{"size", 4, 2, FormatPNG},
+		{"format", 4, 80, "gif"},
+	} {
+		if _, err := EstimateSize(tt.samples, tt.size, tt.format); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package wavatar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// ExtractParts writes every embedded part into dir as a PNG named like
+// mask1.png, the layout NewGenerator reads, along with a manifest.json of
+// the number of parts per kind. dir is created if needed. Existing files are
+// only replaced when overwrite is set; otherwise nothing is written if any
+// of them exists.
+func ExtractParts(dir string, overwrite bool) error {
+	if defaultParts == nil {
+		return errNoParts
+	}
+
+	manifest := make(map[string]int, len(partCounts))
+	names := []string{"manifest.json"}
+	for _, c := range partCounts {
+		manifest[c.part] = c.count
+		for num := 1; num <= c.count; num++ {
+			names = append(names, fmt.Sprintf("%s%d.png", c.part, num))
+		}
+	}
+	if !overwrite {
+		for _, name := range names {
+			if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+				return fmt.Errorf("wavatar: %s already exists", filepath.Join(dir, name))
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return fmt.Errorf("wavatar: %w", err)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("wavatar: %w", err)
+	}
+	for _, c := range partCounts {
+		for num := 1; num <= c.count; num++ {
+			img, err := defaultParts.load(c.part, num)
+			if err != nil {
+				return err
+			}
+			if err := writeFile(filepath.Join(dir, fmt.Sprintf("%s%d.png", c.part, num)), func(f *os.File) error {
+				return png.Encode(f, img)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return fmt.Errorf("wavatar: %w", err)
+	}
+	return writeFile(filepath.Join(dir, "manifest.json"), func(f *os.File) error {
+		_, err := f.Write(append(data, '\n'))
+		return err
+	})
+}
+
+// writeFile creates or truncates the file at path and fills it with write
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("wavatar: %w", err)
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("wavatar: write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("wavatar: %w", err)
+	}
+	return nil
+}
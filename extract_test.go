@@ -0,0 +1,73 @@
+//go:build !wavatar_noembed
+
+package wavatar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExtractPartsRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "pack")
+	if err := ExtractParts(dir, false); err != nil {
+		t.Fatalf("Failed to extract parts: %v", err)
+	}
+
+	g, err := NewGenerator(os.DirFS(dir), WithCountPolicy(CountStrict))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	for i := range 50 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		img, err := g.Generate(hash)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+			t.Errorf("%s: expected the extracted parts to render like the embedded ones", hash)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	var manifest map[string]int
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Failed to parse manifest: %v", err)
+	}
+	for _, c := range partCounts {
+		if manifest[c.part] != c.count {
+			t.Errorf("Expected %d %s parts in the manifest, got %d", c.count, c.part, manifest[c.part])
+		}
+	}
+}
+
+func TestExtractPartsOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "mouth3.png")
+	if err := os.WriteFile(existing, []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ExtractParts(dir, false)
+	if err == nil || !strings.Contains(err.Error(), "mouth3.png") {
+		t.Fatalf("Expected an error naming mouth3.png, got %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected nothing written after refusing, got %d files", len(entries))
+	}
+
+	if err := ExtractParts(dir, true); err != nil {
+		t.Fatalf("Failed to extract parts: %v", err)
+	}
+	if data, _ := os.ReadFile(existing); bytes.Equal(data, []byte("mine")) {
+		t.Error("Expected mouth3.png to be replaced")
+	}
+}
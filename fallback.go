@@ -0,0 +1,52 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// WithDefaultOnEmpty renders img instead of an avatar for hashes that are
+// empty or all zero bytes, which some services send for users without an
+// email address, like the d= default image of Gravatar. img is scaled to the
+// size of the avatar, stretched if it is not square, and returned as is,
+// without the other options. With WithCanonicalization a hash whose
+// canonical form is all zeros, such as an MD5 of zeros in hex, counts too.
+// img is read on every such render, so it must not be changed afterwards.
+func WithDefaultOnEmpty(img image.Image) Option {
+	return func(o *options) error {
+		if img == nil {
+			return fmt.Errorf("wavatar: nil default image")
+		}
+		if img.Bounds().Empty() {
+			return fmt.Errorf("wavatar: default image bounds %v are empty", img.Bounds())
+		}
+		o.defaultOnEmpty = img
+		return nil
+	}
+}
+
+// emptyHash reports whether o renders its default image for hash
+func (o *options) emptyHash(hash []byte) bool {
+	if o.defaultOnEmpty == nil {
+		return false
+	}
+	if o.canonicalize {
+		hash, _ = Canonicalize(hash)
+	}
+	for _, b := range hash {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultImage returns the default image of o scaled to the avatar size
+func (o *options) defaultImage() *image.RGBA {
+	size := o.outputSize()
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, o.defaultOnEmpty, o.defaultOnEmpty.Bounds(), xdraw.Src, nil)
+	return dst
+}
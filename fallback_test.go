@@ -0,0 +1,87 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+// placeholder returns a uniform gray image of the given size
+func placeholder(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Rect, image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	return img
+}
+
+func TestWithDefaultOnEmpty(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	g, err := NewGenerator(nil, WithDefaultOnEmpty(placeholder(20, 40)))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(g)
+	want := placeholder(AvatarSize, AvatarSize)
+	for _, hash := range [][]byte{nil, {}, make([]byte, 16), make([]byte, 32)} {
+		got := New(hash).(*image.RGBA)
+		if got.Rect != want.Rect || !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("Expected the default image for %x, got an avatar of bounds %v", hash, got.Rect)
+		}
+	}
+
+	// Other hashes render as usual
+	SetDefault(nil)
+	for _, hash := range [][]byte{{0, 0, 1}, []byte("test@example.com")} {
+		got, err := g.Generate(hash)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if !bytes.Equal(got.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+			t.Errorf("Expected the avatar of %x to be unchanged", hash)
+		}
+	}
+}
+
+func TestWithDefaultOnEmptySize(t *testing.T) {
+	img, err := Generate(nil, WithDefaultOnEmpty(placeholder(80, 80)), WithSize(48))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if got := img.Bounds(); got != image.Rect(0, 0, 48, 48) {
+		t.Errorf("Expected the default image at 48x48, got %v", got)
+	}
+}
+
+func TestWithDefaultOnEmptyCanonical(t *testing.T) {
+	zeros := []byte(strings.Repeat("0", 32))
+	def := WithDefaultOnEmpty(placeholder(8, 8))
+
+	img, err := Generate(zeros, def, WithCanonicalization())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, placeholder(AvatarSize, AvatarSize).Pix) {
+		t.Error("Expected the default image for a hex digest of zeros with canonicalization")
+	}
+
+	// Without it the digits are an ordinary input
+	img, err = Generate(zeros, def)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(zeros).(*image.RGBA).Pix) {
+		t.Error("Expected the avatar of the hex digits without canonicalization")
+	}
+}
+
+func TestWithDefaultOnEmptyInvalid(t *testing.T) {
+	if _, err := newOptions([]Option{WithDefaultOnEmpty(nil)}); err == nil {
+		t.Error("Expected an error for a nil default image")
+	}
+	if _, err := newOptions([]Option{WithDefaultOnEmpty(image.NewRGBA(image.Rectangle{}))}); err == nil {
+		t.Error("Expected an error for an empty default image")
+	}
+}
@@ -0,0 +1,147 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// WithFillConnectivity sets whether the face fill spreads to the 4 direct
+// neighbors of a pixel, the default, or to all 8 including the diagonals.
+// With 8 a mask outline drawn in single-pixel diagonal steps no longer holds
+// the fill, while one drawn in 4-connected steps holds it either way.
+func WithFillConnectivity(n int) Option {
+	return func(o *options) error {
+		if n != 4 && n != 8 {
+			return fmt.Errorf("wavatar: fill connectivity must be 4 or 8, got %d", n)
+		}
+		o.fillConnectivity = n
+		return nil
+	}
+}
+
+// fillNeighbors returns the offsets of the neighbors a fill with the given connectivity spreads to
+func fillNeighbors(connectivity int) []image.Point {
+	if connectivity == 8 {
+		return []image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+	}
+	return []image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+}
+
+// WithAlphaFill fills the face by compositing the wave color beneath the mask
+// instead of replacing pixels of exactly the mask's white. The anti-aliased
+// edge between the face and its outline then blends into the wave color
+// rather than leaving a light halo.
+func WithAlphaFill() Option {
+	return func(o *options) error {
+		o.alphaFill = true
+		return nil
+	}
+}
+
+// alphaFill treats the opaque region of mask connected to (x,y), through the
+// 4 or 8 neighbors of every pixel as connectivity says, as ink over the wave
+// color: white lets col through fully, black hides it, and the gray
+// anti-aliasing in between shows it proportionally
+func alphaFill(img *image.RGBA, mask image.Image, x, y int, col color.RGBA, connectivity int) {
+	m := toRGBA(mask)
+	bounds := img.Bounds().Intersect(m.Bounds())
+	if !(image.Point{X: x, Y: y}).In(bounds) || m.RGBAAt(x, y).A != 255 {
+		return
+	}
+
+	type point struct{ x, y int }
+	visited := make([]bool, bounds.Dx()*bounds.Dy())
+	queue := []point{{x, y}}
+	visited[(y-bounds.Min.Y)*bounds.Dx()+x-bounds.Min.X] = true
+	neighbors := fillNeighbors(connectivity)
+
+	for len(queue) > 0 {
+		p := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		c := m.RGBAAt(p.x, p.y)
+		// Rec. 601 luma of the opaque mask pixel is how much wave color shows through
+		coverage := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+		img.SetRGBA(p.x, p.y, color.RGBA{
+			R: uint8((int(col.R)*coverage + 127) / 255),
+			G: uint8((int(col.G)*coverage + 127) / 255),
+			B: uint8((int(col.B)*coverage + 127) / 255),
+			A: 255,
+		})
+
+		for _, d := range neighbors {
+			n := point{p.x + d.X, p.y + d.Y}
+			if !(image.Point{X: n.x, Y: n.y}).In(bounds) {
+				continue
+			}
+			i := (n.y-bounds.Min.Y)*bounds.Dx() + n.x - bounds.Min.X
+			if visited[i] || m.RGBAAt(n.x, n.y).A != 255 {
+				continue
+			}
+			visited[i] = true
+			queue = append(queue, n)
+		}
+	}
+}
+
+// maskSeed returns where the face fill of mask starts: center when it lies in
+// the largest 4-connected region of opaque white, the interior a mask leaves
+// for the wave color, and otherwise the pixel of that region closest to
+// center, so a mask line crossing the center cannot leave the face unfilled.
+// A mask without any white keeps center.
+func maskSeed(mask image.Image, center image.Point) image.Point {
+	m := toRGBA(mask)
+	b := m.Rect
+	white := func(x, y int) bool {
+		i := m.PixOffset(x, y)
+		return m.Pix[i] == 255 && m.Pix[i+1] == 255 && m.Pix[i+2] == 255 && m.Pix[i+3] == 255
+	}
+	distance := func(p image.Point) int {
+		d := p.Sub(center)
+		return d.X*d.X + d.Y*d.Y
+	}
+
+	visited := make([]bool, b.Dx()*b.Dy())
+	seen := func(p image.Point) bool {
+		i := (p.Y-b.Min.Y)*b.Dx() + p.X - b.Min.X
+		was := visited[i]
+		visited[i] = true
+		return was
+	}
+
+	best, bestSize := center, 0
+	var stack []image.Point
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			start := image.Pt(x, y)
+			if !white(x, y) || seen(start) {
+				continue
+			}
+
+			// Measure the region, remembering its pixel closest to center
+			size, closest, hasCenter := 0, start, false
+			stack = append(stack[:0], start)
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				size++
+				hasCenter = hasCenter || p == center
+				if distance(p) < distance(closest) {
+					closest = p
+				}
+				for _, d := range fillNeighbors(4) {
+					n := p.Add(d)
+					if n.In(b) && white(n.X, n.Y) && !seen(n) {
+						stack = append(stack, n)
+					}
+				}
+			}
+			// The region of the center wins ties, it is where fills always started
+			if size > bestSize || size == bestSize && hasCenter {
+				best, bestSize = closest, size
+			}
+		}
+	}
+	return best
+}
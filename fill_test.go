@@ -0,0 +1,367 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+	"testing/fstest"
+)
+
+// neutralFacePixels counts light gray pixels left inside the face after filling
+func neutralFacePixels(img *image.RGBA) int {
+	n := 0
+	for i := 0; i < len(img.Pix); i += 4 {
+		r, g, b := img.Pix[i], img.Pix[i+1], img.Pix[i+2]
+		if r == g && g == b && r > 128 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestAlphaFillRemovesHalo(t *testing.T) {
+	for face := 1; face <= FaceCount; face++ {
+		s := Describe([]byte("test@example.com"))
+		s.Face = face
+		// A saturated wave color makes any untinted pixel stand out as neutral
+		s.WaveColor = 40
+
+		legacy := mustRenderFace(t, s, defaultOptions())
+		o := defaultOptions()
+		o.alphaFill = true
+		alpha := mustRenderFace(t, s, o)
+
+		before, after := neutralFacePixels(legacy), neutralFacePixels(alpha)
+		if before == 0 {
+			t.Errorf("Face %d: expected the color equality fill to leave a light halo", face)
+		}
+		if after >= before {
+			t.Errorf("Face %d: expected fewer halo pixels with the alpha fill, got %d >= %d", face, after, before)
+		}
+
+		// Fully white mask pixels get exactly the wave color in both fills
+		center := legacy.RGBAAt(AvatarSize/2, AvatarSize/2)
+		if got := alpha.RGBAAt(AvatarSize/2, AvatarSize/2); got != center {
+			t.Errorf("Face %d: expected center %v, got %v", face, center, got)
+		}
+	}
+}
+
+func TestAlphaFillGolden(t *testing.T) {
+	for name, hash := range map[string]string{"email": "test@example.com", "user1": "user1@example.com"} {
+		img, err := Generate([]byte(hash), WithAlphaFill())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		checkGolden(t, "alphafill-"+name, img)
+
+		legacy, err := Generate([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		_, stats, err := DiffImage(legacy, img)
+		if err != nil {
+			t.Fatalf("Failed to diff: %v", err)
+		}
+		if stats.Changed == 0 {
+			t.Error("Expected the alpha fill to change the face edges")
+		}
+	}
+}
+
+func TestAlphaFillOutsideMask(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	mask := image.NewRGBA(image.Rect(0, 0, 4, 4))
+
+	// Transparent seed pixel leaves the image untouched
+	alphaFill(img, mask, 1, 1, color.RGBA{R: 255, A: 255}, 4)
+	for _, v := range img.Pix {
+		if v != 0 {
+			t.Fatal("Expected no fill when the seed is outside the mask")
+		}
+	}
+}
+
+// diamondMask returns a white 21x21 mask with a transparent diamond outline
+// around its center, which stops both fills, drawn in single-pixel diagonal
+// steps or, when staircase is set, in 4-connected steps
+func diamondMask(staircase bool) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 21, 21))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	gap := color.RGBA{}
+	for i := 0; i <= 8; i++ {
+		for _, p := range []image.Point{{10 + i, 2 + i}, {18 - i, 10 + i}, {10 - i, 18 - i}, {2 + i, 10 - i}} {
+			img.SetRGBA(p.X, p.Y, gap)
+		}
+		if staircase {
+			for _, p := range []image.Point{{11 + i, 2 + i}, {17 - i, 10 + i}, {9 - i, 18 - i}, {3 + i, 10 - i}} {
+				img.SetRGBA(p.X, p.Y, gap)
+			}
+		}
+	}
+	return img
+}
+
+func TestFillConnectivityDiagonalOutline(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	tests := []struct {
+		staircase    bool
+		connectivity int
+		leaks        bool
+	}{
+		{false, 4, false},
+		{false, 8, true},
+		{true, 4, false},
+		{true, 8, false},
+	}
+	for _, tt := range tests {
+		flood := diamondMask(tt.staircase)
+		floodFill(flood, 10, 10, red, tt.connectivity)
+
+		alpha := image.NewRGBA(flood.Rect)
+		alphaFill(alpha, diamondMask(tt.staircase), 10, 10, red, tt.connectivity)
+
+		// Both fills reach the corner exactly when the outline leaks
+		if got := flood.RGBAAt(0, 0) == red; got != tt.leaks {
+			t.Errorf("Staircase %v, %d-way flood fill: expected leak %v, got %v", tt.staircase, tt.connectivity, tt.leaks, got)
+		}
+		if got := alpha.RGBAAt(0, 0) == red; got != tt.leaks {
+			t.Errorf("Staircase %v, %d-way alpha fill: expected leak %v, got %v", tt.staircase, tt.connectivity, tt.leaks, got)
+		}
+		if flood.RGBAAt(10, 10) != red || alpha.RGBAAt(10, 10) != red {
+			t.Errorf("Staircase %v, %d-way: expected the inside to be filled", tt.staircase, tt.connectivity)
+		}
+	}
+}
+
+func TestFillConnectivityEmbeddedFaces(t *testing.T) {
+	for face := 1; face <= FaceCount; face++ {
+		s := Describe([]byte("test@example.com"))
+		s.Face = face
+		for _, alpha := range []bool{false, true} {
+			four := defaultOptions()
+			four.alphaFill = alpha
+			eight := defaultOptions()
+			eight.alphaFill = alpha
+			eight.fillConnectivity = 8
+
+			_, stats, err := DiffImage(mustRenderFace(t, s, four), mustRenderFace(t, s, eight))
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if stats.Changed != 0 {
+				t.Errorf("Face %d, alpha fill %v: expected identical fills, %d pixels differ", face, alpha, stats.Changed)
+			}
+		}
+	}
+
+	if _, err := Generate(nil, WithFillConnectivity(6)); err == nil {
+		t.Error("Expected an error for a connectivity of 6")
+	}
+}
+
+// referenceFill fills like floodFill by visiting one pixel at a time
+func referenceFill(img *image.RGBA, x, y int, col color.RGBA, connectivity int) {
+	start := img.RGBAAt(x, y)
+	if start == col {
+		return
+	}
+	stack := []image.Point{{x, y}}
+	img.SetRGBA(x, y, col)
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, d := range fillNeighbors(connectivity) {
+			n := p.Add(d)
+			if n.In(img.Rect) && img.RGBAAt(n.X, n.Y) == start {
+				img.SetRGBA(n.X, n.Y, col)
+				stack = append(stack, n)
+			}
+		}
+	}
+}
+
+func TestFloodFillMatchesReference(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range 200 {
+		// Sparse walls give large irregular regions with many spans per row
+		img := image.NewRGBA(image.Rect(-3, 2, 37, 31))
+		for p := 0; p < len(img.Pix); p += 4 {
+			v := uint8(255)
+			if r.IntN(100) < 30+i%40 {
+				v = 0
+			}
+			img.Pix[p], img.Pix[p+1], img.Pix[p+2], img.Pix[p+3] = v, v, v, 255
+		}
+		x, y := img.Rect.Min.X+r.IntN(img.Rect.Dx()), img.Rect.Min.Y+r.IntN(img.Rect.Dy())
+
+		for _, connectivity := range []int{4, 8} {
+			got, want := toRGBA(img), toRGBA(img)
+			floodFill(got, x, y, red, connectivity)
+			referenceFill(want, x, y, red, connectivity)
+			if _, stats, _ := DiffImage(got, want); stats.Changed != 0 {
+				t.Fatalf("Image %d, %d-way: %d pixels differ from the reference fill", i, connectivity, stats.Changed)
+			}
+		}
+	}
+
+	// Every embedded face fills exactly like the reference
+	for face := 1; face <= FaceCount; face++ {
+		mask := toRGBA(mustLoadPart(t, "mask", face))
+		got, want := toRGBA(mask), toRGBA(mask)
+		floodFill(got, AvatarSize/2, AvatarSize/2, red, 4)
+		referenceFill(want, AvatarSize/2, AvatarSize/2, red, 4)
+		if _, stats, _ := DiffImage(got, want); stats.Changed != 0 {
+			t.Errorf("Face %d: %d pixels differ from the reference fill", face, stats.Changed)
+		}
+	}
+}
+
+func TestFloodFillMatchesReferenceRendered(t *testing.T) {
+	// The fill as renders see it, over the background and fade of every face
+	wave := color.RGBA{R: 200, G: 120, B: 40, A: 255}
+	for face := 1; face <= FaceCount; face++ {
+		s := Describe([]byte("test@example.com"))
+		s.Face = face
+		img, _, err := renderUnfilled(s, defaultOptions())
+		if err != nil {
+			t.Fatalf("Face %d: failed to render: %v", face, err)
+		}
+		for _, connectivity := range []int{4, 8} {
+			got, want := toRGBA(img), toRGBA(img)
+			floodFill(got, AvatarSize/2, AvatarSize/2, wave, connectivity)
+			referenceFill(want, AvatarSize/2, AvatarSize/2, wave, connectivity)
+			if _, stats, _ := DiffImage(got, want); stats.Changed != 0 {
+				t.Errorf("Face %d, %d-way: %d pixels differ from the reference fill", face, connectivity, stats.Changed)
+			}
+		}
+	}
+}
+
+func TestFloodFillAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	img := toRGBA(New([]byte("test@example.com")))
+	colors := [2]color.RGBA{{R: 255, A: 255}, {B: 255, A: 255}}
+	i := 0
+	allocs := testing.AllocsPerRun(100, func() {
+		floodFill(img, AvatarSize/2, AvatarSize/2, colors[i%2], 4)
+		i++
+	})
+	if allocs >= 1 {
+		t.Errorf("Expected fills to reuse their stack, got %.1f allocations per fill", allocs)
+	}
+}
+
+func BenchmarkFloodFill(b *testing.B) {
+	colors := [2]color.RGBA{{R: 255, A: 255}, {B: 255, A: 255}}
+	for _, size := range []int{AvatarSize, 512, 2048} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			// The face of a scaled avatar is the region its fill spreads through
+			img := toRGBA(New([]byte("test@example.com")))
+			img = scaleAvatar(img, size)
+			x, y := size/2, size/2
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Alternating colors refills the same region every time
+				floodFill(img, x, y, colors[i%2], 4)
+			}
+		})
+		b.Run(fmt.Sprint("reference/", size), func(b *testing.B) {
+			img := scaleAvatar(toRGBA(New([]byte("test@example.com"))), size)
+			x, y := size/2, size/2
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				referenceFill(img, x, y, colors[i%2], 4)
+			}
+		})
+	}
+}
+
+// blockedMask is a white square split by a black line through the center,
+// with the left half larger than the right
+func blockedMask() *image.RGBA {
+	mask := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	for y := 10; y < 70; y++ {
+		for x := 10; x < 70; x++ {
+			c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			if x == 40 || x == 41 {
+				c = color.RGBA{A: 255}
+			}
+			mask.SetRGBA(x, y, c)
+		}
+	}
+	return mask
+}
+
+func TestMaskSeed(t *testing.T) {
+	center := image.Pt(AvatarSize/2, AvatarSize/2)
+	// Every shipped face keeps its fill at the center
+	for face := 1; face <= FaceCount; face++ {
+		if got := maskSeed(mustLoadPart(t, "mask", face), center); got != center {
+			t.Errorf("Face %d: expected the seed at the center, got %v", face, got)
+		}
+	}
+
+	if got, want := maskSeed(blockedMask(), center), image.Pt(39, 40); got != want {
+		t.Errorf("Expected the seed next to the line in the larger half %v, got %v", want, got)
+	}
+	if got := maskSeed(image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize)), center); got != center {
+		t.Errorf("Expected the center for a mask without white, got %v", got)
+	}
+}
+
+func TestFillBlockedCenter(t *testing.T) {
+	pack := loosePack(t)
+	var mask, shine bytes.Buffer
+	if err := png.Encode(&mask, blockedMask()); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&shine, image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))); err != nil {
+		t.Fatal(err)
+	}
+	pack["mask1.png"] = &fstest.MapFile{Data: mask.Bytes()}
+	pack["shine1.png"] = &fstest.MapFile{Data: shine.Bytes()}
+	g, err := NewGenerator(pack)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	s := Spec{Face: 1, Background: 100, WaveColor: 30}
+	wave := defaultOptions().waveColor(s)
+	for _, tt := range []struct {
+		name string
+		opts []Option
+		// The alpha fill spreads through every opaque pixel of the mask,
+		// the black line included, so it reaches the smaller half as well
+		both bool
+	}{
+		{"4-way", nil, false},
+		{"8-way", []Option{WithFillConnectivity(8)}, false},
+		{"alpha", []Option{WithAlphaFill()}, true},
+	} {
+		img, err := g.GenerateFromSpec(s, tt.opts...)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		rgba := img.(*image.RGBA)
+		if got := rgba.RGBAAt(25, 40); got != wave {
+			t.Errorf("%s: expected the larger half in %v, got %v", tt.name, wave, got)
+		}
+		if got := rgba.RGBAAt(40, 40); got != (color.RGBA{A: 255}) {
+			t.Errorf("%s: expected the line to stay black, got %v", tt.name, got)
+		}
+		white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		if got := rgba.RGBAAt(55, 40); !tt.both && got != white {
+			t.Errorf("%s: expected the smaller half to stay white, got %v", tt.name, got)
+		}
+	}
+}
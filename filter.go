@@ -0,0 +1,520 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+)
+
+// WithBlur blurs the finished avatar with the given radius, radius 0 is a no-op
+func WithBlur(radius int) Option {
+	return func(o *options) error {
+		if radius < 0 {
+			return fmt.Errorf("wavatar: blur radius must not be negative, got %d", radius)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			blurRGBA(img, radius)
+		})
+		return nil
+	}
+}
+
+// Blur returns a copy of img blurred with a separable box blur of the given radius
+func Blur(img image.Image, radius int) image.Image {
+	dst := toRGBA(img)
+	blurRGBA(dst, radius)
+	return dst
+}
+
+// toRGBA copies img into a new RGBA image with the same bounds
+func toRGBA(img image.Image) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Src)
+	return dst
+}
+
+// blurRGBA box blurs img in place, horizontally then vertically.
+// Blurring the premultiplied channels keeps transparent edges from darkening.
+func blurRGBA(img *image.RGBA, radius int) {
+	if radius <= 0 {
+		return
+	}
+
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	tmp := make([]uint8, len(img.Pix))
+	for y := 0; y < h; y++ {
+		boxBlurLine(tmp, img.Pix, y*img.Stride, 4, w, radius)
+	}
+	for x := 0; x < w; x++ {
+		boxBlurLine(img.Pix, tmp, x*4, img.Stride, h, radius)
+	}
+}
+
+// boxBlurLine blurs n pixels of src starting at off and spaced step bytes apart into dst
+func boxBlurLine(dst, src []uint8, off, step, n, radius int) {
+	window := 2*radius + 1
+	for c := 0; c < 4; c++ {
+		sum := 0
+		for i := -radius; i <= radius; i++ {
+			sum += int(src[off+clampIndex(i, n)*step+c])
+		}
+		for i := 0; i < n; i++ {
+			dst[off+i*step+c] = uint8((sum + window/2) / window)
+			sum += int(src[off+clampIndex(i+radius+1, n)*step+c])
+			sum -= int(src[off+clampIndex(i-radius, n)*step+c])
+		}
+	}
+}
+
+// clampIndex limits i to the range [0, n)
+func clampIndex(i, n int) int {
+	if i < 0 {
+		return 0
+	} else if i >= n {
+		return n - 1
+	}
+	return i
+}
+
+// WithPixelate pixelates the finished avatar into block x block cells, block 1 is a no-op
+func WithPixelate(block int) Option {
+	return func(o *options) error {
+		if block < 1 {
+			return fmt.Errorf("wavatar: pixelate block must be at least 1, got %d", block)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			pixelateRGBA(img, block)
+		})
+		return nil
+	}
+}
+
+// Pixelate returns a copy of img where every block x block cell is filled with its average color.
+// Cells at the right and bottom edges are smaller when the size isn't divisible by block.
+func Pixelate(img image.Image, block int) image.Image {
+	dst := toRGBA(img)
+	pixelateRGBA(dst, block)
+	return dst
+}
+
+// pixelateRGBA pixelates img in place
+func pixelateRGBA(img *image.RGBA, block int) {
+	if block <= 1 {
+		return
+	}
+
+	b := img.Bounds()
+	for y0 := b.Min.Y; y0 < b.Max.Y; y0 += block {
+		for x0 := b.Min.X; x0 < b.Max.X; x0 += block {
+			cell := image.Rect(x0, y0, x0+block, y0+block).Intersect(b)
+			n := cell.Dx() * cell.Dy()
+
+			var sum [4]int
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					i := img.PixOffset(x, y)
+					for c := 0; c < 4; c++ {
+						sum[c] += int(img.Pix[i+c])
+					}
+				}
+			}
+
+			var avg [4]uint8
+			for c := range avg {
+				avg[c] = uint8((sum[c] + n/2) / n)
+			}
+			for y := cell.Min.Y; y < cell.Max.Y; y++ {
+				for x := cell.Min.X; x < cell.Max.X; x++ {
+					copy(img.Pix[img.PixOffset(x, y):], avg[:])
+				}
+			}
+		}
+	}
+}
+
+// WithSepia applies a sepia tone to the finished avatar, see Sepia.
+// Intensity outside [0, 1] is an error.
+func WithSepia(intensity float64) Option {
+	return func(o *options) error {
+		if !(intensity >= 0 && intensity <= 1) {
+			return fmt.Errorf("wavatar: sepia intensity must be from 0 to 1, got %v", intensity)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			sepiaRGBA(img, intensity)
+		})
+		return nil
+	}
+}
+
+// Sepia returns a copy of img toned with the standard sepia matrix, blended
+// with the original by intensity, 0 for the original and 1 for full sepia.
+// Intensity outside that range is clamped.
+func Sepia(img image.Image, intensity float64) image.Image {
+	dst := toRGBA(img)
+	sepiaRGBA(dst, intensity)
+	return dst
+}
+
+// sepiaRGBA tones img in place
+func sepiaRGBA(img *image.RGBA, intensity float64) {
+	intensity = math.Max(0, math.Min(1, intensity))
+	if intensity == 0 {
+		return
+	}
+
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		sr := 0.393*r + 0.769*g + 0.189*b
+		sg := 0.349*r + 0.686*g + 0.168*b
+		sb := 0.272*r + 0.534*g + 0.131*b
+		return r + (sr-r)*intensity, g + (sg-g)*intensity, b + (sb-b)*intensity
+	})
+}
+
+// mapColors replaces the color of every visible pixel of img with fn applied
+// to its non-premultiplied channels in the 0-255 range, keeping alpha.
+// Results are clamped to the valid range.
+func mapColors(img *image.RGBA, fn func(r, g, b float64) (float64, float64, float64)) {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			p := img.Pix[i : i+4 : i+4]
+			if p[3] == 0 {
+				continue
+			}
+
+			a := float64(p[3])
+			r, g, bl := fn(float64(p[0])*255/a, float64(p[1])*255/a, float64(p[2])*255/a)
+			p[0] = premultiply(r, a)
+			p[1] = premultiply(g, a)
+			p[2] = premultiply(bl, a)
+		}
+	}
+}
+
+// premultiply clamps the straight channel value v to 0-255 and scales it by alpha a
+func premultiply(v, a float64) uint8 {
+	v = math.Max(0, math.Min(255, v))
+	return uint8(math.Round(v * a / 255))
+}
+
+// WithPosterize reduces every color channel of the finished avatar to levels values, see Posterize
+func WithPosterize(levels int) Option {
+	return func(o *options) error {
+		if err := checkPosterizeLevels(levels); err != nil {
+			return err
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			posterizeRGBA(img, levels)
+		})
+		return nil
+	}
+}
+
+// Posterize returns a copy of img with each color channel quantized to the nearest
+// of levels evenly spaced values, leaving alpha untouched.
+// Levels below 2 are an error, 256 or more is a no-op.
+func Posterize(img image.Image, levels int) (image.Image, error) {
+	if err := checkPosterizeLevels(levels); err != nil {
+		return nil, err
+	}
+
+	dst := toRGBA(img)
+	posterizeRGBA(dst, levels)
+	return dst, nil
+}
+
+// checkPosterizeLevels validates the number of posterize levels
+func checkPosterizeLevels(levels int) error {
+	if levels < 2 {
+		return fmt.Errorf("wavatar: posterize needs at least 2 levels, got %d", levels)
+	}
+	return nil
+}
+
+// posterizeRGBA posterizes img in place
+func posterizeRGBA(img *image.RGBA, levels int) {
+	if levels >= 256 {
+		return
+	}
+
+	step := 255 / float64(levels-1)
+	quantize := func(v float64) float64 {
+		return math.Round(math.Round(v/step) * step)
+	}
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return quantize(r), quantize(g), quantize(b)
+	})
+}
+
+// WithInvert inverts the colors of the finished avatar, see Invert
+func WithInvert() Option {
+	return func(o *options) error {
+		o.filters = append(o.filters, invertRGBA)
+		return nil
+	}
+}
+
+// Invert returns a copy of img with its colors inverted and alpha preserved.
+// Colors are inverted before premultiplication so transparent edges don't glow.
+func Invert(img image.Image) image.Image {
+	dst := toRGBA(img)
+	invertRGBA(dst)
+	return dst
+}
+
+// invertRGBA inverts img in place
+func invertRGBA(img *image.RGBA) {
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return 255 - r, 255 - g, 255 - b
+	})
+}
+
+// WithHueShift rotates the hue of the finished avatar, see HueShift.
+// Degrees that are not finite are an error.
+func WithHueShift(degrees float64) Option {
+	return func(o *options) error {
+		if math.IsNaN(degrees) || math.IsInf(degrees, 0) {
+			return fmt.Errorf("wavatar: hue shift must be finite, got %v", degrees)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			hueShiftRGBA(img, degrees)
+		})
+		return nil
+	}
+}
+
+// HueShift returns a copy of img with the hue of every pixel rotated by degrees,
+// wrapping around the color wheel. Near-gray pixels are left as they are so
+// they don't pick up color noise.
+func HueShift(img image.Image, degrees float64) image.Image {
+	dst := toRGBA(img)
+	hueShiftRGBA(dst, degrees)
+	return dst
+}
+
+// hueShiftRGBA rotates the hue of img in place
+func hueShiftRGBA(img *image.RGBA, degrees float64) {
+	if math.Mod(degrees, 360) == 0 {
+		return
+	}
+
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		// Channels within 2 levels of each other carry no meaningful hue
+		if math.Max(r, math.Max(g, b))-math.Min(r, math.Min(g, b)) < 2 {
+			return r, g, b
+		}
+
+		h, s, l := rgbToHSL(r/255, g/255, b/255)
+		r, g, b = hslToRGB(h+degrees, s, l)
+		return r * 255, g * 255, b * 255
+	})
+}
+
+// WithAdjust changes the brightness and contrast of the finished avatar, see
+// Adjust. Brightness outside [-1, 1] and negative or infinite contrast are an error.
+func WithAdjust(brightness, contrast float64) Option {
+	return func(o *options) error {
+		if !(brightness >= -1 && brightness <= 1) {
+			return fmt.Errorf("wavatar: brightness must be from -1 to 1, got %v", brightness)
+		}
+		if !(contrast >= 0) || math.IsInf(contrast, 1) {
+			return fmt.Errorf("wavatar: contrast must be finite and not negative, got %v", contrast)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			adjustRGBA(img, brightness, contrast)
+		})
+		return nil
+	}
+}
+
+// Adjust returns a copy of img with contrast scaled around mid-gray and then
+// brightness added as a fraction of full scale, clamping every channel.
+// Brightness is limited to [-1, 1] and contrast to non-negative values.
+// Brightness 0 and contrast 1 leave the image unchanged.
+func Adjust(img image.Image, brightness, contrast float64) image.Image {
+	dst := toRGBA(img)
+	adjustRGBA(dst, brightness, contrast)
+	return dst
+}
+
+// adjustRGBA adjusts brightness and contrast of img in place
+func adjustRGBA(img *image.RGBA, brightness, contrast float64) {
+	brightness = math.Max(-1, math.Min(1, brightness))
+	contrast = math.Max(0, contrast)
+	if brightness == 0 && contrast == 1 {
+		return
+	}
+
+	adjust := func(v float64) float64 {
+		return (v-127.5)*contrast + 127.5 + brightness*255
+	}
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return adjust(r), adjust(g), adjust(b)
+	})
+}
+
+// WithVignette darkens the finished avatar towards its corners, see Vignette.
+// Strength or radius outside [0, 1] is an error.
+func WithVignette(strength, radius float64) Option {
+	return func(o *options) error {
+		if !(strength >= 0 && strength <= 1) || !(radius >= 0 && radius <= 1) {
+			return fmt.Errorf("wavatar: vignette strength and radius must be from 0 to 1, got %v and %v", strength, radius)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			vignetteRGBA(img, strength, radius)
+		})
+		return nil
+	}
+}
+
+// Vignette returns a copy of img darkened by a radial falloff around its center.
+// Radius is where the falloff begins as a fraction of the center to corner
+// distance, and strength is the darkening reached at the corners, both in [0, 1].
+// Strength 0 leaves the image unchanged.
+func Vignette(img image.Image, strength, radius float64) image.Image {
+	dst := toRGBA(img)
+	vignetteRGBA(dst, strength, radius)
+	return dst
+}
+
+// vignetteFactor is the brightness multiplier at normalized distance d from the center
+func vignetteFactor(d, strength, radius float64) float64 {
+	if d <= radius {
+		return 1
+	}
+	t := math.Min(1, (d-radius)/(1-radius))
+	return 1 - strength*t*t
+}
+
+// vignetteRGBA applies the vignette to img in place
+func vignetteRGBA(img *image.RGBA, strength, radius float64) {
+	strength = math.Max(0, math.Min(1, strength))
+	radius = math.Max(0, math.Min(0.99, radius))
+	if strength == 0 {
+		return
+	}
+
+	b := img.Bounds()
+	cx, cy := float64(b.Min.X+b.Max.X)/2, float64(b.Min.Y+b.Max.Y)/2
+	corner := math.Hypot(float64(b.Dx())/2, float64(b.Dy())/2)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			d := math.Hypot(float64(x)+0.5-cx, float64(y)+0.5-cy) / corner
+			f := vignetteFactor(d, strength, radius)
+			if f == 1 {
+				continue
+			}
+			// Scaling premultiplied channels by the same factor keeps alpha untouched
+			i := img.PixOffset(x, y)
+			for c := 0; c < 3; c++ {
+				img.Pix[i+c] = uint8(math.Round(float64(img.Pix[i+c]) * f))
+			}
+		}
+	}
+}
+
+// WithNormalizeLuminance scales the finished avatar to a mean luminance of target, see NormalizeLuminance
+func WithNormalizeLuminance(target float64) Option {
+	return func(o *options) error {
+		if target < 0 || target > 1 {
+			return fmt.Errorf("wavatar: luminance target must be in [0, 1], got %v", target)
+		}
+		o.filters = append(o.filters, func(img *image.RGBA) {
+			normalizeLuminanceRGBA(img, target)
+		})
+		return nil
+	}
+}
+
+// NormalizeLuminance returns a copy of img with all colors scaled by a common
+// factor so its mean luminance, in [0, 1], matches target as closely as
+// clamping allows. Scaling keeps hue and alpha, so rows of avatars normalized
+// to the same target look equally bright.
+func NormalizeLuminance(img image.Image, target float64) image.Image {
+	dst := toRGBA(img)
+	normalizeLuminanceRGBA(dst, math.Max(0, math.Min(1, target)))
+	return dst
+}
+
+// meanLuminance returns the alpha weighted mean Rec. 709 luma of img with its colors scaled by factor
+func meanLuminance(img *image.RGBA, factor float64) float64 {
+	var sum, weight float64
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := float64(img.Pix[i+3])
+		if a == 0 {
+			continue
+		}
+		r := math.Min(255, float64(img.Pix[i])*255/a*factor)
+		g := math.Min(255, float64(img.Pix[i+1])*255/a*factor)
+		b := math.Min(255, float64(img.Pix[i+2])*255/a*factor)
+		sum += a * (0.2126*r + 0.7152*g + 0.0722*b) / 255
+		weight += a
+	}
+	if weight == 0 {
+		return 0
+	}
+	return sum / weight
+}
+
+// normalizeLuminanceRGBA normalizes img in place
+func normalizeLuminanceRGBA(img *image.RGBA, target float64) {
+	if meanLuminance(img, 1) == 0 {
+		return
+	}
+
+	// Clamping makes the mean grow more slowly than the factor, so search for it
+	lo, hi := 0.0, 1.0
+	for meanLuminance(img, hi) < target && hi < 1<<16 {
+		hi *= 2
+	}
+	for range 40 {
+		mid := (lo + hi) / 2
+		if meanLuminance(img, mid) < target {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	factor := (lo + hi) / 2
+	mapColors(img, func(r, g, b float64) (float64, float64, float64) {
+		return r * factor, g * factor, b * factor
+	})
+}
+
+// WithOpacity makes the finished avatar translucent, see Opacity.
+// a must be in (0, 1], where 1 leaves the avatar unchanged.
+func WithOpacity(a float64) Option {
+	return func(o *options) error {
+		if !(a > 0 && a <= 1) {
+			return fmt.Errorf("wavatar: opacity %v out of range (0, 1]", a)
+		}
+		if a < 1 {
+			o.filters = append(o.filters, func(img *image.RGBA) {
+				opacityRGBA(img, a)
+			})
+		}
+		return nil
+	}
+}
+
+// Opacity returns a copy of img with its alpha scaled by a
+func Opacity(img image.Image, a float64) image.Image {
+	dst := toRGBA(img)
+	opacityRGBA(dst, a)
+	return dst
+}
+
+// opacityRGBA scales every channel of img in place, which scales the alpha
+// of premultiplied pixels without changing their color
+func opacityRGBA(img *image.RGBA, a float64) {
+	a = max(0, min(1, a))
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, y):img.PixOffset(img.Rect.Max.X, y)]
+		for i, v := range row {
+			row[i] = uint8(math.Round(float64(v) * a))
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package wavatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// adjacentDifference sums the absolute channel differences between horizontally adjacent pixels
+func adjacentDifference(img *image.RGBA) int {
+	total := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X + 1; x < b.Max.X; x++ {
+			i, j := img.PixOffset(x-1, y), img.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				d := int(img.Pix[i+c]) - int(img.Pix[j+c])
+				if d < 0 {
+					d = -d
+				}
+				total += d
+			}
+		}
+	}
+	return total
+}
+
+func TestBlurReducesHighFrequencyContent(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	sharp, err := Generate(hash)
+	if err != nil {
+		t.Fatalf("Failed to generate sharp avatar: %v", err)
+	}
+	blurred, err := Generate(hash, WithBlur(2))
+	if err != nil {
+		t.Fatalf("Failed to generate blurred avatar: %v", err)
+	}
+
+	sharpDiff := adjacentDifference(sharp.(*image.RGBA))
+	blurredDiff := adjacentDifference(blurred.(*image.RGBA))
+	if blurredDiff >= sharpDiff {
+		t.Errorf("Expected blurred avatar to have less high-frequency content, got %d >= %d", blurredDiff, sharpDiff)
+	}
+}
+
+func TestBlurRadiusZeroIsNoop(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	sharp := New(hash).(*image.RGBA)
+	blurred, err := Generate(hash, WithBlur(0))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	if !bytes.Equal(sharp.Pix, blurred.(*image.RGBA).Pix) {
+		t.Error("Blur with radius 0 should not change the avatar")
+	}
+}
+
+func TestBlurPreservesAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	// A single opaque white pixel in the middle of a transparent image
+	copy(img.Pix[img.PixOffset(5, 5):], []uint8{255, 255, 255, 255})
+
+	blurred := Blur(img, 1).(*image.RGBA)
+	for i := 0; i < len(blurred.Pix); i += 4 {
+		r, g, b, a := blurred.Pix[i], blurred.Pix[i+1], blurred.Pix[i+2], blurred.Pix[i+3]
+		if r > a || g > a || b > a {
+			t.Fatalf("Blurred pixel is not valid premultiplied color: %v", blurred.Pix[i:i+4])
+		}
+	}
+	if a := blurred.Pix[blurred.PixOffset(5, 5)+3]; a == 0 || a == 255 {
+		t.Errorf("Expected partially transparent center after blur, got alpha %d", a)
+	}
+}
+
+func TestBlurRejectsNegativeRadius(t *testing.T) {
+	if _, err := Generate([]byte("test@example.com"), WithBlur(-1)); err == nil {
+		t.Error("Expected an error for a negative blur radius")
+	}
+}
+
+func TestFilterOptionsRejectInvalidArguments(t *testing.T) {
+	tests := []struct {
+		name  string
+		opt   Option
+		valid Option
+	}{
+		{"pixelate", WithPixelate(0), WithPixelate(1)},
+		{"sepia", WithSepia(1.5), WithSepia(1)},
+		{"sepia NaN", WithSepia(math.NaN()), WithSepia(0)},
+		{"hue shift", WithHueShift(math.Inf(1)), WithHueShift(-720)},
+		{"brightness", WithAdjust(-2, 1), WithAdjust(-1, 1)},
+		{"contrast", WithAdjust(0, -0.5), WithAdjust(0, 0)},
+		{"vignette strength", WithVignette(1.1, 0.5), WithVignette(1, 0.5)},
+		{"vignette radius", WithVignette(0.5, -0.1), WithVignette(0.5, 0)},
+	}
+	for _, tt := range tests {
+		var oerr *OptionsError
+		if _, err := Generate([]byte("test@example.com"), tt.opt); !errors.As(err, &oerr) {
+			t.Errorf("%s: expected an *OptionsError, got %v", tt.name, err)
+		}
+		if _, err := Generate([]byte("test@example.com"), tt.valid); err != nil {
+			t.Errorf("%s: expected the bound to be accepted, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestPixelateGolden(t *testing.T) {
+	for name, hash := range map[string]string{"email": "test@example.com", "user1": "user1@example.com"} {
+		img, err := Generate([]byte(hash), WithPixelate(8))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		checkGolden(t, "pixelate8-"+name, img)
+	}
+}
+
+func TestPixelateDistinctColors(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	for _, block := range []int{2, 3, 7, 8, 13, 80, 200} {
+		out := Pixelate(img, block).(*image.RGBA)
+		cells := ((AvatarSize + block - 1) / block) * ((AvatarSize + block - 1) / block)
+
+		colors := make(map[[4]uint8]bool)
+		for i := 0; i < len(out.Pix); i += 4 {
+			colors[[4]uint8(out.Pix[i:i+4])] = true
+		}
+		if len(colors) > cells {
+			t.Errorf("Block %d: expected at most %d colors, got %d", block, cells, len(colors))
+		}
+		if block >= AvatarSize && len(colors) != 1 {
+			t.Errorf("Block %d: expected a single flat color, got %d", block, len(colors))
+		}
+	}
+}
+
+func TestPixelateSmallBlockIsNoop(t *testing.T) {
+	img := New([]byte("test@example.com")).(*image.RGBA)
+
+	for _, block := range []int{-1, 0, 1} {
+		if !bytes.Equal(Pixelate(img, block).(*image.RGBA).Pix, img.Pix) {
+			t.Errorf("Block %d should not change the image", block)
+		}
+	}
+}
+
+func TestSepiaGray(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 100, G: 100, B: 100, A: 255})
+
+	got := Sepia(img, 1).(*image.RGBA).RGBAAt(0, 0)
+	// 100 * (0.393+0.769+0.189), 100 * (0.349+0.686+0.168), 100 * (0.272+0.534+0.131)
+	want := color.RGBA{R: 135, G: 120, B: 94, A: 255}
+	if got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	// Bright grays saturate the red channel
+	img.SetRGBA(0, 0, color.RGBA{R: 200, G: 200, B: 200, A: 255})
+	got = Sepia(img, 1).(*image.RGBA).RGBAAt(0, 0)
+	want = color.RGBA{R: 255, G: 241, B: 187, A: 255}
+	if got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestSepiaZeroIntensityIsIdentical(t *testing.T) {
+	img := New([]byte("test@example.com")).(*image.RGBA)
+
+	for _, intensity := range []float64{0, -1} {
+		if !bytes.Equal(Sepia(img, intensity).(*image.RGBA).Pix, img.Pix) {
+			t.Errorf("Intensity %v should not change the image", intensity)
+		}
+	}
+}
+
+func TestSepiaClampsIntensity(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	full := Sepia(img, 1).(*image.RGBA)
+	over := Sepia(img, 5).(*image.RGBA)
+	if !bytes.Equal(full.Pix, over.Pix) {
+		t.Error("Intensity above 1 should clamp to full sepia")
+	}
+}
+
+func TestPosterizeLevels(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	out, err := Posterize(img, 4)
+	if err != nil {
+		t.Fatalf("Failed to posterize: %v", err)
+	}
+
+	allowed := map[uint8]bool{0: true, 85: true, 170: true, 255: true}
+	rgba := out.(*image.RGBA)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			if !allowed[rgba.Pix[i+c]] {
+				t.Fatalf("Unexpected channel value %d at offset %d", rgba.Pix[i+c], i+c)
+			}
+		}
+	}
+}
+
+func TestPosterizeIdempotent(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	for _, levels := range []int{2, 3, 4, 7, 16} {
+		once, err := Posterize(img, levels)
+		if err != nil {
+			t.Fatalf("Failed to posterize: %v", err)
+		}
+		twice, err := Posterize(once, levels)
+		if err != nil {
+			t.Fatalf("Failed to posterize: %v", err)
+		}
+		if !bytes.Equal(once.(*image.RGBA).Pix, twice.(*image.RGBA).Pix) {
+			t.Errorf("Posterizing twice with %d levels should equal posterizing once", levels)
+		}
+	}
+}
+
+func TestPosterizeBounds(t *testing.T) {
+	img := New([]byte("test@example.com")).(*image.RGBA)
+
+	for _, levels := range []int{-1, 0, 1} {
+		if _, err := Posterize(img, levels); err == nil {
+			t.Errorf("Expected an error for %d levels", levels)
+		}
+		if _, err := Generate([]byte("test@example.com"), WithPosterize(levels)); err == nil {
+			t.Errorf("Expected WithPosterize to reject %d levels", levels)
+		}
+	}
+
+	out, err := Posterize(img, 256)
+	if err != nil {
+		t.Fatalf("Failed to posterize: %v", err)
+	}
+	if !bytes.Equal(out.(*image.RGBA).Pix, img.Pix) {
+		t.Error("256 levels should not change the image")
+	}
+}
+
+// circleFade returns a copy of img with alpha fading out towards a circular edge
+func circleFade(img image.Image) *image.RGBA {
+	dst := toRGBA(img)
+	b := dst.Bounds()
+	center := float64(b.Dx()) / 2
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dx, dy := float64(x)+0.5-center, float64(y)+0.5-center
+			coverage := math.Max(0, math.Min(1, (center-math.Hypot(dx, dy))/4))
+			i := dst.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(math.Round(float64(dst.Pix[i+c]) * coverage))
+			}
+		}
+	}
+	return dst
+}
+
+func TestInvertTwiceRestoresImage(t *testing.T) {
+	plain := New([]byte("test@example.com"))
+
+	for name, img := range map[string]*image.RGBA{"square": toRGBA(plain), "circle": circleFade(plain)} {
+		t.Run(name, func(t *testing.T) {
+			twice := Invert(Invert(img)).(*image.RGBA)
+			for i := range img.Pix {
+				if d := int(twice.Pix[i]) - int(img.Pix[i]); d < -1 || d > 1 {
+					t.Fatalf("Offset %d: expected %d within 1, got %d", i, img.Pix[i], twice.Pix[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInvertPreservesAlpha(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 0, G: 0, B: 0, A: 128})
+	img.SetRGBA(1, 0, color.RGBA{R: 255, G: 0, B: 255, A: 255})
+
+	out := Invert(img).(*image.RGBA)
+	if got, want := out.RGBAAt(0, 0), (color.RGBA{R: 128, G: 128, B: 128, A: 128}); got != want {
+		t.Errorf("Expected half transparent black to become %v, got %v", want, got)
+	}
+	if got, want := out.RGBAAt(1, 0), (color.RGBA{R: 0, G: 255, B: 0, A: 255}); got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestHueShiftRed(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 255, A: 255})
+
+	tests := []struct {
+		degrees float64
+		want    color.RGBA
+	}{
+		{120, color.RGBA{G: 255, A: 255}},
+		{240, color.RGBA{B: 255, A: 255}},
+		{-120, color.RGBA{B: 255, A: 255}},
+		{480, color.RGBA{G: 255, A: 255}},
+		{360, color.RGBA{R: 255, A: 255}},
+	}
+
+	for _, tt := range tests {
+		if got := HueShift(img, tt.degrees).(*image.RGBA).RGBAAt(0, 0); got != tt.want {
+			t.Errorf("Shift by %v: expected %v, got %v", tt.degrees, tt.want, got)
+		}
+	}
+}
+
+func TestHueShiftLeavesGrays(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 256, 1))
+	for x := 0; x < 256; x++ {
+		img.SetRGBA(x, 0, color.RGBA{R: uint8(x), G: uint8(x), B: uint8(x), A: 255})
+	}
+	// A near-gray with a one level tint
+	img.SetRGBA(100, 0, color.RGBA{R: 101, G: 100, B: 100, A: 255})
+
+	out := HueShift(img, 77).(*image.RGBA)
+	if !bytes.Equal(out.Pix, img.Pix) {
+		t.Error("Gray pixels should not change when shifting hue")
+	}
+}
+
+func TestAdjustIdentity(t *testing.T) {
+	img := circleFade(New([]byte("test@example.com")))
+
+	if !bytes.Equal(Adjust(img, 0, 1).(*image.RGBA).Pix, img.Pix) {
+		t.Error("Brightness 0 and contrast 1 should not change the image")
+	}
+}
+
+func TestAdjustKnownPixel(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 100, G: 200, B: 20, A: 255})
+
+	tests := []struct {
+		brightness, contrast float64
+		want                 color.RGBA
+	}{
+		// (v-127.5)*1.5 + 127.5 + 25.5
+		{0.1, 1.5, color.RGBA{R: 112, G: 255, B: 0, A: 255}},
+		{0.2, 1, color.RGBA{R: 151, G: 251, B: 71, A: 255}},
+		{0, 0, color.RGBA{R: 128, G: 128, B: 128, A: 255}},
+		{-0.5, 0.5, color.RGBA{R: 0, G: 36, B: 0, A: 255}},
+	}
+
+	for _, tt := range tests {
+		got := Adjust(img, tt.brightness, tt.contrast).(*image.RGBA).RGBAAt(0, 0)
+		if got != tt.want {
+			t.Errorf("Adjust(%v, %v): expected %v, got %v", tt.brightness, tt.contrast, tt.want, got)
+		}
+	}
+}
+
+func TestAdjustClamps(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	white := Adjust(img, 1, 1).(*image.RGBA)
+	black := Adjust(img, -1, 1).(*image.RGBA)
+	for i := 0; i < len(white.Pix); i += 4 {
+		if white.Pix[i] != 255 || white.Pix[i+1] != 255 || white.Pix[i+2] != 255 {
+			t.Fatalf("Expected full brightness to clamp to white, got %v", white.Pix[i:i+4])
+		}
+		if black.Pix[i] != 0 || black.Pix[i+1] != 0 || black.Pix[i+2] != 0 {
+			t.Fatalf("Expected minimum brightness to clamp to black, got %v", black.Pix[i:i+4])
+		}
+	}
+
+	over := Adjust(img, 5, 1).(*image.RGBA)
+	if !bytes.Equal(over.Pix, white.Pix) {
+		t.Error("Brightness above 1 should clamp to 1")
+	}
+}
+
+func TestVignette(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 80, 80))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: color.RGBA{R: 200, G: 100, B: 50, A: 255}}, image.Point{}, draw.Src)
+
+	out := Vignette(img, 0.5, 0.3).(*image.RGBA)
+
+	if got := out.RGBAAt(40, 40); got != img.RGBAAt(40, 40) {
+		t.Errorf("Expected the center pixel to be unchanged, got %v", got)
+	}
+
+	// The corner pixel center sits at 39.5*sqrt(2) out of 40*sqrt(2)
+	t2 := (39.5/40 - 0.3) / 0.7
+	factor := 1 - 0.5*t2*t2
+	want := color.RGBA{R: uint8(math.Round(200 * factor)), G: uint8(math.Round(100 * factor)), B: uint8(math.Round(50 * factor)), A: 255}
+	if got := out.RGBAAt(0, 0); got != want {
+		t.Errorf("Expected the corner to be darkened to %v, got %v", want, got)
+	}
+
+	for y := 0; y < 80; y++ {
+		for x := 0; x < 80; x++ {
+			c := out.RGBAAt(x, y)
+			for _, p := range []image.Point{{79 - x, y}, {x, 79 - y}, {y, x}} {
+				if other := out.RGBAAt(p.X, p.Y); other != c {
+					t.Fatalf("Expected (%d,%d) and %v to match, got %v and %v", x, y, p, c, other)
+				}
+			}
+		}
+	}
+}
+
+func TestVignetteZeroStrengthIsNoop(t *testing.T) {
+	img := New([]byte("test@example.com")).(*image.RGBA)
+
+	if !bytes.Equal(Vignette(img, 0, 0.5).(*image.RGBA).Pix, img.Pix) {
+		t.Error("Strength 0 should not change the image")
+	}
+}
+
+func TestNormalizeLuminanceBatch(t *testing.T) {
+	const target = 0.45
+
+	for i := 0; i < 20; i++ {
+		img, err := Generate([]byte(fmt.Sprintf("user%d@example.com", i)), WithNormalizeLuminance(target))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if got := meanLuminance(img.(*image.RGBA), 1); math.Abs(got-target) > 0.01 {
+			t.Errorf("Avatar %d: expected mean luminance %v, got %v", i, target, got)
+		}
+	}
+}
+
+func TestNormalizeLuminanceKeepsHue(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.SetRGBA(0, 0, color.RGBA{R: 40, G: 20, B: 10, A: 255})
+
+	out := NormalizeLuminance(img, 0.2).(*image.RGBA).RGBAAt(0, 0)
+	if math.Abs(float64(out.R)-2*float64(out.G)) > 1 || math.Abs(float64(out.G)-2*float64(out.B)) > 1 {
+		t.Errorf("Expected channel ratios to be kept within rounding, got %v", out)
+	}
+}
+
+func TestNormalizeLuminanceRejectsTarget(t *testing.T) {
+	for _, target := range []float64{-0.1, 1.1} {
+		if _, err := Generate(nil, WithNormalizeLuminance(target)); err == nil {
+			t.Errorf("Expected an error for target %v", target)
+		}
+	}
+}
+
+func TestOpacity(t *testing.T) {
+	hash := []byte("test@example.com")
+	got, err := Generate(hash, WithOpacity(0.4))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	img := got.(*image.RGBA)
+	normal := New(hash).(*image.RGBA)
+
+	for i := 3; i < len(img.Pix); i += 4 {
+		want := math.Round(0.4 * float64(normal.Pix[i]))
+		if float64(img.Pix[i]) != want {
+			t.Fatalf("Expected alpha %v at pixel %d, got %d", want, i/4, img.Pix[i])
+		}
+		// Premultiplied channels never exceed their alpha
+		if max(img.Pix[i-3], img.Pix[i-2], img.Pix[i-1]) > img.Pix[i] {
+			t.Fatalf("Expected premultiplied color at pixel %d, got %v", i/4, img.Pix[i-3:i+1])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	decoded, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			_, _, _, a := decoded.At(x, y).RGBA()
+			if uint8(a>>8) != img.RGBAAt(x, y).A {
+				t.Fatalf("Expected alpha %d at (%d, %d) after a PNG round trip, got %d", img.RGBAAt(x, y).A, x, y, a>>8)
+			}
+		}
+	}
+}
+
+func TestOpacityRange(t *testing.T) {
+	hash := []byte("test@example.com")
+	same, err := Generate(hash, WithOpacity(1))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(same.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected opacity 1 to leave the avatar unchanged")
+	}
+
+	for _, a := range []float64{0, -0.5, 1.01, math.NaN()} {
+		if _, err := Generate(hash, WithOpacity(a)); err == nil {
+			t.Errorf("Expected an error for opacity %v", a)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package wavatar
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// algorithmRevision counts changes to how avatars are generated that the
+// fingerprint cannot see, such as the compositing or the fill. Bump it with
+// any change that alters the pixels of an existing avatar.
+const algorithmRevision = 1
+
+// fingerprint is computed once, the embedded parts never change
+var fingerprint = sync.OnceValue(func() string {
+	return fingerprintOf(archive)
+})
+
+// fingerprintOf digests the part archive parts with the revision and the default settings
+func fingerprintOf(parts []byte) string {
+	o := defaultOptions()
+	h := sha256.New()
+	fmt.Fprintf(h, "wavatar revision=%d size=%d counts=%v version=%d\n", algorithmRevision, AvatarSize, defaultCounts, o.version)
+	fmt.Fprintf(h, "saturation=%d/%d lightness=%d/%d fill=%d shine=%v fade=%v\n",
+		o.bgSaturation, o.waveSaturation, o.bgLightness, o.waveLightness, o.fillConnectivity, o.shineIntensity, o.fadeIntensity)
+	h.Write(parts)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Fingerprint returns a short digest of the embedded parts, the default
+// algorithm version and the default settings. Two binaries with the same
+// fingerprint render identical avatars with the default options, so they can
+// share a cache; any change to the parts or to how avatars are generated
+// changes it.
+func Fingerprint() string {
+	return fingerprint()
+}
@@ -0,0 +1,45 @@
+//go:build !wavatar_noembed
+
+package wavatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+)
+
+// goldenFingerprint changes with the parts or the generation algorithm; when
+// it does on purpose, bump it here together with algorithmRevision as needed
+const goldenFingerprint = "68348ec4c6785d94"
+
+func TestFingerprintGolden(t *testing.T) {
+	if got := Fingerprint(); got != goldenFingerprint {
+		t.Errorf("Expected fingerprint %s, got %s; if avatars change on purpose, update goldenFingerprint", goldenFingerprint, got)
+	}
+}
+
+func TestFingerprintFromFirstPrinciples(t *testing.T) {
+	// The embedded archive is the parts.zip gen_parts.go writes next to the sources
+	parts, err := os.ReadFile("parts.zip")
+	if err != nil {
+		t.Fatalf("Failed to read parts.zip: %v", err)
+	}
+
+	h := sha256.New()
+	h.Write([]byte("wavatar revision=1 size=80 counts=[11 4 8 13 11 19] version=1\n"))
+	h.Write([]byte("saturation=240/240 lightness=50/170 fill=4 shine=1 fade=1\n"))
+	h.Write(parts)
+	if got, want := Fingerprint(), hex.EncodeToString(h.Sum(nil)[:8]); got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestFingerprintChangesWithParts(t *testing.T) {
+	changed := bytes.Clone(archive)
+	changed[len(changed)/2] ^= 1
+	if fingerprintOf(changed) == Fingerprint() {
+		t.Error("Expected a changed part archive to change the fingerprint")
+	}
+}
@@ -0,0 +1,81 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"slices"
+)
+
+// DrawRGB565 draws the avatar of hash with the default Generator into an
+// RGB565 framebuffer, see Generator.DrawRGB565
+func DrawRGB565(dst []byte, stride int, bounds image.Rectangle, hash []byte, opts ...Option) error {
+	return Default().DrawRGB565(dst, stride, bounds, hash, opts...)
+}
+
+// DrawRGB565 draws the avatar of hash into bounds of a framebuffer of
+// little-endian RGB565 pixels, stride bytes per row, such as that of a small
+// SPI display. bounds must be square and is the size the avatar renders at,
+// overriding WithSize; pixels of dst outside it are left alone. The avatar is
+// converted straight into dst, without an intermediate image, and
+// WithDithering(DitherOrdered) hides the banding of the 5 and 6 bit channels.
+// Translucent pixels, such as those around WithCircleMask, are drawn over black.
+func (g *Generator) DrawRGB565(dst []byte, stride int, bounds image.Rectangle, hash []byte, opts ...Option) error {
+	if err := checkRGB565(len(dst), stride, bounds); err != nil {
+		return err
+	}
+	o, err := g.options(append(slices.Clip(opts), WithSize(bounds.Dx())))
+	if err != nil {
+		return err
+	}
+	if o.dither == DitherFloydSteinberg {
+		return fmt.Errorf("wavatar: DrawRGB565 only supports ordered dithering")
+	}
+	img, err := generateHash(hash, o)
+	if err != nil {
+		return err
+	}
+	drawRGB565(dst, stride, bounds.Min, img.(*image.RGBA), o.dither == DitherOrdered)
+	return nil
+}
+
+// checkRGB565 reports whether a square avatar fits bounds of a framebuffer of n bytes
+func checkRGB565(n, stride int, bounds image.Rectangle) error {
+	if bounds.Dx() != bounds.Dy() {
+		return fmt.Errorf("wavatar: framebuffer bounds %v are not square", bounds)
+	}
+	if bounds.Min.X < 0 || bounds.Min.Y < 0 {
+		return fmt.Errorf("wavatar: framebuffer bounds %v start before the buffer", bounds)
+	}
+	if stride < 2*bounds.Max.X {
+		return fmt.Errorf("wavatar: framebuffer stride %d is shorter than the %d bytes of bounds %v", stride, 2*bounds.Max.X, bounds)
+	}
+	if need := (bounds.Max.Y-1)*stride + 2*bounds.Max.X; n < need {
+		return fmt.Errorf("wavatar: framebuffer of %d bytes is smaller than the %d bytes bounds %v need", n, need, bounds)
+	}
+	return nil
+}
+
+// drawRGB565 converts img into dst with its top left corner at at. With
+// ordered dithering every channel is offset by the 4x4 Bayer matrix, lined up
+// with the framebuffer so neighboring avatars share one pattern, before its
+// low bits are dropped.
+func drawRGB565(dst []byte, stride int, at image.Point, img *image.RGBA, ordered bool) {
+	b := img.Rect
+	for y := 0; y < b.Dy(); y++ {
+		src := img.Pix[y*img.Stride : y*img.Stride+4*b.Dx()]
+		row := dst[(at.Y+y)*stride+2*at.X:]
+		for x := 0; x < b.Dx(); x++ {
+			// Half a step rounds to nearest, the Bayer threshold dithers
+			off := 128
+			if ordered {
+				off = (2*int(bayer4[(at.Y+y)%4][(at.X+x)%4]) + 1) * 255 / 32
+			}
+			p := src[4*x : 4*x+4]
+			r := (int(p[0])*31 + off) / 255
+			g := (int(p[1])*63 + off) / 255
+			bl := (int(p[2])*31 + off) / 255
+			v := uint16(r<<11 | g<<5 | bl)
+			row[2*x], row[2*x+1] = byte(v), byte(v>>8)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// rgb565ToRGBA expands the pixels of bounds in an RGB565 framebuffer
+func rgb565ToRGBA(buf []byte, stride int, bounds image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			i := (bounds.Min.Y+y)*stride + 2*(bounds.Min.X+x)
+			v := int(buf[i]) | int(buf[i+1])<<8
+			r, g, b := v>>11, v>>5&63, v&31
+			img.SetRGBA(x, y, color.RGBA{uint8(r * 255 / 31), uint8(g * 255 / 63), uint8(b * 255 / 31), 255})
+		}
+	}
+	return img
+}
+
+func TestDrawRGB565(t *testing.T) {
+	hash := []byte("test@example.com")
+	for _, tt := range []struct {
+		name      string
+		opts      []Option
+		tolerance uint8
+	}{
+		// Rounding is off by at most half a 5 bit step
+		{"rounded", nil, 5},
+		// Dithering moves each pixel by up to a whole step
+		{"dithered", []Option{WithDithering(DitherOrdered)}, 9},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			const stride = 2*100 + 6
+			buf := make([]byte, stride*70)
+			for i := range buf {
+				buf[i] = 0xAA
+			}
+			bounds := image.Rect(10, 5, 74, 69)
+			if err := DrawRGB565(buf, stride, bounds, hash, tt.opts...); err != nil {
+				t.Fatalf("Failed to draw: %v", err)
+			}
+
+			want, err := Generate(hash, WithSize(64))
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			got := rgb565ToRGBA(buf, stride, bounds)
+			_, stats, err := DiffImage(got, want)
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if stats.MaxDelta > tt.tolerance {
+				t.Errorf("Expected channels within %d of the render, got %d", tt.tolerance, stats.MaxDelta)
+			}
+
+			// Bytes outside bounds are untouched
+			for y := 0; y < 70; y++ {
+				for x := 0; x < stride; x++ {
+					if (image.Point{x / 2, y}).In(bounds) && x < 200 {
+						continue
+					}
+					if buf[y*stride+x] != 0xAA {
+						t.Fatalf("Expected byte %d of row %d untouched, got %#x", x, y, buf[y*stride+x])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDrawRGB565Dithering(t *testing.T) {
+	// Averaged over each Bayer tile, dithering recovers the colors rounding loses
+	hash := []byte("user1@example.com")
+	want, err := Generate(hash, WithSize(64))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	bounds := image.Rect(0, 0, 64, 64)
+	tileError := func(opts ...Option) float64 {
+		buf := make([]byte, 2*64*64)
+		if err := DrawRGB565(buf, 2*64, bounds, hash, opts...); err != nil {
+			t.Fatalf("Failed to draw: %v", err)
+		}
+		return meanChannelDiff(resample(rgb565ToRGBA(buf, 2*64, bounds), 16), resample(want.(*image.RGBA), 16))
+	}
+	rounded, dithered := tileError(), tileError(WithDithering(DitherOrdered))
+	if dithered >= rounded {
+		t.Errorf("Expected dithering to bring tile averages closer than %.3f, got %.3f", rounded, dithered)
+	}
+}
+
+func TestDrawRGB565Invalid(t *testing.T) {
+	hash := []byte("test@example.com")
+	buf := make([]byte, 2*80*80)
+	for _, tt := range []struct {
+		name   string
+		buf    []byte
+		stride int
+		bounds image.Rectangle
+		opts   []Option
+	}{
+		{"not square", buf, 160, image.Rect(0, 0, 80, 40), nil},
+		{"negative", buf, 160, image.Rect(-1, 0, 79, 80), nil},
+		{"short stride", buf, 150, image.Rect(0, 0, 80, 80), nil},
+		{"short buffer", buf[:len(buf)-1], 160, image.Rect(0, 0, 80, 80), nil},
+		{"too small", buf, 160, image.Rect(0, 0, 4, 4), nil},
+		{"floyd-steinberg", buf, 160, image.Rect(0, 0, 80, 80), []Option{WithDithering(DitherFloydSteinberg)}},
+	} {
+		if err := DrawRGB565(tt.buf, tt.stride, tt.bounds, hash, tt.opts...); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// The last row needs no padding past its pixels
+	if err := DrawRGB565(make([]byte, 79*200+160), 200, image.Rect(0, 0, 80, 80), hash); err != nil {
+		t.Errorf("Expected the last row to fit without padding, got %v", err)
+	}
+}
+
+func BenchmarkDrawRGB565(b *testing.B) {
+	hash := []byte("test@example.com")
+	buf := make([]byte, 2*240*240)
+	bounds := image.Rect(0, 0, 240, 240)
+
+	b.Run("direct", func(b *testing.B) {
+		for range b.N {
+			if err := DrawRGB565(buf, 2*240, bounds, hash); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	// Rendering an image and converting it pixel by pixel through color.Color
+	b.Run("convert", func(b *testing.B) {
+		for range b.N {
+			img, err := Generate(hash, WithSize(240))
+			if err != nil {
+				b.Fatal(err)
+			}
+			for y := 0; y < 240; y++ {
+				for x := 0; x < 240; x++ {
+					r, g, bl, _ := img.At(x, y).RGBA()
+					v := uint16(r>>11<<11 | g>>10<<5 | bl>>11)
+					buf[y*480+2*x], buf[y*480+2*x+1] = byte(v), byte(v>>8)
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+//go:build ignore
+
+// gen_parts packs parts/*.png into parts.zip as deflated raw NRGBA pixels,
+// which compresses better than the PNGs themselves. Run `go generate` after
+// changing any part.
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	files, err := filepath.Glob(filepath.Join("parts", "*.png"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	zw.RegisterCompressor(zip.Deflate, func(w io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(w, flate.BestCompression)
+	})
+
+	// Glob sorts its results, and no timestamps are stored, so the output is reproducible
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			log.Fatalf("%s: %v", file, err)
+		}
+		nrgba := image.NewNRGBA(img.Bounds())
+		draw.Draw(nrgba, nrgba.Bounds(), img, img.Bounds().Min, draw.Src)
+
+		name := strings.TrimSuffix(filepath.Base(file), ".png") + ".nrgba"
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := w.Write(nrgba.Pix); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile("parts.zip", buf.Bytes(), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,245 @@
+package wavatar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Generator renders avatars from its own part source with a set of default options.
+// It is safe for concurrent use.
+type Generator struct {
+	parts *partSet
+	// config holds the options of every avatar, swapped by Reconfigure
+	config atomic.Pointer[generatorConfig]
+	// reconfigure serializes Reconfigure calls
+	reconfigure sync.Mutex
+	// policy and counts describe the part pack, see WithCountPolicy
+	policy CountPolicy
+	counts layerCounts
+	// cache holds rendered avatars, nil without WithRenderCache
+	cache *renderCache
+	// log records the render lifecycle, nil without WithLogger
+	log *renderLogger
+}
+
+// NewGenerator creates a Generator loading parts from fsys, which holds PNGs
+// named like mask1.png at its root; use os.DirFS for a directory. A nil fsys
+// uses the embedded parts, which builds with the wavatar_noembed tag lack.
+// Every part the counts name must be in fsys. Parts are decoded on first
+// use, or up front with WithPreload, and cached for the life of the Generator.
+// opts apply to every avatar, before the options of each call.
+func NewGenerator(fsys fs.FS, opts ...Option) (*Generator, error) {
+	return newGenerator(fsys, nil, opts)
+}
+
+// newGenerator creates a Generator for the parts in fsys. Without counts they
+// follow the CountPolicy of opts, otherwise hashes select among counts.
+func newGenerator(fsys fs.FS, counts *layerCounts, opts []Option) (*Generator, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := defaultParts
+	if fsys != nil {
+		parts = newPartSet(fsys)
+	} else if o.memoryBudget > 0 || o.logger != nil {
+		// A budget or a logger needs a cache of its own rather than the shared one
+		parts = embeddedParts()
+	}
+	if parts == nil {
+		return nil, errNoParts
+	}
+	var budget *memoryBudget
+	if o.memoryBudget > 0 {
+		budget = &memoryBudget{limit: o.memoryBudget}
+	}
+	parts.budget = budget
+
+	policy, pack := o.countPolicy, defaultCounts
+	if counts != nil {
+		policy, pack = CountExtend, *counts
+	} else if policy != 0 && fsys != nil {
+		if pack, err = countParts(fsys); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkCounts(policy, pack); err != nil {
+		return nil, err
+	}
+	// A Config was validated already, the embedded parts are all there
+	if fsys != nil && counts == nil {
+		var errs []error
+		for l, n := range pack {
+			errs = append(errs, missingParts(fsys, Layer(l), n)...)
+		}
+		if err := errors.Join(errs...); err != nil {
+			return nil, fmt.Errorf("wavatar: invalid part source: %w", err)
+		}
+	}
+
+	g := &Generator{parts: parts, policy: policy, counts: pack}
+	g.config.Store(&generatorConfig{opts: slices.Clone(opts)})
+	if o.renderCache > 0 {
+		g.cache = newRenderCache(o.renderCache)
+		g.cache.budget = budget
+	}
+	if o.logger != nil {
+		g.log = &renderLogger{l: o.logger, slow: o.slowRender}
+		parts.log = g.log
+	}
+	if o.preload {
+		if err := parts.preloadAll(pack); err != nil {
+			return nil, err
+		}
+	}
+	return g, nil
+}
+
+// WithPreload makes NewGenerator decode every part of the pack up front and
+// fail if any is undecodable or not AvatarSize square, instead of reporting
+// it when the part is first rendered; missing parts fail NewGenerator anyway. With a CountPolicy the counts
+// found in the pack are checked, otherwise those of the embedded parts.
+// It only takes effect when passed to NewGenerator.
+func WithPreload() Option {
+	return func(o *options) error {
+		o.preload = true
+		return nil
+	}
+}
+
+// WithMemoryBudget caps the approximate bytes a Generator keeps in its
+// caches, the decoded parts and the avatars of WithRenderCache together.
+// Once it is exceeded, the cache that just grew evicts its least recently
+// used entries until the total fits again.
+// It only takes effect when passed to NewGenerator.
+func WithMemoryBudget(bytes int64) Option {
+	return func(o *options) error {
+		if bytes <= 0 {
+			return fmt.Errorf("wavatar: memory budget %d must be positive", bytes)
+		}
+		o.memoryBudget = bytes
+		return nil
+	}
+}
+
+// memoryBudget counts the bytes retained by the caches of a Generator against its limit
+type memoryBudget struct {
+	limit int64
+	used  atomic.Int64
+}
+
+// add records n more retained bytes, or fewer for a negative n
+func (b *memoryBudget) add(n int64) {
+	if b != nil {
+		b.used.Add(n)
+	}
+}
+
+// exceeded reports whether the caches retain more than the limit
+func (b *memoryBudget) exceeded() bool {
+	return b != nil && b.used.Load() > b.limit
+}
+
+// MemoryUsage returns the approximate number of bytes g keeps in its caches
+func (g *Generator) MemoryUsage() int64 {
+	return g.parts.memoryUsage() + g.cache.memoryUsage()
+}
+
+// options applies the current default options of g followed by opts
+func (g *Generator) options(opts []Option) (*options, error) {
+	return g.configOptions(g.currentConfig(), opts)
+}
+
+// configOptions applies the default options of cfg followed by opts
+func (g *Generator) configOptions(cfg *generatorConfig, opts []Option) (*options, error) {
+	o, err := newOptions(append(slices.Clip(cfg.opts), opts...))
+	if err != nil {
+		return nil, err
+	}
+	o.parts = g.parts
+	o.countPolicy, o.counts = g.policy, g.counts
+	return o, nil
+}
+
+// mustDescribe returns the options of g and the Spec they select for hash,
+// panicking if the options fail to apply
+func (g *Generator) mustDescribe(hash []byte) (*options, Spec) {
+	o, err := g.options(nil)
+	if err != nil {
+		panic(err)
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		panic(err)
+	}
+	return o, s
+}
+
+// Generate creates a new Wavatar from a hash, applying the given options
+func (g *Generator) Generate(hash []byte, opts ...Option) (image.Image, error) {
+	return g.GenerateContext(context.Background(), hash, opts...)
+}
+
+// GenerateContext is Generate stopping early once ctx is done. The render
+// checks ctx between its stages, so it returns soon after, with an error
+// wrapping the context error, instead of running to completion. With
+// WithRenderTimeout the render also stops once its time is up.
+func (g *Generator) GenerateContext(ctx context.Context, hash []byte, opts ...Option) (image.Image, error) {
+	start := time.Now()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("wavatar: render canceled: %w", err)
+	}
+	// The whole render uses one config, even if Reconfigure swaps it meanwhile
+	cfg := g.currentConfig()
+	cached := g.cache != nil && len(opts) == 0
+	if cached {
+		img, ok := g.cache.get(hash, cfg.generation)
+		g.log.cache(hash, ok)
+		if ok {
+			return img, nil
+		}
+	}
+
+	o, err := g.configOptions(cfg, opts)
+	if err != nil {
+		g.log.render(hash, nil, start, err)
+		return nil, err
+	}
+	if o.renderTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.renderTimeout)
+		defer cancel()
+	}
+	o.ctx = ctx
+	img, err := generateHash(hash, o)
+	g.log.render(hash, o, start, err)
+	if err != nil {
+		return nil, err
+	}
+	g.log.timings(hash, o.timings)
+	if cached {
+		g.cache.add(hash, img, cfg.generation)
+	}
+	return img, nil
+}
+
+// GenerateFromSpec renders the avatar described by s, applying the given options.
+// The indices of s must fit the parts of g, unless its CountPolicy is CountWrap.
+func (g *Generator) GenerateFromSpec(s Spec, opts ...Option) (image.Image, error) {
+	o, err := g.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	if s, err = o.fitSpec(s); err != nil {
+		return nil, err
+	}
+	return generate(s, o)
+}
@@ -0,0 +1,246 @@
+package wavatar
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestGeneratorDirectory(t *testing.T) {
+	g, err := NewGenerator(os.DirFS("parts"))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	hash := []byte("test@example.com")
+	img, err := g.Generate(hash)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected the directory parts to render like the embedded ones")
+	}
+}
+
+func TestGeneratorDefaultOptions(t *testing.T) {
+	g, err := NewGenerator(nil, WithInvert())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	hash := []byte("test@example.com")
+	img, err := g.Generate(hash)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, Invert(New(hash)).(*image.RGBA).Pix) {
+		t.Error("Expected the generator options to apply")
+	}
+
+	// Per call options run after the generator's
+	twice, err := g.GenerateFromSpec(Describe(hash), WithInvert())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(twice.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected inverting twice to restore the avatar")
+	}
+}
+
+func TestGeneratorInvalidSource(t *testing.T) {
+	if _, err := NewGenerator(os.DirFS("testdata/missing")); err == nil {
+		t.Error("Expected an error for a directory without parts")
+	}
+	if _, err := NewGenerator(nil, WithBlur(-1)); err == nil {
+		t.Error("Expected an error for an invalid option")
+	}
+
+	// Every part the counts name has to be there, not just the first mask
+	if _, err := NewGenerator(fstest.MapFS{"mask1.png": &fstest.MapFile{Data: []byte("not a png")}}); err == nil {
+		t.Error("Expected an error for a source missing parts")
+	}
+
+	// A broken part fails the render instead of panicking
+	hash := []byte("test@example.com")
+	pack := loosePack(t)
+	pack[fmt.Sprintf("mask%d.png", Describe(hash).Face)].Data = []byte("not a png")
+	g, err := NewGenerator(pack)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if _, err := g.Generate(hash); err == nil {
+		t.Error("Expected an error for a broken part source")
+	}
+}
+
+func TestGeneratorMemoryBudget(t *testing.T) {
+	const budget = 300 << 10
+	g, err := NewGenerator(nil, WithMemoryBudget(budget))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	unlimited, err := NewGenerator(nil)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	styles := [][]Option{
+		nil,
+		{WithAlphaFill()},
+		{WithFeatureOutline(1)},
+		{WithSeasonal(SeasonalSnow), WithInvert()},
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 60; i++ {
+				hash := []byte(fmt.Sprintf("user%d-%d@example.com", w, i))
+				opts := styles[i%len(styles)]
+
+				thumbs, err := g.Thumbnails(hash, []int{16, 32, 64, 160}, opts...)
+				if err != nil {
+					t.Errorf("Failed to create thumbnails: %v", err)
+					return
+				}
+				want, err := unlimited.Generate(hash, opts...)
+				if err != nil {
+					t.Errorf("Failed to generate avatar: %v", err)
+					return
+				}
+				// Eviction must never affect a render in progress
+				if !bytes.Equal(thumbs[32].(*image.RGBA).Pix, resample(want, 32).Pix) {
+					t.Errorf("Budgeted render of %s differs from the unlimited one", hash)
+				}
+				if usage := g.MemoryUsage(); usage > budget {
+					t.Errorf("Expected usage within %d bytes, got %d", budget, usage)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if usage := g.MemoryUsage(); usage == 0 || usage > budget {
+		t.Errorf("Expected usage between 0 and %d bytes, got %d", budget, usage)
+	}
+	if _, err := NewGenerator(nil, WithMemoryBudget(0)); err == nil {
+		t.Error("Expected an error for a zero budget")
+	}
+}
+
+func TestGeneratorMemoryBudgetRenderCache(t *testing.T) {
+	// Each cached avatar takes 256 KB, so the parts leave room for one or two
+	const budget = 600 << 10
+	g, err := NewGenerator(nil, WithMemoryBudget(budget), WithRenderCache(100), WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	unlimited, err := NewGenerator(nil, WithRenderCache(100), WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		if _, err := g.Generate(hash); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, err := unlimited.Generate(hash); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if usage := g.MemoryUsage(); usage > budget {
+			t.Errorf("Expected the parts and avatars within %d bytes, got %d", budget, usage)
+		}
+	}
+	if n := g.Stats().RenderCacheEntries; n < 1 || n > 2 {
+		t.Errorf("Expected the budget to leave room for one or two cached avatars, got %d", n)
+	}
+
+	// Without a budget the cached avatars count towards the usage all the same
+	parts := unlimited.parts.memoryUsage()
+	if usage, avatars := unlimited.MemoryUsage(), int64(20*256*256*4); usage != parts+avatars {
+		t.Errorf("Expected %d bytes of parts and %d of cached avatars, got %d", parts, avatars, usage)
+	}
+	if err := unlimited.Reconfigure(); err != nil {
+		t.Fatalf("Failed to reconfigure: %v", err)
+	}
+	if usage := unlimited.MemoryUsage(); usage != unlimited.parts.memoryUsage() {
+		t.Errorf("Expected Reconfigure to release the cached avatars, got %d bytes in use", usage)
+	}
+}
+
+// hookFS serves parts from FS, calling onOpen before opening each file
+type hookFS struct {
+	fs.FS
+	onOpen func(name string)
+}
+
+func (f hookFS) Open(name string) (fs.File, error) {
+	f.onOpen(name)
+	return f.FS.Open(name)
+}
+
+func TestGenerateContextCanceled(t *testing.T) {
+	var armed atomic.Bool
+	var opened atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The first part opened ends the request, so the render stops after its first stage
+	g, err := NewGenerator(hookFS{FS: os.DirFS("parts"), onOpen: func(string) {
+		if armed.Load() {
+			opened.Add(1)
+			cancel()
+		}
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	armed.Store(true)
+
+	goroutines := runtime.NumGoroutine()
+	done := make(chan error)
+	go func() {
+		_, err := g.GenerateContext(ctx, []byte("test@example.com"))
+		done <- err
+	}()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected the render to be canceled, got %v", err)
+	}
+	// The background, fade and mask are one stage; the shine and features are never loaded
+	if n := opened.Load(); n > 2 {
+		t.Errorf("Expected at most the fade and mask opened, got %d parts", n)
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("Expected no goroutines left behind, got %d more", n-goroutines)
+	}
+
+	if _, err := g.GenerateContext(ctx, []byte("test@example.com")); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a done context to fail at once, got %v", err)
+	}
+}
+
+func TestGenerateContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	img, err := GenerateContext(ctx, []byte("test@example.com"), WithInvert())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	want, _ := Generate([]byte("test@example.com"), WithInvert())
+	if _, stats, _ := DiffImage(want, img); stats.Changed != 0 {
+		t.Error("Expected a live context to render as usual")
+	}
+}
@@ -1,3 +1,5 @@
 module github.com/weavatar/wavatar
 
 go 1.24.1
+
+require golang.org/x/image v0.32.0
@@ -0,0 +1,107 @@
+package wavatar
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Reasons a GravatarURLError gives for rejecting a URL
+var (
+	ErrGravatarMalformed = errors.New("malformed URL")
+	ErrGravatarDigest    = errors.New("missing or invalid digest")
+	ErrGravatarSize      = errors.New("invalid size")
+)
+
+// gravatarDefaultSize and gravatarMaxSize are the size Gravatar serves
+// without a size parameter and the largest it accepts
+const (
+	gravatarDefaultSize = 80
+	gravatarMaxSize     = 2048
+)
+
+// GravatarURLError is a URL that does not describe a Gravatar avatar.
+// Err is one of ErrGravatarMalformed, ErrGravatarDigest or ErrGravatarSize.
+type GravatarURLError struct {
+	URL    string
+	Err    error
+	Detail string
+}
+
+func (e *GravatarURLError) Error() string {
+	return fmt.Sprintf("wavatar: gravatar URL %q: %v: %s", e.URL, e.Err, e.Detail)
+}
+
+func (e *GravatarURLError) Unwrap() error {
+	return e.Err
+}
+
+// ParseGravatarURL returns the digest and size of a Gravatar avatar URL like
+// https://secure.gravatar.com/avatar/<hex>?s=96&d=wavatar. The digest is the
+// MD5 or SHA-256 of the email address in hex, optionally followed by an image
+// extension, and the size comes from the s or size parameter, 80 without one.
+// Other parameters, such as the default image, are ignored.
+func ParseGravatarURL(u string) (hash []byte, size int, err error) {
+	fail := func(reason error, format string, args ...any) ([]byte, int, error) {
+		return nil, 0, &GravatarURLError{URL: u, Err: reason, Detail: fmt.Sprintf(format, args...)}
+	}
+
+	parsed, err := url.Parse(strings.TrimSpace(u))
+	if err != nil {
+		return fail(ErrGravatarMalformed, "%v", err)
+	}
+	// Protocol relative URLs like //gravatar.com/avatar/<hex> have no scheme
+	if parsed.Scheme != "" && parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fail(ErrGravatarMalformed, "unsupported scheme %q", parsed.Scheme)
+	}
+	if parsed.Host == "" {
+		return fail(ErrGravatarMalformed, "no host")
+	}
+
+	dir, file := path.Split(parsed.Path)
+	if dir != "/avatar/" || file == "" {
+		return fail(ErrGravatarDigest, "path %q is not /avatar/<digest>", parsed.Path)
+	}
+	digest := strings.TrimSuffix(file, path.Ext(file))
+	if len(digest) != 32 && len(digest) != 64 {
+		return fail(ErrGravatarDigest, "%q is neither an MD5 nor a SHA-256 digest", digest)
+	}
+	if hash, err = hex.DecodeString(digest); err != nil {
+		return fail(ErrGravatarDigest, "%q is not hex", digest)
+	}
+
+	query := parsed.Query()
+	param := query.Get("s")
+	if param == "" {
+		param = query.Get("size")
+	}
+	if param == "" {
+		return hash, gravatarDefaultSize, nil
+	}
+	size, err = strconv.Atoi(param)
+	if err != nil || size < 1 || size > gravatarMaxSize {
+		return fail(ErrGravatarSize, "%q is not a size from 1 to %d", param, gravatarMaxSize)
+	}
+	return hash, size, nil
+}
+
+// NewFromGravatarURL renders the avatar a Gravatar avatar URL describes at the
+// size it asks for, scaled like Thumbnails. For the MD5 digest of an email
+// address this is the avatar NewFromString creates for it. opts apply as for
+// Generate, before the avatar is scaled to the size of the URL.
+func NewFromGravatarURL(u string, opts ...Option) (image.Image, error) {
+	hash, size, err := ParseGravatarURL(u)
+	if err != nil {
+		return nil, err
+	}
+	img, err := Generate(hash, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return scaleThumbnail(img, size), nil
+}
@@ -0,0 +1,96 @@
+package wavatar
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestParseGravatarURL(t *testing.T) {
+	md5hex := "55502f40dc8b7c769880b10874abc9d0"
+	sha256hex := "973dfe463ec85785f5f95af5ba3906eedb2d931c24e69824a89ea65dba4e813b"
+	tests := []struct {
+		url    string
+		digest string
+		size   int
+	}{
+		{"https://secure.gravatar.com/avatar/" + md5hex + "?s=96&d=wavatar", md5hex, 96},
+		{"http://www.gravatar.com/avatar/" + md5hex, md5hex, 80},
+		{"https://0.gravatar.com/avatar/" + md5hex + ".jpg?size=200", md5hex, 200},
+		{"//gravatar.com/avatar/" + md5hex + ".png?d=wavatar&r=g&s=40", md5hex, 40},
+		{"https://gravatar.com/avatar/" + sha256hex + "?s=2048", sha256hex, 2048},
+		{"  https://gravatar.com/avatar/55502F40DC8B7C769880B10874ABC9D0?d=wavatar&f=y  ", md5hex, 80},
+		{"https://avatars.example.net/avatar/" + md5hex + "?s=1", md5hex, 1},
+	}
+	for _, tt := range tests {
+		hash, size, err := ParseGravatarURL(tt.url)
+		if err != nil {
+			t.Errorf("%s: failed to parse: %v", tt.url, err)
+			continue
+		}
+		if got := hex.EncodeToString(hash); got != tt.digest || size != tt.size {
+			t.Errorf("%s: expected %s at %d, got %s at %d", tt.url, tt.digest, tt.size, got, size)
+		}
+	}
+}
+
+func TestParseGravatarURLInvalid(t *testing.T) {
+	md5hex := "55502f40dc8b7c769880b10874abc9d0"
+	tests := []struct {
+		url    string
+		reason error
+	}{
+		{"https://gravatar.com/avatar/%zz", ErrGravatarMalformed},
+		{"ftp://gravatar.com/avatar/" + md5hex, ErrGravatarMalformed},
+		{"/avatar/" + md5hex, ErrGravatarMalformed},
+		{"https://gravatar.com/avatar/", ErrGravatarDigest},
+		{"https://gravatar.com/" + md5hex, ErrGravatarDigest},
+		{"https://gravatar.com/avatar/" + md5hex + "/extra", ErrGravatarDigest},
+		{"https://gravatar.com/avatar/" + md5hex[:31], ErrGravatarDigest},
+		{"https://gravatar.com/avatar/" + md5hex[:31] + "g", ErrGravatarDigest},
+		{"https://gravatar.com/avatar/" + md5hex + "?s=0", ErrGravatarSize},
+		{"https://gravatar.com/avatar/" + md5hex + "?s=2049", ErrGravatarSize},
+		{"https://gravatar.com/avatar/" + md5hex + "?size=-5", ErrGravatarSize},
+		{"https://gravatar.com/avatar/" + md5hex + "?s=big", ErrGravatarSize},
+	}
+	for _, tt := range tests {
+		_, _, err := ParseGravatarURL(tt.url)
+		var uerr *GravatarURLError
+		if !errors.As(err, &uerr) || uerr.URL != tt.url {
+			t.Errorf("%s: expected a *GravatarURLError, got %v", tt.url, err)
+			continue
+		}
+		if !errors.Is(err, tt.reason) {
+			t.Errorf("%s: expected %v, got %v", tt.url, tt.reason, err)
+		}
+	}
+}
+
+func TestNewFromGravatarURL(t *testing.T) {
+	email := "Test@Example.com "
+	digest := md5.Sum([]byte("test@example.com"))
+	u := "https://secure.gravatar.com/avatar/" + hex.EncodeToString(digest[:]) + "?d=wavatar"
+
+	img, err := NewFromGravatarURL(u)
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, NewFromString(email).(*image.RGBA).Pix) {
+		t.Error("Expected the URL to render like NewFromString of its address")
+	}
+
+	img, err = NewFromGravatarURL(u+"&s=32", WithInvert())
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 32 || b.Dy() != 32 {
+		t.Errorf("Expected 32x32, got %dx%d", b.Dx(), b.Dy())
+	}
+
+	if _, err := NewFromGravatarURL("https://gravatar.com/avatar/nope"); !errors.Is(err, ErrGravatarDigest) {
+		t.Errorf("Expected a digest error, got %v", err)
+	}
+}
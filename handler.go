@@ -0,0 +1,370 @@
+package wavatar
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// handlerCacheControl lets clients and proxies keep an avatar for a year;
+// the same URL always renders the same avatar until the fingerprint changes
+const handlerCacheControl = "public, max-age=31536000"
+
+// handlerRetryAfter is the Retry-After, in seconds, of renders that timed out
+const handlerRetryAfter = "1"
+
+// maxHashHex is the longest hex hash the handler accepts, a SHA-512 digest
+const maxHashHex = 128
+
+// Handler returns an http.Handler serving the avatars of the default
+// Generator, see Generator.Handler
+func Handler(opts ...Option) http.Handler {
+	return Default().Handler(opts...)
+}
+
+// Handler returns an http.Handler serving avatars as PNG for GET and HEAD
+// requests like /<hex hash>.png?s=160, where the extension is optional and
+// only the last path segment is read, so the handler can be mounted under any
+// prefix. The s or size parameter scales the avatar like Thumbnails, to at
+// most MaxSize, and defaults to AvatarSize. opts apply to every avatar.
+// Requests for /<hex hash>.json get the Spec the avatar renders, with its
+// colors resolved like Resolve, and for /<hex hash>.svg the document of
+// Generator.EncodeSVG with the same parts, options and size, or 400 Bad
+// Request when the options draw something SVG cannot show.
+//
+// Responses carry a Cache-Control for a year and an ETag derived from the
+// hash, the size, the format, the Fingerprint and the settings the avatars
+// render with: the options of g and opts, the parts and the generation of
+// Reconfigure. The settings are digested from probe renders once per
+// generation, in the background from when the handler is created or first
+// sees a Reconfigure, and requests wait for that digest. Requests whose
+// If-None-Match names the ETag get 304 Not Modified.
+// JSON and SVG responses are gzipped for clients that accept it, PNG, which
+// is compressed already, never is. Invalid hashes, formats, sizes and
+// options get 400 Bad Request, without the details of the options, and renders that run out of the time WithRenderTimeout allows
+// or whose request is canceled get 503 Service Unavailable with a
+// Retry-After, as do requests canceled while the settings are digested.
+func (g *Generator) Handler(opts ...Option) http.Handler {
+	var (
+		mu       sync.Mutex
+		settings *handlerSettings
+	)
+	// settingsFor returns the settings for cfg, starting their digest in the
+	// background the first time a generation is seen
+	settingsFor := func(cfg *generatorConfig) *handlerSettings {
+		mu.Lock()
+		defer mu.Unlock()
+		if settings == nil || cfg.generation > settings.generation {
+			settings = &handlerSettings{generation: cfg.generation, ready: make(chan struct{})}
+			go settings.compute(g, cfg, opts)
+		}
+		return settings
+	}
+	settingsFor(g.currentConfig())
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		hash, format, size, err := parseAvatarRequest(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		current := settingsFor(g.currentConfig())
+		select {
+		case <-current.ready:
+		case <-r.Context().Done():
+			w.Header().Set("Retry-After", handlerRetryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		if current.err != nil {
+			renderError(w, current.err)
+			return
+		}
+
+		compress := format.compressible()
+		gzipped := compress && acceptsGzip(r.Header.Get("Accept-Encoding"))
+		etag := avatarETag(hash, size, format, gzipped, current)
+		w.Header().Set("Cache-Control", handlerCacheControl)
+		w.Header().Set("ETag", etag)
+		if compress {
+			w.Header().Set("Vary", "Accept-Encoding")
+		}
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		buf := pngBuffers.Get().(*bytes.Buffer)
+		defer pngBuffers.Put(buf)
+		buf.Reset()
+		err = g.writeAvatar(r.Context(), buf, hash, format, size, current.timeout, opts)
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			w.Header().Set("Retry-After", handlerRetryAfter)
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		if errors.Is(err, ErrSVGUnsupported) {
+			http.Error(w, "svg is not available for these avatars", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			renderError(w, err)
+			return
+		}
+
+		body := buf
+		if gzipped {
+			body = pngBuffers.Get().(*bytes.Buffer)
+			defer pngBuffers.Put(body)
+			body.Reset()
+			if err := writeGzip(body, buf.Bytes()); err != nil {
+				serverError(w)
+				return
+			}
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+		w.Header().Set("Content-Type", format.contentType())
+		w.Header().Set("Content-Length", strconv.Itoa(body.Len()))
+		if r.Method == http.MethodGet {
+			w.Write(body.Bytes())
+		}
+	})
+}
+
+// writeAvatar writes the avatar of hash to buf in format, rendering within timeout
+func (g *Generator) writeAvatar(ctx context.Context, buf *bytes.Buffer, hash []byte, format Format, size int, timeout time.Duration, opts []Option) error {
+	switch format {
+	case FormatSVG:
+		o, err := g.options(opts)
+		if err != nil {
+			return err
+		}
+		o.size = size
+		return encodeSVGHash(buf, hash, o)
+	case formatJSON:
+		o, err := g.options(opts)
+		if err != nil {
+			return err
+		}
+		s, err := describeHash(hash, o)
+		if err != nil {
+			return err
+		}
+		s.BackgroundRGBA, s.WaveRGBA = o.backgroundColor(s), o.waveColor(s)
+		return json.NewEncoder(buf).Encode(s)
+	default:
+		img, err := g.renderBefore(ctx, timeout, hash, opts)
+		if err != nil {
+			return err
+		}
+		return WritePNG(buf, scaleThumbnail(img, size), png.DefaultCompression)
+	}
+}
+
+// handlerFormats are the formats the handler serves by their extension
+var handlerFormats = map[string]Format{"": FormatPNG, ".png": FormatPNG, ".svg": FormatSVG, ".json": formatJSON}
+
+// parseAvatarRequest returns the hash, format and size an avatar request asks for
+func parseAvatarRequest(r *http.Request) ([]byte, Format, int, error) {
+	base := path.Base(r.URL.Path)
+	ext := path.Ext(base)
+	format, ok := handlerFormats[ext]
+	if !ok {
+		return nil, "", 0, fmt.Errorf("wavatar: unsupported format %q", ext)
+	}
+	name := strings.TrimSuffix(base, ext)
+	if name == "" || name == "/" || name == "." || len(name) > maxHashHex {
+		return nil, "", 0, fmt.Errorf("wavatar: invalid hash %q", name)
+	}
+	hash, err := hex.DecodeString(name)
+	if err != nil {
+		return nil, "", 0, fmt.Errorf("wavatar: hash %q is not hex", name)
+	}
+
+	query := r.URL.Query()
+	param := query.Get("s")
+	if param == "" {
+		param = query.Get("size")
+	}
+	if param == "" {
+		return hash, format, AvatarSize, nil
+	}
+	size, err := strconv.Atoi(param)
+	if err != nil || size < 1 || size > MaxSize {
+		return nil, "", 0, fmt.Errorf("wavatar: size %q is not from 1 to %d", param, MaxSize)
+	}
+	return hash, format, size, nil
+}
+
+// serverError responds with 500 Internal Server Error, leaving the details of
+// the error, such as part paths, out of the response; see WithLogger
+func serverError(w http.ResponseWriter) {
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// renderError responds to err from rendering or digesting the settings with
+// 400 Bad Request for an *OptionsError and serverError otherwise, leaving the
+// details out of the response either way
+func renderError(w http.ResponseWriter, err error) {
+	var oerr *OptionsError
+	if errors.As(err, &oerr) {
+		http.Error(w, "invalid avatar options", http.StatusBadRequest)
+		return
+	}
+	serverError(w)
+}
+
+// handlerSettings is the digest of the settings a handler renders with for
+// one generation of the config of its Generator, and their render timeout.
+// The other fields are set once ready is closed.
+type handlerSettings struct {
+	generation uint64
+	ready      chan struct{}
+	digest     string
+	timeout    time.Duration
+	err        error
+}
+
+// compute digests the settings of g with cfg and opts and marks s ready. It
+// runs once per generation, apart from any request, so requests waiting for
+// it can give up without stopping it for the others.
+func (s *handlerSettings) compute(g *Generator, cfg *generatorConfig, opts []Option) {
+	defer close(s.ready)
+	s.digest, s.timeout, s.err = g.settingsDigest(cfg, opts)
+}
+
+// WithRenderTimeout gives up on renders that take longer than d, such as
+// those of huge sizes on a loaded server. Generate returns an error wrapping
+// context.DeadlineExceeded, and Handler responds with 503 Service
+// Unavailable as soon as d has passed, while the render stops at its next
+// stage; see GenerateContext.
+func WithRenderTimeout(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("wavatar: render timeout %v must be positive", d)
+		}
+		o.renderTimeout = d
+		return nil
+	}
+}
+
+// renderResult is the outcome of a render run in the background
+type renderResult struct {
+	img image.Image
+	err error
+}
+
+// renderBefore renders hash, returning once ctx is done or timeout, if not
+// zero, has passed even if the render has not stopped yet. An abandoned
+// render finishes its stage in the background and exits.
+func (g *Generator) renderBefore(ctx context.Context, timeout time.Duration, hash []byte, opts []Option) (image.Image, error) {
+	if timeout == 0 {
+		return g.GenerateContext(ctx, hash, opts...)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Buffered so an abandoned render can send its result and exit
+	done := make(chan renderResult, 1)
+	go func() {
+		img, err := g.GenerateContext(ctx, hash, opts...)
+		done <- renderResult{img, err}
+	}()
+	select {
+	case res := <-done:
+		return res.img, res.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("wavatar: render canceled: %w", ctx.Err())
+	}
+}
+
+// probeHashes exercise the options that act on the hash rather than the Spec,
+// such as WithDomainHue, WithCanonicalization and WithDefaultOnEmpty
+var probeHashes = []string{"", "\x00\x00\x00\x00", "probe@example.com", "0123456789abcdef0123456789abcdef"}
+
+// settingsDigest digests renders with the options of cfg and opts of probe
+// Specs that between them use every part, and of probeHashes, and returns it
+// with their render timeout. Options are functions that cannot be compared,
+// so what they render stands in for them.
+func (g *Generator) settingsDigest(cfg *generatorConfig, opts []Option) (string, time.Duration, error) {
+	o, err := g.configOptions(cfg, opts)
+	if err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "version=%d\n", o.version)
+	digest := func(img image.Image) {
+		rgba := toRGBA(img)
+		fmt.Fprintf(h, "%v\n", rgba.Rect)
+		h.Write(rgba.Pix)
+	}
+
+	counts := o.selectionCounts()
+	for i := range slices.Max(counts[:]) {
+		s := Spec{
+			Face:       i%counts[LayerFace] + 1,
+			Background: i*97%240 + 1,
+			Fade:       i%counts[LayerFade] + 1,
+			WaveColor:  i*41%240 + 1,
+			Brow:       i%counts[LayerBrow] + 1,
+			Eyes:       i%counts[LayerEyes] + 1,
+			Pupil:      i%counts[LayerPupils] + 1,
+			Mouth:      i%counts[LayerMouth] + 1,
+		}
+		img, err := generate(s, o)
+		if err != nil {
+			return "", 0, err
+		}
+		digest(img)
+	}
+	for _, hash := range probeHashes {
+		img, err := generateHash([]byte(hash), o)
+		if err != nil {
+			return "", 0, err
+		}
+		digest(img)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), o.renderTimeout, nil
+}
+
+// avatarETag is the strong ETag of the avatar for hash at size in format
+// with settings, which differs between its gzipped and plain encodings
+func avatarETag(hash []byte, size int, format Format, gzipped bool, settings *handlerSettings) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s %d %d %s ", Fingerprint(), settings.digest, settings.generation, size, format)
+	h.Write(hash)
+	etag := hex.EncodeToString(h.Sum(nil)[:12])
+	if gzipped {
+		etag += "-gzip"
+	}
+	return `"` + etag + `"`
+}
+
+// etagMatches reports whether an If-None-Match header names etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,402 @@
+package wavatar
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// serve sends a request for target with the given If-None-Match to h
+func serve(h http.Handler, method, target, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler(t *testing.T) {
+	h := Handler()
+	hash := []byte("test@example.com")
+	hexHash := "74657374406578616d706c652e636f6d"
+
+	for _, tt := range []struct {
+		target string
+		size   int
+	}{
+		{"/" + hexHash + ".png", AvatarSize},
+		{"/avatar/" + hexHash + "?s=160", 160},
+		{"/" + hexHash + ".png?size=32", 32},
+	} {
+		rec := serve(h, http.MethodGet, tt.target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tt.target, rec.Code, rec.Body)
+		}
+		hdr := rec.Header()
+		if hdr.Get("Content-Type") != "image/png" || hdr.Get("Cache-Control") != handlerCacheControl || hdr.Get("ETag") == "" {
+			t.Errorf("%s: unexpected headers %v", tt.target, hdr)
+		}
+		if n, _ := strconv.Atoi(hdr.Get("Content-Length")); n != rec.Body.Len() {
+			t.Errorf("%s: expected Content-Length %d, got %s", tt.target, rec.Body.Len(), hdr.Get("Content-Length"))
+		}
+
+		img, err := png.Decode(bytes.NewReader(rec.Body.Bytes()))
+		if err != nil {
+			t.Fatalf("%s: failed to decode: %v", tt.target, err)
+		}
+		thumbs, err := Thumbnails(hash, []int{tt.size})
+		if err != nil {
+			t.Fatalf("Failed to create thumbnails: %v", err)
+		}
+		if _, stats, _ := DiffImage(thumbs[tt.size], img); stats.Changed != 0 {
+			t.Errorf("%s: expected the avatar at %d, %d pixels differ", tt.target, tt.size, stats.Changed)
+		}
+	}
+
+	// HEAD gets the headers only
+	rec := serve(h, http.MethodHead, "/"+hexHash, "")
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") == "" {
+		t.Errorf("Expected HEAD to send headers only, got %d with %d bytes", rec.Code, rec.Body.Len())
+	}
+	if rec := serve(h, http.MethodPost, "/"+hexHash, ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for POST, got %d", rec.Code)
+	}
+}
+
+func TestHandlerNotModified(t *testing.T) {
+	h := Handler()
+	target := "/74657374406578616d706c652e636f6d.png?s=64"
+	etag := serve(h, http.MethodGet, target, "").Header().Get("ETag")
+
+	for _, header := range []string{etag, `"other", ` + etag, "W/" + etag, "*"} {
+		rec := serve(h, http.MethodGet, target, header)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: expected an empty 304, got %d", header, rec.Code)
+		}
+		if rec.Header().Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: expected the ETag on the 304", header)
+		}
+	}
+
+	// Another size, hash or version is another avatar
+	for _, other := range []struct {
+		h      http.Handler
+		target string
+	}{
+		{h, "/74657374406578616d706c652e636f6d.png?s=65"},
+		{h, "/74657374406578616d706c652e636f6e.png?s=64"},
+		{Handler(WithVersion(V2)), target},
+	} {
+		rec := serve(other.h, http.MethodGet, other.target, etag)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for a stale ETag, got %d", other.target, rec.Code)
+		}
+		if rec.Header().Get("ETag") == etag {
+			t.Errorf("%s: expected a different ETag", other.target)
+		}
+	}
+
+	if again := serve(h, http.MethodGet, target, "").Header().Get("ETag"); again != etag {
+		t.Errorf("Expected a deterministic ETag %s, got %s", etag, again)
+	}
+}
+
+// The ETag changes with anything that changes the avatar behind the URL
+func TestHandlerETagSettings(t *testing.T) {
+	target := "/74657374406578616d706c652e636f6d.png"
+	g, err := NewGenerator(nil)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	h := g.Handler()
+	etag := serve(h, http.MethodGet, target, "").Header().Get("ETag")
+
+	// A part pack with two mouths swapped renders other avatars
+	pack := loosePack(t)
+	pack["mouth1.png"], pack["mouth2.png"] = pack["mouth2.png"], pack["mouth1.png"]
+	swapped, err := NewGenerator(pack)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	dark, err := NewGenerator(nil, WithDarkTheme())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	for name, other := range map[string]http.Handler{
+		"generator options": dark.Handler(),
+		"handler options":   g.Handler(WithPastel()),
+		"parts":             swapped.Handler(),
+	} {
+		rec := serve(other, http.MethodGet, target, etag)
+		if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("%s: expected 200 with a new ETag, got %d with %s", name, rec.Code, rec.Header().Get("ETag"))
+		}
+	}
+
+	// Reconfiguring the Generator invalidates the ETags of its handlers
+	if err := g.Reconfigure(WithPastel()); err != nil {
+		t.Fatalf("Failed to reconfigure: %v", err)
+	}
+	rec := serve(h, http.MethodGet, target, etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("Expected 200 with a new ETag after Reconfigure, got %d with %s", rec.Code, rec.Header().Get("ETag"))
+	}
+	reconfigured := rec.Header().Get("ETag")
+	if rec := serve(h, http.MethodGet, target, reconfigured); rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for the ETag after Reconfigure, got %d", rec.Code)
+	}
+}
+
+func TestHandlerRenderTimeout(t *testing.T) {
+	// Every part is loaded anew for each render and takes longer than the timeout
+	var slow atomic.Bool
+	g, err := NewGenerator(hookFS{FS: os.DirFS("parts"), onOpen: func(string) {
+		if slow.Load() {
+			time.Sleep(time.Second)
+		}
+	}}, WithMemoryBudget(1), WithRenderTimeout(250*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	h := g.Handler()
+	target := "/74657374406578616d706c652e636f6d.png"
+	if rec := serve(h, http.MethodGet, target, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for a fast render, got %d", rec.Code)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	slow.Store(true)
+	for i := 0; i < 3; i++ {
+		start := time.Now()
+		rec := serve(h, http.MethodGet, target, "")
+		if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+			t.Errorf("Expected 503 with a Retry-After, got %d with %v", rec.Code, rec.Header())
+		}
+		if elapsed := time.Since(start); elapsed >= time.Second {
+			t.Errorf("Expected the 503 before the slow part was loaded, took %v", elapsed)
+		}
+	}
+
+	// Abandoned renders stop after the part they are loading
+	deadline := time.Now().Add(3 * time.Second)
+	for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("Expected abandoned renders to exit, got %d goroutines left", n-goroutines)
+	}
+
+	if _, err := newOptions([]Option{WithRenderTimeout(0)}); err == nil {
+		t.Error("Expected an error for a zero render timeout")
+	}
+}
+
+func TestHandlerSettingsOnce(t *testing.T) {
+	// A filter counts the renders, those of the settings digest included
+	var renders atomic.Int32
+	count := WithPostProcess(func(*image.RGBA) { renders.Add(1) })
+	target := "/74657374406578616d706c652e636f6d.png"
+
+	if rec := serve(Handler(count), http.MethodGet, target, ""); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	digest := renders.Load() - 1
+
+	// Concurrent first requests share a single digest, as do those after Reconfigure
+	g, err := NewGenerator(nil)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	h := g.Handler(count)
+	for round := range 2 {
+		renders.Store(0)
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if rec := serve(h, http.MethodGet, target, ""); rec.Code != http.StatusOK {
+					t.Errorf("Expected 200, got %d", rec.Code)
+				}
+			}()
+		}
+		wg.Wait()
+		if got := renders.Load(); got != digest+8 {
+			t.Errorf("Round %d: expected %d renders for one digest and 8 requests, got %d", round, digest+8, got)
+		}
+		if err := g.Reconfigure(WithDarkTheme()); err != nil {
+			t.Fatalf("Failed to reconfigure: %v", err)
+		}
+	}
+}
+
+func TestHandlerSettingsCanceled(t *testing.T) {
+	// Once armed, parts can't be opened until released, so the settings digest waits
+	var armed atomic.Bool
+	release := make(chan struct{})
+	g, err := NewGenerator(hookFS{FS: os.DirFS("parts"), onOpen: func(string) {
+		if armed.Load() {
+			<-release
+		}
+	}})
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	armed.Store(true)
+	h := g.Handler()
+	target := "/74657374406578616d706c652e636f6d.png"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 503 with a Retry-After while the settings are digested, got %d", rec.Code)
+	}
+
+	close(release)
+	if rec := serve(h, http.MethodGet, target, ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 once the settings are digested, got %d", rec.Code)
+	}
+}
+
+func TestHandlerSVGOptions(t *testing.T) {
+	hash := []byte("test@example.com")
+	target := "/" + hex.EncodeToString(hash) + ".svg?s=160"
+	g, err := NewGenerator(nil, WithDarkTheme())
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	var want bytes.Buffer
+	if err := g.EncodeSVG(&want, hash, WithPastel(), WithSize(160)); err != nil {
+		t.Fatalf("Failed to encode SVG: %v", err)
+	}
+	rec := serve(g.Handler(WithPastel()), http.MethodGet, target, "")
+	if rec.Code != http.StatusOK || !bytes.Equal(rec.Body.Bytes(), want.Bytes()) {
+		t.Errorf("Expected the SVG of the handler options, got %d", rec.Code)
+	}
+
+	if rec := serve(g.Handler(WithCircleMask()), http.MethodGet, target, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an SVG with a circle mask, got %d", rec.Code)
+	}
+}
+
+func TestHandlerCompression(t *testing.T) {
+	h := Handler()
+	hexHash := "74657374406578616d706c652e636f6d"
+	for _, tt := range []struct {
+		ext, contentType string
+		compressible     bool
+	}{
+		{".png", "image/png", false},
+		{"", "image/png", false},
+		{".svg", "image/svg+xml", true},
+		{".json", "application/json", true},
+	} {
+		target := "/" + hexHash + tt.ext
+		plain := serve(h, http.MethodGet, target, "")
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Accept-Encoding", "br, gzip")
+		gzipped := httptest.NewRecorder()
+		h.ServeHTTP(gzipped, req)
+
+		for _, rec := range []*httptest.ResponseRecorder{plain, gzipped} {
+			if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != tt.contentType {
+				t.Fatalf("%s: expected 200 with %s, got %d with %s", target, tt.contentType, rec.Code, rec.Header().Get("Content-Type"))
+			}
+			if vary := rec.Header().Get("Vary"); (vary == "Accept-Encoding") != tt.compressible {
+				t.Errorf("%s: unexpected Vary %q", target, vary)
+			}
+			if n, _ := strconv.Atoi(rec.Header().Get("Content-Length")); n != rec.Body.Len() {
+				t.Errorf("%s: expected Content-Length %d, got %s", target, rec.Body.Len(), rec.Header().Get("Content-Length"))
+			}
+		}
+		if plain.Header().Get("Content-Encoding") != "" {
+			t.Errorf("%s: expected no Content-Encoding without Accept-Encoding", target)
+		}
+		if !tt.compressible {
+			if gzipped.Header().Get("Content-Encoding") != "" || !bytes.Equal(gzipped.Body.Bytes(), plain.Body.Bytes()) {
+				t.Errorf("%s: expected the PNG uncompressed", target)
+			}
+			continue
+		}
+
+		if gzipped.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("%s: expected gzip, got %q", target, gzipped.Header().Get("Content-Encoding"))
+		}
+		if gzipped.Header().Get("ETag") == plain.Header().Get("ETag") {
+			t.Errorf("%s: expected the encodings to have different ETags", target)
+		}
+		zr, err := gzip.NewReader(gzipped.Body)
+		if err != nil {
+			t.Fatalf("%s: failed to gunzip: %v", target, err)
+		}
+		body, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("%s: failed to gunzip: %v", target, err)
+		}
+		if !bytes.Equal(body, plain.Body.Bytes()) {
+			t.Errorf("%s: expected the gzipped body to round-trip", target)
+		}
+	}
+
+	// The JSON is the Spec Resolve returns
+	var s Spec
+	rec := serve(h, http.MethodGet, "/"+hexHash+".json", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &s); err != nil {
+		t.Fatalf("Failed to decode Spec: %v", err)
+	}
+	if want, _ := Resolve([]byte("test@example.com")); s != want {
+		t.Errorf("Expected %v, got %v", want, s)
+	}
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	h := Handler()
+	for _, target := range []string{
+		"/",
+		"/xyz.png",
+		"/abc.png",
+		"/" + string(bytes.Repeat([]byte("ab"), 65)),
+		"/7465?s=0",
+		"/7465?s=-1",
+		"/7465?s=4097",
+		"/7465?size=huge",
+		"/7465.gif",
+	} {
+		if rec := serve(h, http.MethodGet, target, ""); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+
+	// Options that cannot apply are rejected without their details
+	for _, opt := range []Option{WithBlur(-1), WithSepia(2)} {
+		h := Handler(opt)
+		for _, target := range []string{"/7465", "/7465.svg", "/7465.json"} {
+			rec := serve(h, http.MethodGet, target, "")
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected 400 for invalid options, got %d", target, rec.Code)
+			}
+			if strings.Contains(rec.Body.String(), "wavatar:") {
+				t.Errorf("%s: expected the body to leave out the error, got %q", target, rec.Body)
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package wavatar
+
+import (
+	"image/color"
+	"math"
+)
+
+// colorMode is the arithmetic that turns hue, saturation and lightness into RGB
+type colorMode int
+
+const (
+	// colorsVersion is the arithmetic of the algorithm version, see Version
+	colorsVersion colorMode = iota
+	// colorsInteger is the integer arithmetic of hsl that V1 and V2 use
+	colorsInteger
+	// colorsPrecise is the standard conversion in floating point, see hslPrecise
+	colorsPrecise
+	// colorsLegacy is the formula as the original PHP Wavatar computes it, see hslLegacy
+	colorsLegacy
+)
+
+// WithPreciseColors computes the background, wave and outline colors with
+// the standard HSL conversion in floating point. The integer arithmetic of
+// V1 and V2 truncates the ramps between the primary hues and the lightness
+// blend, so many hues share one color; with this option every hue and
+// lightness step gets its own color. It is the default from V3 on.
+// Of the color options the last one applies.
+func WithPreciseColors() Option {
+	return func(o *options) error {
+		o.colorMode = colorsPrecise
+		return nil
+	}
+}
+
+// WithIntegerColors computes the background, wave and outline colors with
+// the integer arithmetic of V1 and V2 under any version, so V3 can be adopted
+// for its part selection while existing colors stay.
+// Of the color options the last one applies.
+func WithIntegerColors() Option {
+	return func(o *options) error {
+		o.colorMode = colorsInteger
+		return nil
+	}
+}
+
+// WithLegacyColors computes the background, wave and outline colors exactly
+// like Wavatar::HSLtoRGB of the original PHP Wavatar, so avatars migrated
+// from it keep the colors their users know. PHP divides in floating point and
+// truncates only some of the ramps, which neither the integer arithmetic nor
+// WithPreciseColors reproduce. Of the color options the last one applies.
+func WithLegacyColors() Option {
+	return func(o *options) error {
+		o.colorMode = colorsLegacy
+		return nil
+	}
+}
+
+// hslColor converts a hue, saturation and lightness on the 0-240 scales to an opaque color
+func (o *options) hslColor(h, s, l int) color.RGBA {
+	var rgb []int
+	mode := o.colorMode
+	if mode == colorsVersion {
+		mode = colorsInteger
+		if o.version >= V3 {
+			mode = colorsPrecise
+		}
+	}
+	switch mode {
+	case colorsPrecise:
+		rgb = hslPrecise(h, s, l)
+	case colorsLegacy:
+		rgb = hslLegacy(h, s, l)
+	default:
+		rgb = hsl(h, s, l)
+	}
+	return color.RGBA{R: uint8(rgb[0]), G: uint8(rgb[1]), B: uint8(rgb[2]), A: 255}
+}
+
+// hslPrecise is the standard HSL to RGB conversion on the 0-240 scales of
+// hsl, computed in float64 throughout and rounded only at the end
+func hslPrecise(h, s, l int) []int {
+	if h > 240 || h < 0 || s > 240 || s < 0 || l > 240 || l < 0 {
+		return []int{0, 0, 0}
+	}
+
+	// The pure hue runs through six 40 step ramps between the primaries
+	var rgb [3]float64
+	hf := float64(h)
+	switch {
+	case h <= 40:
+		rgb = [3]float64{1, hf / 40, 0}
+	case h <= 80:
+		rgb = [3]float64{1 - (hf-40)/40, 1, 0}
+	case h <= 120:
+		rgb = [3]float64{0, 1, (hf - 80) / 40}
+	case h <= 160:
+		rgb = [3]float64{0, 1 - (hf-120)/40, 1}
+	case h <= 200:
+		rgb = [3]float64{(hf - 160) / 40, 0, 1}
+	default:
+		rgb = [3]float64{1, 0, 1 - (hf-200)/40}
+	}
+
+	// Saturation blends the hue with mid gray, lightness with black or white
+	sf, lf := float64(s)/240, float64(l)/240
+	out := make([]int, 3)
+	for i, c := range rgb {
+		c = 0.5 + sf*(c-0.5)
+		if lf < 0.5 {
+			c *= 2 * lf
+		} else {
+			c += (2*lf - 1) * (1 - c)
+		}
+		out[i] = clamp(int(math.Round(c * 255)))
+	}
+	return out
+}
+
+// hslLegacy is hsl the way PHP evaluates it: divisions yield floats, the
+// rising ramps alone are cast to int, and GD truncates the final channels.
+// Every product is converted explicitly so no platform fuses it into an
+// addition and rounds differently from PHP.
+func hslLegacy(h, s, l int) []int {
+	if h > 240 || h < 0 || s > 240 || s < 0 || l > 240 || l < 0 {
+		return []int{0, 0, 0}
+	}
+
+	var rgb [3]float64
+	hf := float64(h)
+	switch {
+	case h <= 40:
+		rgb = [3]float64{255, math.Trunc(hf / 40 * 256), 0}
+	case h <= 80:
+		rgb = [3]float64{float64((1 - (hf-40)/40) * 256), 255, 0}
+	case h <= 120:
+		rgb = [3]float64{0, 255, math.Trunc((hf - 80) / 40 * 256)}
+	case h <= 160:
+		rgb = [3]float64{0, float64((1 - (hf-120)/40) * 256), 255}
+	case h <= 200:
+		rgb = [3]float64{math.Trunc((hf - 160) / 40 * 256), 0, 255}
+	default:
+		rgb = [3]float64{255, 0, math.Trunc((1 - (hf-200)/40) * 256)}
+	}
+
+	out := make([]int, 3)
+	sf, lf := float64(s), float64(l)
+	for i, c := range rgb {
+		c += float64((240 - sf) / 240 * (128 - c))
+		if l < 120 {
+			c = c / 120 * lf
+		} else {
+			c = float64(lf*((256-c)/120)) + float64(2*c) - 256
+		}
+		out[i] = int(max(0, min(c, 255)))
+	}
+	return out
+}
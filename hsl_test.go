@@ -0,0 +1,222 @@
+package wavatar
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestHSLPrecise(t *testing.T) {
+	tests := []struct {
+		h, s, l int
+		want    [3]int
+	}{
+		// Halfway up the red to yellow ramp, which integer division drops entirely
+		{20, 240, 120, [3]int{255, 128, 0}},
+		{100, 240, 120, [3]int{0, 255, 128}},
+		{180, 240, 120, [3]int{128, 0, 255}},
+		// Primaries come out the same either way
+		{40, 240, 120, [3]int{255, 255, 0}},
+		{120, 240, 120, [3]int{0, 255, 255}},
+		// No saturation is mid gray at any hue
+		{73, 0, 120, [3]int{128, 128, 128}},
+		// Lightness scales down towards black and up towards white
+		{20, 240, 60, [3]int{128, 64, 0}},
+		{20, 240, 180, [3]int{255, 191, 128}},
+		{20, 240, 0, [3]int{0, 0, 0}},
+		{20, 240, 240, [3]int{255, 255, 255}},
+		{241, 240, 120, [3]int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		if got := hslPrecise(tt.h, tt.s, tt.l); [3]int(got) != tt.want {
+			t.Errorf("hslPrecise(%d, %d, %d): expected %v, got %v", tt.h, tt.s, tt.l, tt.want, got)
+		}
+	}
+}
+
+// standardHSL is the textbook conversion through chroma, with hue in degrees
+// and saturation and lightness from 0 to 1, returning channels from 0 to 255
+func standardHSL(h, s, l float64) [3]float64 {
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(h/60, 2)-1))
+	m := l - c/2
+	var rgb [3]float64
+	switch {
+	case h < 60:
+		rgb = [3]float64{c, x, 0}
+	case h < 120:
+		rgb = [3]float64{x, c, 0}
+	case h < 180:
+		rgb = [3]float64{0, c, x}
+	case h < 240:
+		rgb = [3]float64{0, x, c}
+	case h < 300:
+		rgb = [3]float64{x, 0, c}
+	default:
+		rgb = [3]float64{c, 0, x}
+	}
+	for i := range rgb {
+		rgb[i] = (rgb[i] + m) * 255
+	}
+	return rgb
+}
+
+func TestHSLPreciseMatchesStandard(t *testing.T) {
+	for _, h := range []int{0, 1, 13, 40, 59, 80, 101, 120, 147, 160, 199, 200, 233, 240} {
+		for _, s := range []int{0, 30, 120, 200, 240} {
+			for _, l := range []int{0, 25, 50, 119, 120, 121, 170, 215, 240} {
+				got := hslPrecise(h, s, l)
+				want := standardHSL(float64(h)*360/240, float64(s)/240, float64(l)/240)
+				for i := range got {
+					// Rounding may go either way for a channel halfway between two levels
+					if math.Abs(float64(got[i])-want[i]) > 0.5+1e-9 {
+						t.Errorf("hslPrecise(%d, %d, %d): expected %.2f, got %v", h, s, l, want, got)
+						break
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHSLPreciseDistinctHues(t *testing.T) {
+	o := defaultOptions()
+	precise := defaultOptions()
+	precise.colorMode = colorsPrecise
+
+	count := func(o *options) int {
+		seen := make(map[color.RGBA]bool)
+		for h := 1; h <= 240; h++ {
+			seen[o.hslColor(h, o.bgSaturation, o.bgLightness)] = true
+		}
+		return len(seen)
+	}
+	legacy, exact := count(o), count(precise)
+	if exact <= legacy || exact < 200 {
+		t.Errorf("Expected far more than the %d integer colors over the hue wheel, got %d", legacy, exact)
+	}
+}
+
+func TestWithPreciseColors(t *testing.T) {
+	hash := []byte("test@example.com")
+	img, err := Generate(hash, WithPreciseColors())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	checkGolden(t, "precise-colors", img)
+
+	// The default keeps the integer colors of existing avatars
+	s := Describe(hash)
+	o := defaultOptions()
+	rgb := hsl(s.Background, o.bgSaturation, o.bgLightness)
+	for _, tt := range []struct {
+		opts []Option
+		same bool
+	}{{nil, true}, {[]Option{WithPreciseColors()}, false}} {
+		resolved, err := Resolve(hash, tt.opts...)
+		if err != nil {
+			t.Fatalf("Failed to resolve: %v", err)
+		}
+		legacy := color.RGBA{R: uint8(rgb[0]), G: uint8(rgb[1]), B: uint8(rgb[2]), A: 255}
+		if got := resolved.BackgroundRGBA == legacy; got != tt.same {
+			t.Errorf("Options %d: expected the integer background %v %v, got %v", len(tt.opts), legacy, tt.same, resolved.BackgroundRGBA)
+		}
+	}
+}
+
+// Reference colors of the PHP Wavatar::HSLtoRGB, evaluated with PHP's float
+// division, its (int) casts of the rising ramps and GD truncating the result
+func TestHSLLegacy(t *testing.T) {
+	tests := []struct {
+		h, s, l int
+		want    [3]int
+	}{
+		{1, 240, 50, [3]int{106, 2, 0}},
+		{20, 240, 50, [3]int{106, 53, 0}},
+		{39, 240, 50, [3]int{106, 103, 0}},
+		{55, 240, 170, [3]int{200, 255, 106}},
+		{100, 240, 170, [3]int{106, 255, 181}},
+		{137, 240, 50, [3]int{0, 61, 106}},
+		{180, 240, 170, [3]int{181, 106, 255}},
+		{222, 240, 50, [3]int{106, 0, 47}},
+		{240, 240, 170, [3]int{255, 106, 106}},
+		{20, 120, 200, [3]int{234, 213, 192}},
+		{120, 0, 120, [3]int{128, 128, 128}},
+		{20, 240, 240, [3]int{255, 255, 255}},
+		{241, 240, 120, [3]int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		got := hslLegacy(tt.h, tt.s, tt.l)
+		if [3]int(got) != tt.want {
+			t.Errorf("hslLegacy(%d, %d, %d): expected %v, got %v", tt.h, tt.s, tt.l, tt.want, got)
+		}
+	}
+}
+
+func TestWithLegacyColors(t *testing.T) {
+	// Background and wave colors PHP Wavatar draws for the hues of these hashes
+	tests := []struct {
+		hash       string
+		background color.RGBA
+		wave       color.RGBA
+	}{
+		{"test@example.com", color.RGBA{106, 82, 0, 255}, color.RGBA{255, 211, 106, 255}},
+		{"user1", color.RGBA{34, 0, 106, 255}, color.RGBA{106, 255, 251, 255}},
+		{"", color.RGBA{106, 103, 0, 255}, color.RGBA{106, 185, 255, 255}},
+		{"wavatar", color.RGBA{85, 0, 106, 255}, color.RGBA{188, 255, 106, 255}},
+	}
+	for _, tt := range tests {
+		s, err := Resolve([]byte(tt.hash), WithLegacyColors())
+		if err != nil {
+			t.Fatalf("Failed to resolve %q: %v", tt.hash, err)
+		}
+		if s.BackgroundRGBA != tt.background || s.WaveRGBA != tt.wave {
+			t.Errorf("Hash %q: expected background %v and wave %v, got %v and %v",
+				tt.hash, tt.background, tt.wave, s.BackgroundRGBA, s.WaveRGBA)
+		}
+	}
+
+	img, err := Generate([]byte("test@example.com"), WithLegacyColors())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	checkGolden(t, "legacy-colors", img)
+
+	// The last of the color options applies
+	a, _ := Resolve([]byte("user1"), WithLegacyColors(), WithPreciseColors())
+	b, _ := Resolve([]byte("user1"), WithPreciseColors())
+	if a != b {
+		t.Errorf("Expected WithPreciseColors after WithLegacyColors to win, got %+v and %+v", a, b)
+	}
+}
+
+// The integer desaturation agrees with the PHP float formula to within the
+// truncation of its result, for every saturation and every channel the hue
+// ramps produce
+func TestDesaturateMatchesPHP(t *testing.T) {
+	for s := 0; s <= 240; s++ {
+		for c := 0; c <= 256; c++ {
+			php := float64(c) + float64(240-s)/240*float64(128-c)
+			if got := desaturate(c, s); math.Abs(float64(got)-php) >= 1 {
+				t.Fatalf("Saturation %d, channel %d: expected %.3f, got %d", s, c, php, got)
+			}
+		}
+	}
+	// Full saturation leaves every channel alone, so defaults are unchanged
+	for c := 0; c <= 256; c++ {
+		if got := desaturate(c, 240); got != c {
+			t.Errorf("Expected channel %d unchanged at full saturation, got %d", c, got)
+		}
+	}
+}
+
+func TestSaturationGolden(t *testing.T) {
+	for _, s := range []int{0, 60, 120, 180} {
+		img, err := Generate([]byte("test@example.com"), WithSaturation(s, s))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		checkGolden(t, fmt.Sprintf("saturation-%d", s), img)
+	}
+}
@@ -0,0 +1,67 @@
+package wavatar
+
+import (
+	"fmt"
+	"slices"
+)
+
+// HueRange is an inclusive interval on the 0-240 hue wheel
+type HueRange struct {
+	Min, Max int
+}
+
+// WithHueRange limits background and wave hues to the union of ranges. The
+// hash-derived hue is spread proportionally over the allowed hues, so they are
+// still used evenly. Colors from WithColorblindSafe are not affected.
+func WithHueRange(ranges ...HueRange) Option {
+	return func(o *options) error {
+		if len(ranges) == 0 {
+			return fmt.Errorf("wavatar: no hue ranges given")
+		}
+		for _, r := range ranges {
+			if r.Min < 0 || r.Max > 240 || r.Min > r.Max {
+				return fmt.Errorf("wavatar: invalid hue range %d-%d", r.Min, r.Max)
+			}
+		}
+		o.hueRanges = mergeHueRanges(ranges)
+		return nil
+	}
+}
+
+// mergeHueRanges sorts ranges and joins the ones that overlap or touch
+func mergeHueRanges(ranges []HueRange) []HueRange {
+	sorted := slices.Clone(ranges)
+	slices.SortFunc(sorted, func(a, b HueRange) int { return a.Min - b.Min })
+
+	merged := sorted[:1]
+	for _, r := range sorted[1:] {
+		last := &merged[len(merged)-1]
+		if r.Min <= last.Max+1 {
+			last.Max = max(last.Max, r.Max)
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// remapHue maps a hash-derived hue in the 1-240 range onto the allowed ranges
+func remapHue(ranges []HueRange, hue int) int {
+	if len(ranges) == 0 {
+		return hue
+	}
+
+	total := 0
+	for _, r := range ranges {
+		total += r.Max - r.Min + 1
+	}
+	pos := (hue - 1) * total / 240
+	for _, r := range ranges {
+		if width := r.Max - r.Min + 1; pos >= width {
+			pos -= width
+		} else {
+			return r.Min + pos
+		}
+	}
+	return ranges[len(ranges)-1].Max
+}
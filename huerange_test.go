@@ -0,0 +1,70 @@
+package wavatar
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestHueRangeExcludesBand(t *testing.T) {
+	// Keep clear of the red hues used for alerts
+	allowed := HueRange{Min: 21, Max: 219}
+	o, err := newOptions([]Option{WithHueRange(allowed)})
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+
+	const n = 10000
+	counts := make([]int, 241)
+	for i := 0; i < n; i++ {
+		s := Describe([]byte(fmt.Sprintf("user%d@example.com", i)))
+		for _, hue := range []int{remapHue(o.hueRanges, s.Background), remapHue(o.hueRanges, s.WaveColor)} {
+			if hue < allowed.Min || hue > allowed.Max {
+				t.Fatalf("Expected hue in %d-%d, got %d", allowed.Min, allowed.Max, hue)
+			}
+			counts[hue]++
+		}
+	}
+
+	// Every allowed hue gets its share of the 2n samples, give or take
+	expected := 2 * n / (allowed.Max - allowed.Min + 1)
+	for hue := allowed.Min; hue <= allowed.Max; hue++ {
+		if counts[hue] < expected/3 || counts[hue] > 3*expected {
+			t.Errorf("Expected roughly %d samples of hue %d, got %d", expected, hue, counts[hue])
+		}
+	}
+}
+
+func TestHueRangeUnion(t *testing.T) {
+	ranges := mergeHueRanges([]HueRange{{200, 210}, {10, 20}, {15, 30}, {31, 40}})
+	if fmt.Sprint(ranges) != "[{10 40} {200 210}]" {
+		t.Errorf("Expected [{10 40} {200 210}], got %v", ranges)
+	}
+
+	seen := make(map[int]bool)
+	for hue := 1; hue <= 240; hue++ {
+		seen[remapHue(ranges, hue)] = true
+	}
+	for _, r := range ranges {
+		for hue := r.Min; hue <= r.Max; hue++ {
+			if !seen[hue] {
+				t.Errorf("Expected hue %d to be reachable", hue)
+			}
+		}
+	}
+	if len(seen) != 42 {
+		t.Errorf("Expected 42 distinct hues, got %d", len(seen))
+	}
+}
+
+func TestHueRangeInvalid(t *testing.T) {
+	for _, ranges := range [][]HueRange{
+		nil,
+		{{Min: 30, Max: 20}},
+		{{Min: -1, Max: 20}},
+		{{Min: 0, Max: 241}},
+	} {
+		if _, err := Generate(nil, WithHueRange(ranges...)); err == nil {
+			t.Errorf("Expected an error for hue ranges %v", ranges)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package wavatar
+
+import (
+	"errors"
+	"image"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// WithInitialsMouth replaces the mouth with up to the first two characters of
+// text drawn in face, centered where the selected mouth would be.
+// All other layers stay hash-derived.
+func WithInitialsMouth(text string, face font.Face) Option {
+	return func(o *options) error {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return errors.New("wavatar: initials text must not be empty")
+		}
+		if face == nil {
+			return errors.New("wavatar: initials font face must not be nil")
+		}
+
+		if utf8.RuneCountInString(text) > 2 {
+			text = string([]rune(text)[:2])
+		}
+		o.initials = text
+		o.initialsFace = face
+		return nil
+	}
+}
+
+// drawInitials draws text in face centered on the visible area of the mouth part
+func drawInitials(img *image.RGBA, parts *partSet, text string, face font.Face, mouth int) error {
+	part, err := parts.load("mouth", mouth)
+	if err != nil {
+		return err
+	}
+	region := visibleBounds(toRGBA(part))
+	if region.Empty() {
+		region = img.Bounds()
+	}
+
+	d := &font.Drawer{Dst: img, Src: image.Black, Face: face}
+	width := d.MeasureString(text)
+	metrics := face.Metrics()
+	height := metrics.Ascent + metrics.Descent
+
+	center := region.Min.Add(region.Max).Div(2)
+	d.Dot = fixed.Point26_6{
+		X: fixed.I(center.X) - width/2,
+		Y: fixed.I(center.Y) - height/2 + metrics.Ascent,
+	}
+	d.DrawString(text)
+	return nil
+}
+
+// visibleBounds returns the smallest rectangle containing every non-transparent pixel of img
+func visibleBounds(img *image.RGBA) image.Rectangle {
+	var bounds image.Rectangle
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.Pix[img.PixOffset(x, y)+3] > 0 {
+				bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return bounds
+}
@@ -0,0 +1,68 @@
+package wavatar
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestInitialsMouth(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+
+	img, err := Generate(hash, WithInitialsMouth("JD", basicfont.Face7x13))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	initials := img.(*image.RGBA)
+
+	// Reference render with every layer except the mouth
+	noMouth := mustRenderFace(t, s, defaultOptions())
+	mustApplyImage(t, noMouth, "shine", s.Face)
+	mustApplyImage(t, noMouth, "brow", s.Brow)
+	mustApplyImage(t, noMouth, "eyes", s.Eyes)
+	mustApplyImage(t, noMouth, "pupils", s.Pupil)
+
+	_, stats, err := DiffImage(noMouth, initials)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if stats.Changed == 0 {
+		t.Fatal("Expected glyph pixels in the mouth region")
+	}
+
+	mouth := partBounds(t, "mouth", s.Mouth)
+	center := mouth.Min.Add(mouth.Max).Div(2)
+	if !center.In(stats.Bounds.Inset(-1)) {
+		t.Errorf("Expected glyphs %v to be centered around the mouth at %v", stats.Bounds, center)
+	}
+	if stats.Bounds.Dx() > 2*7+1 || stats.Bounds.Dy() > 13 {
+		t.Errorf("Expected at most two 7x13 glyphs, got %v", stats.Bounds)
+	}
+}
+
+func TestInitialsMouthTruncatesText(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	two, err := Generate(hash, WithInitialsMouth("ÅB", basicfont.Face7x13))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	three, err := Generate(hash, WithInitialsMouth(" ÅBC ", basicfont.Face7x13))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(two, three); stats.Changed != 0 {
+		t.Error("Expected only the first two characters to be drawn")
+	}
+}
+
+func TestInitialsMouthValidation(t *testing.T) {
+	if _, err := Generate(nil, WithInitialsMouth("  ", basicfont.Face7x13)); err == nil {
+		t.Error("Expected an error for empty initials")
+	}
+	if _, err := Generate(nil, WithInitialsMouth("AB", nil)); err == nil {
+		t.Error("Expected an error for a nil font face")
+	}
+}
@@ -0,0 +1,60 @@
+package wavatar
+
+import (
+	"encoding/hex"
+	"fmt"
+	"image"
+	"strconv"
+)
+
+// legacyDigits is the length of the hex MD5 digest the PHP Wavatar reads
+const legacyDigits = 32
+
+// DescribeLegacy returns the Spec the original PHP Wavatar, as served by
+// Gravatar for d=wavatar, selects for an MD5 digest in hex. Instead of seeding
+// a random stream it reads each layer from two hex digits of the digest,
+// starting at the second digit, and takes them modulo the number of choices.
+func DescribeLegacy(md5hex string) (Spec, error) {
+	if len(md5hex) != legacyDigits {
+		return Spec{}, fmt.Errorf("wavatar: legacy digest %q is not %d hex digits", md5hex, legacyDigits)
+	}
+	if _, err := hex.DecodeString(md5hex); err != nil {
+		return Spec{}, fmt.Errorf("wavatar: legacy digest %q is not hex", md5hex)
+	}
+
+	// pick is hexdec(substr($seed, offset, 2)) % n
+	pick := func(offset, n int) int {
+		v, _ := strconv.ParseUint(md5hex[offset:offset+2], 16, 8)
+		return int(v) % n
+	}
+	s := Spec{
+		Face:       pick(1, FaceCount) + 1,
+		Background: pick(3, 240),
+		Fade:       pick(5, BgCount) + 1,
+		WaveColor:  pick(7, 240),
+		Brow:       pick(9, BrowCount) + 1,
+		Eyes:       pick(11, EyeCount) + 1,
+		Pupil:      pick(13, PupilCount) + 1,
+		Mouth:      pick(15, MouthCount) + 1,
+	}
+	// PHP hues run from 0 to 239, ours from 1 to 240; hues 0 and 240 are the same red
+	if s.Background == 0 {
+		s.Background = 240
+	}
+	if s.WaveColor == 0 {
+		s.WaveColor = 240
+	}
+	return s, nil
+}
+
+// NewLegacy renders the avatar the original PHP Wavatar draws for an MD5
+// digest in hex, with the parts of DescribeLegacy and the colors of
+// WithLegacyColors, so users migrated from it keep their avatar. opts apply
+// after WithLegacyColors, as for GenerateFromSpec.
+func NewLegacy(md5hex string, opts ...Option) (image.Image, error) {
+	s, err := DescribeLegacy(md5hex)
+	if err != nil {
+		return nil, err
+	}
+	return Default().GenerateFromSpec(s, append([]Option{WithLegacyColors()}, opts...)...)
+}
@@ -0,0 +1,98 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+)
+
+// Parts the PHP Wavatar selects for the MD5 digests of a few addresses
+func TestDescribeLegacy(t *testing.T) {
+	tests := []struct {
+		address string
+		md5hex  string
+		want    Spec
+	}{
+		{"test@example.com", "55502f40dc8b7c769880b10874abc9d0",
+			Spec{Face: 9, Background: 2, Fade: 1, WaveColor: 13, Brow: 1, Eyes: 2, Pupil: 2, Mouth: 11}},
+		{"", "d41d8cd98f00b204e9800998ecf8427e",
+			Spec{Face: 11, Background: 216, Fade: 2, WaveColor: 152, Brow: 1, Eyes: 12, Pupil: 11, Mouth: 3}},
+		{"user1@example.com", "111d68d06e2d317b5a59c2c6c5bad808",
+			Spec{Face: 7, Background: 214, Fade: 2, WaveColor: 6, Brow: 3, Eyes: 4, Pupil: 2, Mouth: 11}},
+		{"beau@dentedreality.com.au", "205e460b479e2e5b48aec07710c08d50",
+			Spec{Face: 6, Background: 228, Fade: 1, WaveColor: 180, Brow: 2, Eyes: 6, Pupil: 10, Mouth: 10}},
+	}
+	for _, tt := range tests {
+		got, err := DescribeLegacy(tt.md5hex)
+		if err != nil {
+			t.Fatalf("Failed to describe %s: %v", tt.md5hex, err)
+		}
+		if got != tt.want {
+			t.Errorf("Address %q: expected %v, got %v", tt.address, tt.want, got)
+		}
+		// hexdec reads either case
+		if upper, _ := DescribeLegacy(strings.ToUpper(tt.md5hex)); upper != got {
+			t.Errorf("Address %q: expected the uppercase digest to select %v, got %v", tt.address, got, upper)
+		}
+	}
+}
+
+func TestDescribeLegacyHueZero(t *testing.T) {
+	// f0 is 240, which PHP takes modulo 240 to hue 0
+	s, err := DescribeLegacy("000f000f00000000000000000000000")
+	if err == nil {
+		t.Fatalf("Expected an error for 31 digits, got %v", s)
+	}
+	s, err = DescribeLegacy("000f000f000000000000000000000000")
+	if err != nil {
+		t.Fatalf("Failed to describe: %v", err)
+	}
+	if s.Background != 240 || s.WaveColor != 240 {
+		t.Errorf("Expected hue 0 as 240, got background %d and wave %d", s.Background, s.WaveColor)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected a valid spec, got %v", err)
+	}
+	if a, b := hslLegacy(0, 240, 50), hslLegacy(240, 240, 50); [3]int(a) != [3]int(b) {
+		t.Errorf("Expected hues 0 and 240 to share a color, got %v and %v", a, b)
+	}
+}
+
+func TestDescribeLegacyInvalid(t *testing.T) {
+	for _, md5hex := range []string{"", "55502f40", "55502f40dc8b7c769880b10874abc9d0ff", "55502f40dc8b7c769880b10874abc9dz"} {
+		if _, err := DescribeLegacy(md5hex); err == nil {
+			t.Errorf("Expected an error for %q", md5hex)
+		}
+		if _, err := NewLegacy(md5hex); err == nil {
+			t.Errorf("Expected NewLegacy to fail for %q", md5hex)
+		}
+	}
+}
+
+func TestNewLegacy(t *testing.T) {
+	const md5hex = "55502f40dc8b7c769880b10874abc9d0"
+	img, err := NewLegacy(md5hex)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	checkGolden(t, "legacy-email", img)
+
+	s, _ := DescribeLegacy(md5hex)
+	want, err := GenerateFromSpec(s, WithLegacyColors())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, want.(*image.RGBA).Pix) {
+		t.Error("Expected NewLegacy to render its spec in legacy colors")
+	}
+
+	// Options apply on top
+	sized, err := NewLegacy(md5hex, WithSize(40))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if got := sized.Bounds().Dx(); got != 40 {
+		t.Errorf("Expected width 40, got %d", got)
+	}
+}
@@ -0,0 +1,65 @@
+package wavatar
+
+import (
+	"image"
+	"image/draw"
+)
+
+// lineArtStyle controls how the line work of an avatar is drawn
+type lineArtStyle struct {
+	// threshold snaps every pixel to black or white at cutoff
+	threshold bool
+	cutoff    uint8
+}
+
+// WithLineArt draws only the dark line work on white, for coloring books and
+// low ink printing: the mask outline and the features, without background,
+// fade, shine or wave fill. The features are the ones the hash selects anyway.
+func WithLineArt() Option {
+	return func(o *options) error {
+		if o.lineArt == nil {
+			o.lineArt = &lineArtStyle{}
+		}
+		return nil
+	}
+}
+
+// WithLineArtThreshold is WithLineArt with every pixel darker than cutoff, by
+// Rec. 709 luma on the 0-255 scale, turned black and every other pixel white
+func WithLineArtThreshold(cutoff uint8) Option {
+	return func(o *options) error {
+		o.lineArt = &lineArtStyle{threshold: true, cutoff: cutoff}
+		return nil
+	}
+}
+
+// renderLineArt draws the mask of s on a white canvas, leaving the face unfilled
+func renderLineArt(s Spec, o *options) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	if o.sticker == nil && !o.transparent {
+		draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	o.lap(stageBackground)
+	if err := o.parts.apply(img, "mask", s.Face); err != nil {
+		return nil, err
+	}
+	o.lap(stageMask)
+	return img, nil
+}
+
+// thresholdRGBA snaps every pixel of img to black or white at cutoff, keeping alpha
+func thresholdRGBA(img *image.RGBA, cutoff uint8) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := img.Pix[i+3]
+		if a == 0 {
+			continue
+		}
+		// Comparing premultiplied luma against the premultiplied cutoff avoids dividing by alpha
+		luma := 0.2126*float64(img.Pix[i]) + 0.7152*float64(img.Pix[i+1]) + 0.0722*float64(img.Pix[i+2])
+		v := a
+		if luma < float64(cutoff)*float64(a)/255 {
+			v = 0
+		}
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2] = v, v, v
+	}
+}
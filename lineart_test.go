@@ -0,0 +1,81 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestLineArtGolden(t *testing.T) {
+	for name, hash := range map[string]string{"email": "test@example.com", "user1": "user1@example.com"} {
+		img, err := Generate([]byte(hash), WithLineArt())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		checkGolden(t, "lineart-"+name, img)
+
+		img, err = Generate([]byte(hash), WithLineArtThreshold(128))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		checkGolden(t, "lineart-threshold-"+name, img)
+	}
+}
+
+func TestLineArtThresholdColors(t *testing.T) {
+	allowed := map[color.RGBA]bool{
+		{A: 255}:                         true,
+		{R: 255, G: 255, B: 255, A: 255}: true,
+	}
+	for _, hash := range []string{"test@example.com", "user1@example.com", "user2@example.com"} {
+		for _, cutoff := range []uint8{64, 128, 200} {
+			img, err := Generate([]byte(hash), WithLineArtThreshold(cutoff))
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			rgba := img.(*image.RGBA)
+			seen := map[color.RGBA]bool{}
+			for y := 0; y < AvatarSize; y++ {
+				for x := 0; x < AvatarSize; x++ {
+					c := rgba.RGBAAt(x, y)
+					if !allowed[c] {
+						t.Fatalf("%s cutoff %d: expected only black and white, got %v at %d,%d", hash, cutoff, c, x, y)
+					}
+					seen[c] = true
+				}
+			}
+			if len(seen) != 2 {
+				t.Errorf("%s cutoff %d: expected both black and white, got %v", hash, cutoff, seen)
+			}
+		}
+	}
+}
+
+func TestLineArtKeepsFeatures(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+
+	img, err := Generate(hash, WithLineArt())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	rgba := img.(*image.RGBA)
+
+	// Every opaque pixel of the selected eyes is drawn unchanged
+	eyes := toRGBA(mustLoadPart(t, "eyes", s.Eyes))
+	pupils := partBounds(t, "pupils", s.Pupil)
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			if c := eyes.RGBAAt(x, y); c.A == 255 && !image.Pt(x, y).In(pupils) {
+				if got := rgba.RGBAAt(x, y); got != c {
+					t.Fatalf("Expected eye pixel %v at %d,%d, got %v", c, x, y, got)
+				}
+			}
+		}
+	}
+
+	// The corners outside the face stay white
+	if got := rgba.RGBAAt(0, 0); got != (color.RGBA{R: 255, G: 255, B: 255, A: 255}) {
+		t.Errorf("Expected a white background, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package wavatar
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// defaultSlowRender is how long a render may take before a Generator with a logger reports it
+const defaultSlowRender = 100 * time.Millisecond
+
+// WithLogger makes a Generator log its Generate calls to l. Render cache hits
+// and misses and part loads are logged at debug level, renders slower than
+// the WithSlowRenderThreshold at info level and failed renders at error
+// level. Entries carry hash_prefix, the first bytes of the hash in hex, and
+// renders also size, style and duration_ms. Parts get a cache of their own,
+// so their loads are logged by the Generator that uses them. Renders timed
+// with WithTiming also log their stage durations at debug level.
+// It only takes effect when passed to NewGenerator.
+func WithLogger(l *slog.Logger) Option {
+	return func(o *options) error {
+		if l == nil {
+			return fmt.Errorf("wavatar: logger is nil")
+		}
+		o.logger = l
+		return nil
+	}
+}
+
+// WithSlowRenderThreshold sets how long a render may take before WithLogger
+// reports it, replacing the default of 100ms.
+// It only takes effect when passed to NewGenerator.
+func WithSlowRenderThreshold(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("wavatar: slow render threshold %v must be positive", d)
+		}
+		o.slowRender = d
+		return nil
+	}
+}
+
+// renderLogger logs the lifecycle of renders, doing nothing when nil
+type renderLogger struct {
+	l    *slog.Logger
+	slow time.Duration
+}
+
+// enabled reports whether entries at level are logged. Callers check it
+// before building attributes so disabled logging allocates nothing.
+func (r *renderLogger) enabled(level slog.Level) bool {
+	return r != nil && r.l.Enabled(context.Background(), level)
+}
+
+// cache logs whether the render cache held the avatar for hash
+func (r *renderLogger) cache(hash []byte, hit bool) {
+	if !r.enabled(slog.LevelDebug) {
+		return
+	}
+	msg := "render cache miss"
+	if hit {
+		msg = "render cache hit"
+	}
+	r.l.LogAttrs(context.Background(), slog.LevelDebug, msg, hashPrefix(hash))
+}
+
+// render logs a render of hash with o that started at start and ended with err.
+// o is nil when the options themselves failed.
+func (r *renderLogger) render(hash []byte, o *options, start time.Time, err error) {
+	d := time.Since(start)
+	level, msg := slog.LevelInfo, "slow render"
+	if err != nil {
+		level, msg = slog.LevelError, "render failed"
+	} else if d < r.threshold() {
+		return
+	}
+	if !r.enabled(level) {
+		return
+	}
+
+	attrs := []slog.Attr{hashPrefix(hash)}
+	if o != nil {
+		attrs = append(attrs, slog.Int("size", o.outputSize()), slog.String("style", o.style()))
+	}
+	attrs = append(attrs, durationMs(d))
+	if err != nil {
+		attrs = append(attrs, errorAttrs(err)...)
+	}
+	r.l.LogAttrs(context.Background(), level, msg, attrs...)
+}
+
+// part logs that the part num of kind part was loaded in d
+func (r *renderLogger) part(part string, num int, d time.Duration, err error) {
+	if !r.enabled(slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{slog.String("part", part), slog.Int("index", num), durationMs(d)}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	r.l.LogAttrs(context.Background(), slog.LevelDebug, "part loaded", attrs...)
+}
+
+// timings logs the stage durations of a render of hash timed with WithTiming
+func (r *renderLogger) timings(hash []byte, t *Timings) {
+	if t == nil || !r.enabled(slog.LevelDebug) {
+		return
+	}
+	r.l.LogAttrs(context.Background(), slog.LevelDebug, "render timings", hashPrefix(hash),
+		slog.Duration("background", t.Background), slog.Duration("fade", t.Fade),
+		slog.Duration("mask", t.Mask), slog.Duration("fill", t.Fill), slog.Duration("shine", t.Shine),
+		slog.Duration("brow", t.Brow), slog.Duration("eyes", t.Eyes), slog.Duration("pupils", t.Pupils),
+		slog.Duration("mouth", t.Mouth), slog.Duration("post_process", t.PostProcess),
+		slog.Duration("total", t.Total))
+}
+
+// threshold returns the duration above which a render is slow
+func (r *renderLogger) threshold() time.Duration {
+	if r == nil || r.slow == 0 {
+		return defaultSlowRender
+	}
+	return r.slow
+}
+
+// hashPrefix is the first four bytes of hash in hex, enough to tell renders apart
+func hashPrefix(hash []byte) slog.Attr {
+	return slog.String("hash_prefix", hex.EncodeToString(hash[:min(len(hash), 4)]))
+}
+
+// durationMs is d in fractional milliseconds
+func durationMs(d time.Duration) slog.Attr {
+	return slog.Float64("duration_ms", float64(d)/float64(time.Millisecond))
+}
+
+// errorAttrs describes err, with the part that failed for a PartError
+func errorAttrs(err error) []slog.Attr {
+	attrs := []slog.Attr{slog.String("error", err.Error())}
+	var perr *PartError
+	var oerr *OptionsError
+	switch {
+	case errors.As(err, &perr):
+		attrs = append(attrs, slog.String("error_kind", "part"), slog.String("part", perr.Part), slog.Int("index", perr.Index))
+	case errors.As(err, &oerr):
+		attrs = append(attrs, slog.String("error_kind", "options"))
+	default:
+		attrs = append(attrs, slog.String("error_kind", "render"))
+	}
+	return attrs
+}
+
+// outputSize is the side of the avatars o renders, before any sticker cropping
+func (o *options) outputSize() int {
+	if o.size > 0 {
+		return o.size
+	}
+	return AvatarSize
+}
+
+// style names how o draws the avatar
+func (o *options) style() string {
+	switch {
+	case o.sticker != nil:
+		return "sticker"
+	case o.lineArt != nil:
+		return "lineart"
+	default:
+		return "full"
+	}
+}
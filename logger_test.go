@@ -0,0 +1,151 @@
+package wavatar
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// captureHandler keeps every record it handles at or above its level
+type captureHandler struct {
+	level slog.Level
+	mu    sync.Mutex
+	recs  []slog.Record
+}
+
+func (h *captureHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recs = append(h.recs, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
+
+// records returns the messages handled so far with the keys of their attributes
+func (h *captureHandler) records() map[string][]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string][]string)
+	for _, r := range h.recs {
+		var keys []string
+		r.Attrs(func(a slog.Attr) bool {
+			keys = append(keys, a.Key)
+			return true
+		})
+		out[r.Message] = keys
+	}
+	return out
+}
+
+func TestLoggerSchema(t *testing.T) {
+	h := &captureHandler{level: slog.LevelDebug}
+	g, err := NewGenerator(nil, WithLogger(slog.New(h)), WithRenderCache(4), WithSlowRenderThreshold(time.Nanosecond))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	hash := []byte("test@example.com")
+	for range 2 {
+		if _, err := g.Generate(hash); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+	}
+
+	want := map[string][]string{
+		"render cache miss": {"hash_prefix"},
+		"render cache hit":  {"hash_prefix"},
+		"part loaded":       {"part", "index", "duration_ms"},
+		"slow render":       {"hash_prefix", "size", "style", "duration_ms"},
+	}
+	got := h.records()
+	for msg, keys := range want {
+		if !slices.Equal(got[msg], keys) {
+			t.Errorf("%s: expected attributes %v, got %v", msg, keys, got[msg])
+		}
+	}
+
+	var prefix string
+	h.recs[0].Attrs(func(a slog.Attr) bool {
+		prefix = a.Value.String()
+		return false
+	})
+	if prefix != "74657374" {
+		t.Errorf("Expected the hash prefix 74657374, got %s", prefix)
+	}
+}
+
+func TestLoggerFailure(t *testing.T) {
+	h := &captureHandler{level: slog.LevelInfo}
+	pack := loosePack(t)
+	pack[fmt.Sprintf("mask%d.png", Describe([]byte("test@example.com")).Face)].Data = []byte("not a png")
+	g, err := NewGenerator(pack, WithLogger(slog.New(h)))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if _, err := g.Generate([]byte("test@example.com"), WithSize(128)); err == nil {
+		t.Fatal("Expected an error for a broken part source")
+	}
+	if _, err := g.Generate([]byte("test@example.com"), WithBlur(-1)); err == nil {
+		t.Fatal("Expected an error for an invalid option")
+	}
+
+	if len(h.recs) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(h.recs))
+	}
+	for i, want := range [][]string{
+		{"hash_prefix", "size", "style", "duration_ms", "error", "error_kind", "part", "index"},
+		{"hash_prefix", "duration_ms", "error", "error_kind"},
+	} {
+		r := h.recs[i]
+		var keys []string
+		r.Attrs(func(a slog.Attr) bool {
+			keys = append(keys, a.Key)
+			return true
+		})
+		if r.Level != slog.LevelError || r.Message != "render failed" || !slices.Equal(keys, want) {
+			t.Errorf("Record %d: expected an error with %v, got %v %q with %v", i, want, r.Level, r.Message, keys)
+		}
+	}
+}
+
+func TestLoggerNoAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	hash := []byte("test@example.com")
+	allocs := func(opts ...Option) float64 {
+		g, err := NewGenerator(nil, append(opts, WithRenderCache(4))...)
+		if err != nil {
+			t.Fatalf("Failed to create generator: %v", err)
+		}
+		if _, err := g.Generate(hash); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		// AllocsPerRun counts every goroutine and a GC empties the pools, so
+		// the fewest of a few runs is the cost of the renders themselves
+		fewest := math.Inf(1)
+		for range 3 {
+			fewest = min(fewest, testing.AllocsPerRun(100, func() {
+				g.Generate(hash)
+				g.Generate(hash, WithInvert())
+			}))
+		}
+		return fewest
+	}
+
+	base := allocs()
+	disabled := allocs(WithLogger(slog.New(&captureHandler{level: slog.LevelError})))
+	if disabled != base {
+		t.Errorf("Expected a disabled logger to allocate nothing, got %v allocations against %v", disabled, base)
+	}
+}
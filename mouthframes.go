@@ -0,0 +1,77 @@
+package wavatar
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// mouthFrames lists for every mouth the closed, half-open and open mouths a
+// talking animation swaps between, drawn in the same style
+var mouthFrames = [MouthCount + 1][3]int{
+	1:  {1, 2, 5},    // slanted line
+	2:  {7, 2, 5},    // small round mouth, already half open
+	3:  {3, 2, 5},    // frown
+	4:  {4, 18, 5},   // smile
+	5:  {4, 18, 5},   // open smile
+	6:  {6, 2, 5},    // line with the tongue out
+	7:  {7, 2, 5},    // straight line
+	8:  {8, 8, 8},    // taped shut
+	9:  {10, 5, 9},   // open smile with a tooth
+	10: {10, 5, 9},   // line with a tooth
+	11: {17, 12, 11}, // grin
+	12: {17, 12, 11}, // grin with the upper teeth
+	13: {13, 2, 5},   // line with dimples
+	14: {14, 2, 5},   // zigzag
+	15: {17, 11, 15}, // fangs
+	16: {16, 2, 5},   // line with a tongue tip
+	17: {17, 12, 11}, // clenched teeth
+	18: {4, 18, 5},   // smile with the tongue out
+	19: {19, 2, 5},   // frown with dimples
+}
+
+// MouthFrames renders the avatar for hash of the default Generator three
+// times, with a closed, half-open and open mouth in the style of its own, for
+// clients that animate the avatar talking. Every other layer is identical across the frames.
+// Avatars without a mouth, see WithOptionalLayer, have no frames.
+func MouthFrames(hash []byte, opts ...Option) ([]image.Image, error) {
+	return Default().MouthFrames(hash, opts...)
+}
+
+// MouthFrames renders the avatar for hash with a closed, half-open and open mouth
+func (g *Generator) MouthFrames(hash []byte, opts ...Option) ([]image.Image, error) {
+	o, err := g.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	return mouthFramesOf(hash, o)
+}
+
+// mouthFramesOf renders the talking frames for hash with o
+func mouthFramesOf(hash []byte, o *options) ([]image.Image, error) {
+	if o.initials != "" {
+		return nil, errors.New("wavatar: cannot animate a mouth replaced by initials")
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+	if s.Mouth < 1 || s.Mouth > MouthCount {
+		return nil, fmt.Errorf("wavatar: no talking frames for mouth %d", s.Mouth)
+	}
+
+	frames := make([]image.Image, 0, len(mouthFrames[s.Mouth]))
+	for _, mouth := range mouthFrames[s.Mouth] {
+		s.Mouth = mouth
+		frame, err := o.fitSpec(s)
+		if err != nil {
+			return nil, err
+		}
+		img, err := generate(frame, o)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, img)
+	}
+	return frames, nil
+}
@@ -0,0 +1,88 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestMouthFramesTable(t *testing.T) {
+	for mouth := 1; mouth <= MouthCount; mouth++ {
+		frames := mouthFrames[mouth]
+		if frames[0] == 0 {
+			t.Errorf("Mouth %d: expected talking frames", mouth)
+			continue
+		}
+		for _, frame := range frames {
+			if frame < 1 || frame > MouthCount {
+				t.Errorf("Mouth %d: expected frames among the mouths, got %d", mouth, frame)
+			}
+		}
+	}
+}
+
+func TestMouthFrames(t *testing.T) {
+	for i := range 20 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		frames, err := MouthFrames(hash)
+		if err != nil {
+			t.Fatalf("Failed to render frames: %v", err)
+		}
+		if len(frames) != 3 {
+			t.Fatalf("Expected 3 frames, got %d", len(frames))
+		}
+
+		// Frames differ from each other only where one of their mouths is drawn
+		s := Describe(hash)
+		var region image.Rectangle
+		for _, mouth := range mouthFrames[s.Mouth] {
+			region = region.Union(partBounds(t, "mouth", mouth))
+		}
+		for j := 1; j < len(frames); j++ {
+			_, stats, err := DiffImage(frames[0], frames[j])
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if !stats.Bounds.In(region) {
+				t.Errorf("%s: expected frame %d to differ only within %v, got %v", hash, j, region, stats.Bounds)
+			}
+		}
+
+		again, err := MouthFrames(hash)
+		if err != nil {
+			t.Fatalf("Failed to render frames: %v", err)
+		}
+		for j := range frames {
+			if !bytes.Equal(frames[j].(*image.RGBA).Pix, again[j].(*image.RGBA).Pix) {
+				t.Errorf("%s: expected frame %d to be deterministic", hash, j)
+			}
+		}
+	}
+}
+
+func TestMouthFramesOwnMouth(t *testing.T) {
+	// The mouth of the avatar is one of its frames
+	for mouth := 1; mouth <= MouthCount; mouth++ {
+		found := false
+		for _, frame := range mouthFrames[mouth] {
+			found = found || frame == mouth
+		}
+		if !found {
+			t.Errorf("Mouth %d: expected it among its frames %v", mouth, mouthFrames[mouth])
+		}
+	}
+}
+
+func TestMouthFramesErrors(t *testing.T) {
+	hash := []byte("test@example.com")
+	if _, err := MouthFrames(hash, WithInitialsMouth("AB", basicfont.Face7x13)); err == nil || !strings.Contains(err.Error(), "initials") {
+		t.Errorf("Expected an error animating initials, got %v", err)
+	}
+	if _, err := MouthFrames(hash, WithOptionalLayer(LayerMouth, 0)); err == nil {
+		t.Error("Expected an error animating an absent mouth")
+	}
+}
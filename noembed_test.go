@@ -0,0 +1,80 @@
+//go:build wavatar_noembed
+
+package wavatar
+
+// Only the tests in this file work without the embedded parts, run them with
+// `go test -tags wavatar_noembed -run NoEmbed .`
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/weavatar/wavatar/wavatartest"
+)
+
+func TestNoEmbedRequiresPartSource(t *testing.T) {
+	if _, err := NewGenerator(nil); !errors.Is(err, errNoParts) {
+		t.Errorf("Expected errNoParts, got %v", err)
+	}
+	if _, err := Generate([]byte("test@example.com")); !errors.Is(err, errNoParts) {
+		t.Errorf("Expected errNoParts, got %v", err)
+	}
+	token, err := Describe([]byte("test@example.com")).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal spec: %v", err)
+	}
+	if _, err := NewFromBinary(token); !errors.Is(err, errNoParts) {
+		t.Errorf("Expected errNoParts, got %v", err)
+	}
+}
+
+func TestNoEmbedDirectoryGenerator(t *testing.T) {
+	g, err := NewGenerator(os.DirFS("parts"))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	img, err := g.Generate([]byte("test@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	golden := wavatartest.LoadGolden(t, filepath.Join("testdata", "golden", "default-email.png"))
+	if err := wavatartest.CompareImages(img, golden, 0, 0); err != nil {
+		t.Errorf("Golden default-email mismatch: %v", err)
+	}
+}
+
+func TestNoEmbedSetDefault(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+
+	g, err := NewGenerator(os.DirFS("parts"))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(g)
+
+	golden := wavatartest.LoadGolden(t, filepath.Join("testdata", "golden", "default-email.png"))
+	if err := wavatartest.CompareImages(New([]byte("test@example.com")), golden, 0, 0); err != nil {
+		t.Errorf("Golden default-email mismatch: %v", err)
+	}
+
+	token, err := Describe([]byte("test@example.com")).MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal spec: %v", err)
+	}
+	img, err := NewFromBinary(token)
+	if err != nil {
+		t.Fatalf("Failed to render token: %v", err)
+	}
+	if err := wavatartest.CompareImages(img, golden, 0, 0); err != nil {
+		t.Errorf("Golden default-email mismatch for the token: %v", err)
+	}
+}
+
+func TestNoEmbedExtractParts(t *testing.T) {
+	if err := ExtractParts(t.TempDir(), false); !errors.Is(err, errNoParts) {
+		t.Errorf("Expected errNoParts, got %v", err)
+	}
+}
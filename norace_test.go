@@ -0,0 +1,5 @@
+//go:build !race
+
+package wavatar
+
+const raceEnabled = false
@@ -0,0 +1,52 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+)
+
+// WithOptionalLayer makes layer present in only about presentProbability of
+// avatars, decided by the hash, for more variety or minimalist part packs.
+// Any layer but LayerFace can be optional. The other layers are selected as
+// before, and the Spec from Resolve records an absent layer as index 0.
+// An absent mouth also leaves out the initials of WithInitialsMouth.
+// Avatars not made from a hash, as with NewFromSource, are not affected.
+func WithOptionalLayer(layer Layer, presentProbability float64) Option {
+	return func(o *options) error {
+		if layer <= LayerFace || layer > LayerMouth {
+			return fmt.Errorf("wavatar: layer %v cannot be optional", layer)
+		}
+		if !(presentProbability >= 0 && presentProbability <= 1) {
+			return fmt.Errorf("wavatar: present probability %v out of range [0, 1]", presentProbability)
+		}
+		if o.optional == nil {
+			o.optional = make(map[Layer]float64)
+		}
+		o.optional[layer] = presentProbability
+		return nil
+	}
+}
+
+// dropAbsent sets the index of every optional layer of s that hash rolls absent to 0.
+// Each layer rolls on its own stream, so the selections of s are not shifted.
+func dropAbsent(s Spec, hash []byte, optional map[Layer]float64) Spec {
+	seed := hashSeed(hash)
+	for layer, p := range optional {
+		if p == 1 {
+			continue
+		}
+		if layerRand(seed, "present-"+layer.String()).Float64() >= p {
+			index, _, _ := s.layer(layer)
+			*index = 0
+		}
+	}
+	return s
+}
+
+// applyPresent draws a part over base unless num is 0, which marks an absent layer
+func (p *partSet) applyPresent(base *image.RGBA, part string, num int) error {
+	if num == 0 {
+		return nil
+	}
+	return p.apply(base, part, num)
+}
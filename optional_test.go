@@ -0,0 +1,114 @@
+package wavatar
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestOptionalLayerRate(t *testing.T) {
+	const n = 4000
+	for _, p := range []float64{0, 0.3, 0.75} {
+		absent := 0
+		for i := range n {
+			hash := []byte(fmt.Sprintf("user%d@example.com", i))
+			s, err := Resolve(hash, WithOptionalLayer(LayerBrow, p))
+			if err != nil {
+				t.Fatalf("Failed to resolve: %v", err)
+			}
+
+			// Only the brow may change, everything else is selected as before
+			want := Describe(hash)
+			if s.Brow == 0 {
+				absent++
+				want.Brow = 0
+			}
+			s.BackgroundRGBA, s.WaveRGBA = want.BackgroundRGBA, want.WaveRGBA
+			if s != want {
+				t.Fatalf("Expected %+v, got %+v", want, s)
+			}
+		}
+
+		// Four standard deviations of the binomial distribution
+		rate, want := float64(absent)/n, 1-p
+		if tolerance := 4 * math.Sqrt(p*(1-p)/n); math.Abs(rate-want) > tolerance {
+			t.Errorf("Probability %v: expected an absence rate of %.3f ± %.3f, got %.3f", p, want, tolerance, rate)
+		}
+	}
+}
+
+func TestOptionalLayerAlwaysPresent(t *testing.T) {
+	opts := []Option{
+		WithOptionalLayer(LayerFade, 1),
+		WithOptionalLayer(LayerBrow, 1),
+		WithOptionalLayer(LayerEyes, 1),
+		WithOptionalLayer(LayerPupils, 1),
+		WithOptionalLayer(LayerMouth, 1),
+	}
+	for i := range 20 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		img, err := Generate(hash, opts...)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(New(hash), img); stats.Changed != 0 {
+			t.Errorf("%s: expected a probability of 1 to change nothing", hash)
+		}
+	}
+}
+
+func TestOptionalLayerAbsentBrow(t *testing.T) {
+	// Find a hash whose brow is left out at even odds
+	var hash []byte
+	for i := 0; hash == nil; i++ {
+		candidate := []byte(fmt.Sprintf("user%d@example.com", i))
+		if s, _ := Resolve(candidate, WithOptionalLayer(LayerBrow, 0.5)); s.Brow == 0 {
+			hash = candidate
+		}
+	}
+
+	img, err := Generate(hash, WithOptionalLayer(LayerBrow, 0.5))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	checkGolden(t, "absent-brow", img)
+
+	// The render differs from the default only where the brow was
+	s := Describe(hash)
+	_, stats, err := DiffImage(New(hash), img)
+	if err != nil {
+		t.Fatalf("Failed to diff: %v", err)
+	}
+	if brow := partBounds(t, "brow", s.Brow); stats.Changed == 0 || !stats.Bounds.In(brow) {
+		t.Errorf("Expected changes only within the brow %v, got %v", brow, stats.Bounds)
+	}
+
+	// A Spec with the brow absent renders the same and has no binary form
+	s.Brow = 0
+	fromSpec, err := GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(img, fromSpec); stats.Changed != 0 {
+		t.Error("Expected the Spec with an absent brow to render the same")
+	}
+	if _, err := s.MarshalBinary(); err == nil {
+		t.Error("Expected an error marshaling an absent layer")
+	}
+}
+
+func TestOptionalLayerValidation(t *testing.T) {
+	if _, err := Generate(nil, WithOptionalLayer(LayerFace, 0.5)); err == nil {
+		t.Error("Expected an error for an optional face")
+	}
+	for _, p := range []float64{-0.1, 1.1, math.NaN()} {
+		if _, err := Generate(nil, WithOptionalLayer(LayerMouth, p)); err == nil {
+			t.Errorf("Expected an error for probability %v", p)
+		}
+	}
+	s := Describe([]byte("test@example.com"))
+	s.Face = 0
+	if err := s.Validate(); err == nil {
+		t.Error("Expected an error for an absent face")
+	}
+}
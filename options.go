@@ -0,0 +1,343 @@
+package wavatar
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/image/font"
+)
+
+// Option configures how Generate renders an avatar
+type Option func(*options) error
+
+// options holds the settings collected from a list of Option values
+type options struct {
+	// filters run in order on the composited avatar
+	filters []func(*image.RGBA)
+	// outline is the width of the stroke around the features, 0 for none
+	outline int
+	// seasonal is the overlay drawn on top of the avatar
+	seasonal SeasonalKind
+	// alphaFill composites the wave color beneath the mask instead of flood filling
+	alphaFill bool
+	// fillConnectivity is 4 or 8, the neighbors the face fill spreads to
+	fillConnectivity int
+	// initials replace the mouth when set, drawn in initialsFace
+	initials     string
+	initialsFace font.Face
+	// background replaces the uniform background fill when set
+	background func(dst *image.RGBA, seed uint64)
+	// transparent leaves out the background and fade
+	transparent bool
+	// colorMode is the arithmetic of the hsl conversion
+	colorMode colorMode
+	// circle makes everything outside the inscribed circle transparent
+	circle bool
+	// palette restricts the background and wave colors when set
+	palette *safePalette
+	// saturation and lightness of the background and wave colors on the 0-240 scale
+	bgSaturation, waveSaturation int
+	bgLightness, waveLightness   int
+	// hueRanges are the merged hue intervals colors may use, nil for the whole wheel
+	hueRanges []HueRange
+	// complementWave colors the face opposite the background instead of with its own hue
+	complementWave bool
+	// domainHue extracts the domain that picks the background hue band, nil to disable
+	domainHue func(input []byte) string
+	// canonicalize describes the canonical form of a hash, see Canonicalize
+	canonicalize bool
+	// defaultOnEmpty is rendered instead of empty and all zero hashes when set
+	defaultOnEmpty image.Image
+	// version is the algorithm that describes a hash
+	version Version
+	// parts is where part images are loaded from
+	parts *partSet
+	// encodeConcurrency is how many sizes EncodeSizes encodes at once
+	encodeConcurrency int
+	// memoryBudget caps the bytes a Generator caches, 0 for no limit
+	memoryBudget int64
+	// preload decodes and checks every part when a Generator is created
+	preload bool
+	// sticker outlines the face on a transparent canvas when set
+	sticker *stickerStyle
+	// size is the side of the returned avatar, 0 for AvatarSize
+	size int
+	// faceCrop crops the avatar to the face plus faceCropMargin pixels
+	faceCrop       bool
+	faceCropMargin int
+	// lineArt draws only the mask outline and features when set
+	lineArt *lineArtStyle
+	// despeckle cleans up single pixels in Stencil output
+	despeckle bool
+	// dither is how palette-limited outputs approximate colors
+	dither DitherMode
+	// shineIntensity scales the alpha of the shine layer, 1 for the parts as drawn
+	shineIntensity float64
+	// fadeIntensity scales the alpha of the fade layer, 1 for the parts as drawn
+	fadeIntensity float64
+	// countPolicy is how a Generator handles a part pack with other counts, 0 when unset
+	countPolicy CountPolicy
+	// counts are the number of parts per layer of the part source
+	counts layerCounts
+	// renderCache is how many avatars a Generator caches, 0 for none
+	renderCache int
+	// timings receives the stage durations of the render, nil for none
+	timings *Timings
+	// timingStart and lapStart are when the timed render and its current stage began
+	timingStart, lapStart time.Time
+	// logger receives the render lifecycle of a Generator, nil for none
+	logger *slog.Logger
+	// slowRender is how long a render may take before it is logged, 0 for the default
+	slowRender time.Duration
+	// renderTimeout is how long a render may take, 0 for no limit
+	renderTimeout time.Duration
+	// ctx cancels a render between its stages, nil for never
+	ctx context.Context
+	// optional maps optional layers to the probability they are present
+	optional map[Layer]float64
+}
+
+// defaultOptions returns the settings used when no Option is given
+func defaultOptions() *options {
+	return &options{
+		bgSaturation:      240,
+		waveSaturation:    240,
+		bgLightness:       50,
+		waveLightness:     170,
+		version:           V1,
+		parts:             defaultParts,
+		encodeConcurrency: 1,
+		fillConnectivity:  4,
+		shineIntensity:    1,
+		fadeIntensity:     1,
+		counts:            defaultCounts,
+	}
+}
+
+// newOptions applies opts in order and returns the resulting settings. Every
+// option is applied even after one fails, and the conflicts between them
+// checked, so an *OptionsError lists all problems at once.
+func newOptions(opts []Option) (*options, error) {
+	o := defaultOptions()
+	var errs []error
+	for i, opt := range opts {
+		if opt == nil {
+			errs = append(errs, fmt.Errorf("wavatar: option %d is nil", i))
+		} else if err := opt(o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, o.conflicts()...)
+	if len(errs) > 0 {
+		return nil, &OptionsError{Errs: errs}
+	}
+	return o, nil
+}
+
+// backgroundColor returns the background color of s
+func (o *options) backgroundColor(s Spec) color.RGBA {
+	if s.BackgroundRGBA != (color.RGBA{}) {
+		return s.BackgroundRGBA
+	}
+	if o.palette != nil {
+		return paletteColor(o.palette.background, s.Background)
+	}
+	return o.hslColor(remapHue(o.hueRanges, s.Background), o.bgSaturation, o.bgLightness)
+}
+
+// waveColor returns the color the face of s is filled with
+func (o *options) waveColor(s Spec) color.RGBA {
+	if s.WaveRGBA != (color.RGBA{}) {
+		return s.WaveRGBA
+	}
+	if o.palette != nil {
+		return paletteColor(o.palette.wave, s.WaveColor)
+	}
+	hue := remapHue(o.hueRanges, s.WaveColor)
+	if o.complementWave {
+		hue = complementHue(remapHue(o.hueRanges, s.Background))
+	}
+	return o.hslColor(hue, o.waveSaturation, o.waveLightness)
+}
+
+// Generate creates a new Wavatar from a hash with the default Generator,
+// applying the given options after its own, see SetDefault
+func Generate(hash []byte, opts ...Option) (image.Image, error) {
+	return Default().Generate(hash, opts...)
+}
+
+// GenerateContext is Generate stopping early once ctx is done, see Generator.GenerateContext
+func GenerateContext(ctx context.Context, hash []byte, opts ...Option) (image.Image, error) {
+	return Default().GenerateContext(ctx, hash, opts...)
+}
+
+// generateHash describes hash according to o and renders it
+func generateHash(hash []byte, o *options) (image.Image, error) {
+	if o.emptyHash(hash) {
+		return o.defaultImage(), nil
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+	return generate(s, o)
+}
+
+// describeHash returns the Spec that o selects for hash
+func describeHash(hash []byte, o *options) (Spec, error) {
+	input := hash
+	if o.canonicalize {
+		hash, _ = Canonicalize(hash)
+	}
+	s, err := describeVersion(hash, o.version, o.selectionCounts())
+	if err != nil {
+		return Spec{}, err
+	}
+	if o.domainHue != nil {
+		s.Background = domainHue(o.domainHue(input), s.Background)
+	}
+	if o.optional != nil {
+		s = dropAbsent(s, hash, o.optional)
+	}
+	return o.fitSpec(s)
+}
+
+// NewFromSource creates a new Wavatar with every selection drawn from src
+// instead of a hash, in the same order Describe uses. The result is only
+// deterministic if src is; a source such as crypto/rand gives one-off avatars
+// that cannot be linked to any input. Options that need the input, such as
+// WithDomainHue and WithVersion, have no effect.
+func NewFromSource(src rand.Source, opts ...Option) (image.Image, error) {
+	if src == nil {
+		return nil, fmt.Errorf("wavatar: nil random source")
+	}
+	o, err := Default().options(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := o.fitSpec(describeFrom(rand.New(src), o.selectionCounts()))
+	if err != nil {
+		return nil, err
+	}
+	return generate(s, o)
+}
+
+// GenerateFromSpec renders the avatar described by s with the default
+// Generator, applying the given options after its own
+func GenerateFromSpec(s Spec, opts ...Option) (image.Image, error) {
+	return Default().GenerateFromSpec(s, opts...)
+}
+
+// generate renders s with o and runs the filter chain on the result
+func generate(s Spec, o *options) (image.Image, error) {
+	o.startTiming()
+	img, err := render(s, o)
+	if err != nil {
+		return nil, err
+	}
+	o.stopTiming()
+
+	return img, nil
+}
+
+// canceled returns an error once the context of the render is done
+func (o *options) canceled() error {
+	if o.ctx == nil {
+		return nil
+	}
+	if err := o.ctx.Err(); err != nil {
+		return fmt.Errorf("wavatar: render canceled: %w", err)
+	}
+	return nil
+}
+
+// WithPostProcess runs fns in order on the composited avatar at AvatarSize,
+// after all layers and before the sticker, face crop, WithSize scaling and
+// circle mask. Built-in filter options use the same chain, so user functions
+// and built-ins run in the order their options are given.
+// A panic inside a function is returned as an error from Generate.
+func WithPostProcess(fns ...func(*image.RGBA)) Option {
+	return func(o *options) error {
+		for i, fn := range fns {
+			if fn == nil {
+				return fmt.Errorf("wavatar: post-process function %d is nil", i)
+			}
+		}
+		o.filters = append(o.filters, fns...)
+		return nil
+	}
+}
+
+// WithBackgroundFunc draws the background with fn instead of the uniform
+// background color. fn receives a seed derived from the avatar so custom
+// backgrounds stay deterministic per hash. The fade, face and features are
+// drawn on top as usual.
+func WithBackgroundFunc(fn func(dst *image.RGBA, seed uint64)) Option {
+	return func(o *options) error {
+		if fn == nil {
+			return fmt.Errorf("wavatar: background function is nil")
+		}
+		o.background = fn
+		return nil
+	}
+}
+
+// WithTransparentBackground leaves out the background color and the fade, so
+// everything outside the face is transparent, for placing avatars on a
+// background of your own. Unlike WithSticker the canvas keeps its size and
+// the face gets no outline.
+func WithTransparentBackground() Option {
+	return func(o *options) error {
+		o.transparent = true
+		return nil
+	}
+}
+
+// WithSaturation sets the saturation of the background and wave colors on the
+// 0-240 scale, replacing the default of 240 for both
+func WithSaturation(bg, wave int) Option {
+	return func(o *options) error {
+		if err := checkHSLRange("saturation", bg, wave); err != nil {
+			return err
+		}
+		o.bgSaturation, o.waveSaturation = bg, wave
+		return nil
+	}
+}
+
+// WithLightness sets the lightness of the background and wave colors on the
+// 0-240 scale, replacing the defaults of 50 and 170
+func WithLightness(bg, wave int) Option {
+	return func(o *options) error {
+		if err := checkHSLRange("lightness", bg, wave); err != nil {
+			return err
+		}
+		o.bgLightness, o.waveLightness = bg, wave
+		return nil
+	}
+}
+
+// checkHSLRange reports an error if bg or wave is outside the 0-240 scale
+func checkHSLRange(name string, bg, wave int) error {
+	if bg < 0 || bg > 240 || wave < 0 || wave > 240 {
+		return fmt.Errorf("wavatar: %s %d/%d out of range 0-240", name, bg, wave)
+	}
+	return nil
+}
+
+// runFilter applies filter to img, turning a panic into an error naming its index in the chain
+func runFilter(index int, filter func(*image.RGBA), img *image.RGBA) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("wavatar: post-process filter %d panicked: %v", index, r)
+		}
+	}()
+
+	filter(img)
+	return nil
+}
@@ -0,0 +1,319 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weavatar/wavatar/wavatartest"
+)
+
+func TestPostProcessOrder(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	blue := color.RGBA{B: 255, A: 255}
+	fillRed := func(img *image.RGBA) {
+		fillRect(img, img.Bounds(), red)
+	}
+	redToBlue := func(img *image.RGBA) {
+		if img.RGBAAt(0, 0) == red {
+			fillRect(img, img.Bounds(), blue)
+		}
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want color.RGBA
+	}{
+		{"red then blue", []Option{WithPostProcess(fillRed, redToBlue)}, blue},
+		{"blue then red", []Option{WithPostProcess(redToBlue, fillRed)}, red},
+		{"separate options", []Option{WithPostProcess(fillRed), WithPostProcess(redToBlue)}, blue},
+		// Built-in filters interleave with user functions in option order
+		{"invert after", []Option{WithPostProcess(fillRed), WithInvert()}, color.RGBA{G: 255, B: 255, A: 255}},
+		{"invert before", []Option{WithInvert(), WithPostProcess(fillRed)}, red},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Generate([]byte("test@example.com"), tt.opts...)
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			if got := img.(*image.RGBA).RGBAAt(10, 10); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPostProcessBeforeResize(t *testing.T) {
+	red := color.RGBA{R: 255, A: 255}
+	var bounds image.Rectangle
+	fillRed := func(img *image.RGBA) {
+		bounds = img.Bounds()
+		fillRect(img, img.Bounds(), red)
+	}
+
+	img, err := Generate([]byte("test@example.com"), WithPostProcess(fillRed), WithSize(160), WithCircleMask())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if want := image.Rect(0, 0, AvatarSize, AvatarSize); bounds != want {
+		t.Errorf("Expected the filter to see %v, got %v", want, bounds)
+	}
+
+	// The circle mask still clears the corners the filter painted
+	rgba := img.(*image.RGBA)
+	if got := rgba.Bounds(); got != image.Rect(0, 0, 160, 160) {
+		t.Fatalf("Expected a 160px avatar, got %v", got)
+	}
+	if got := rgba.RGBAAt(0, 0); got.A != 0 {
+		t.Errorf("Expected a transparent corner, got %v", got)
+	}
+	if got := rgba.RGBAAt(80, 80); got != red {
+		t.Errorf("Expected the filter color in the center, got %v", got)
+	}
+}
+
+func TestPostProcessPanic(t *testing.T) {
+	noop := func(*image.RGBA) {}
+	boom := func(*image.RGBA) { panic("boom") }
+
+	_, err := Generate([]byte("test@example.com"), WithBlur(1), WithPostProcess(noop, boom))
+	if err == nil {
+		t.Fatal("Expected the panic to be returned as an error")
+	}
+	if !strings.Contains(err.Error(), "filter 2") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Expected the error to name filter 2 and the panic value, got %v", err)
+	}
+}
+
+func TestPostProcessNil(t *testing.T) {
+	if _, err := Generate(nil, WithPostProcess(nil)); err == nil {
+		t.Error("Expected an error for a nil post-process function")
+	}
+}
+
+func TestBackgroundFunc(t *testing.T) {
+	black := color.RGBA{A: 255}
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	var seeds []uint64
+	checkerboard := func(dst *image.RGBA, seed uint64) {
+		seeds = append(seeds, seed)
+		b := dst.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if (x/4+y/4)%2 == 0 {
+					dst.SetRGBA(x, y, black)
+				} else {
+					dst.SetRGBA(x, y, white)
+				}
+			}
+		}
+	}
+
+	hash := []byte("test@example.com")
+	got, err := Generate(hash, WithBackgroundFunc(checkerboard))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	img := got.(*image.RGBA)
+	want := New(hash).(*image.RGBA)
+
+	// Neighbouring checker cells in the corner must still differ beneath the fade
+	if a, b := img.RGBAAt(1, 1), img.RGBAAt(5, 1); a == b {
+		t.Errorf("Expected the checkerboard to show through outside the face, got %v twice", a)
+	}
+	if got, want := img.RGBAAt(AvatarSize/2, AvatarSize/2), want.RGBAAt(AvatarSize/2, AvatarSize/2); got != want {
+		t.Errorf("Expected the face center to be %v, got %v", want, got)
+	}
+
+	if _, err := Generate(hash, WithBackgroundFunc(checkerboard)); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, err := Generate([]byte("other@example.com"), WithBackgroundFunc(checkerboard)); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if seeds[0] != seeds[1] {
+		t.Errorf("Expected the same seed for the same hash, got %d and %d", seeds[0], seeds[1])
+	}
+	if seeds[0] == seeds[2] {
+		t.Errorf("Expected different seeds for different hashes, got %d twice", seeds[0])
+	}
+}
+
+// chroma returns the spread between the largest and smallest channel of c
+func chroma(c color.RGBA) int {
+	return int(max(c.R, c.G, c.B)) - int(min(c.R, c.G, c.B))
+}
+
+func TestSaturationLowersChroma(t *testing.T) {
+	full := defaultOptions()
+	half, err := newOptions([]Option{WithSaturation(120, 120)})
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+
+	for h := 1; h <= 240; h++ {
+		s := Spec{Background: h, WaveColor: h}
+		if got, was := chroma(half.backgroundColor(s)), chroma(full.backgroundColor(s)); got >= was {
+			t.Errorf("Expected background hue %d chroma below %d, got %d", h, was, got)
+		}
+		if got, was := chroma(half.waveColor(s)), chroma(full.waveColor(s)); got >= was {
+			t.Errorf("Expected wave hue %d chroma below %d, got %d", h, was, got)
+		}
+	}
+}
+
+func TestDefaultSaturationAndLightness(t *testing.T) {
+	hash := []byte("test@example.com")
+	img, err := Generate(hash, WithSaturation(240, 240), WithLightness(50, 170))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected the default saturation and lightness to reproduce New")
+	}
+
+	light, err := newOptions([]Option{WithLightness(100, 200)})
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+	s := Spec{Background: 1, WaveColor: 1}
+	if got, was := light.backgroundColor(s).R, defaultOptions().backgroundColor(s).R; got <= was {
+		t.Errorf("Expected a lighter background than %d, got %d", was, got)
+	}
+}
+
+func TestSaturationAndLightnessRange(t *testing.T) {
+	for _, opt := range []Option{
+		WithSaturation(-1, 120),
+		WithSaturation(120, 241),
+		WithLightness(241, 0),
+		WithLightness(0, -5),
+	} {
+		if _, err := Generate(nil, opt); err == nil {
+			t.Error("Expected an error for an out of range value")
+		}
+	}
+}
+
+// countingSource is a deterministic rand.Source that counts its draws
+type countingSource struct {
+	draws int
+}
+
+func (s *countingSource) Uint64() uint64 {
+	s.draws++
+	return uint64(s.draws) * 0x9e3779b97f4a7c15
+}
+
+func TestNewFromSourceDraws(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"default", nil},
+		{"seasonal", []Option{WithSeasonal(SeasonalConfetti)}},
+		{"v2", []Option{WithVersion(V2)}},
+		{"domain", []Option{WithDomainHue(nil)}},
+		{"filters", []Option{WithBlur(1), WithHueShift(30)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := &countingSource{}
+			if _, err := NewFromSource(src, tt.opts...); err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			// One draw per field of the Spec, in Describe order
+			if src.draws != 8 {
+				t.Errorf("Expected 8 draws, got %d", src.draws)
+			}
+		})
+	}
+}
+
+func TestNewFromSourceDeterministic(t *testing.T) {
+	a, err := NewFromSource(&countingSource{})
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	b, err := NewFromSource(&countingSource{})
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(a.(*image.RGBA).Pix, b.(*image.RGBA).Pix) {
+		t.Error("Expected identical avatars from identical sources")
+	}
+
+	// The same PCG state Describe uses must give the same avatar
+	hash := []byte("test@example.com")
+	seed := hashSeed(hash)
+	c, err := NewFromSource(rand.NewPCG(seed, (seed>>1)|1))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(c.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected the hash's PCG source to reproduce New")
+	}
+
+	if _, err := NewFromSource(nil); err == nil {
+		t.Error("Expected an error for a nil source")
+	}
+}
+
+func TestTransparentBackground(t *testing.T) {
+	for i := range 20 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		img, err := Generate(hash, WithTransparentBackground())
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		rgba := img.(*image.RGBA)
+		if b := rgba.Bounds(); b.Dx() != AvatarSize || b.Dy() != AvatarSize {
+			t.Errorf("Expected %dx%d, got %v", AvatarSize, AvatarSize, b)
+		}
+		for _, p := range []image.Point{{0, 0}, {AvatarSize - 1, 0}, {0, AvatarSize - 1}, {AvatarSize - 1, AvatarSize - 1}} {
+			if a := rgba.RGBAAt(p.X, p.Y).A; a != 0 {
+				t.Errorf("%s: expected the corner %v transparent, got alpha %d", hash, p, a)
+			}
+		}
+
+		// The face is filled with the wave color as usual
+		s, _ := Resolve(hash)
+		if got := rgba.RGBAAt(AvatarSize/2, AvatarSize/2); got.A != 255 {
+			t.Errorf("%s: expected the face center opaque, got %v", hash, got)
+		}
+		mask, err := FaceMask(hash)
+		if err != nil {
+			t.Fatalf("Failed to get face mask: %v", err)
+		}
+		opaque := New(hash).(*image.RGBA)
+		for j, a := range mask.Pix {
+			x, y := j%AvatarSize, j/AvatarSize
+			if a == 255 && opaque.RGBAAt(x, y) == s.WaveRGBA && rgba.RGBAAt(x, y) != s.WaveRGBA {
+				t.Fatalf("%s: expected the wave color at (%d,%d), got %v", hash, x, y, rgba.RGBAAt(x, y))
+			}
+		}
+	}
+}
+
+func TestTransparentBackgroundGolden(t *testing.T) {
+	img, err := Generate([]byte("test@example.com"), WithTransparentBackground())
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	// PNG stores unpremultiplied colors, so the translucent edge of the mask rounds by one
+	path := filepath.Join("testdata", "golden", "transparent.png")
+	wavatartest.SaveGolden(t, path, img)
+	if err := wavatartest.CompareImages(img, wavatartest.LoadGolden(t, path), 1, 0); err != nil {
+		t.Errorf("Golden transparent mismatch: %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// WithFeatureOutline strokes the brow, eyes, pupils and mouth with an outline
+// of the given width in a color complementary to the background
+func WithFeatureOutline(width int) Option {
+	return func(o *options) error {
+		if width < 0 {
+			return fmt.Errorf("wavatar: outline width must not be negative, got %d", width)
+		}
+		o.outline = width
+		return nil
+	}
+}
+
+// complementColor returns the color opposite the background hue on the wheel
+func (o *options) complementColor(bgColor int) color.RGBA {
+	return o.hslColor(complementHue(bgColor), 240, 50)
+}
+
+// drawOutline paints col on dst around the visible pixels of layer, then draws layer over dst
+func drawOutline(dst, layer *image.RGBA, width int, col color.RGBA) {
+	ring := dilate(alphaMask(layer), layer.Rect.Dx(), layer.Rect.Dy(), width)
+	for i, set := range ring {
+		if set {
+			x, y := i%layer.Rect.Dx(), i/layer.Rect.Dx()
+			dst.SetRGBA(layer.Rect.Min.X+x, layer.Rect.Min.Y+y, col)
+		}
+	}
+
+	draw.Draw(dst, dst.Bounds(), layer, layer.Bounds().Min, draw.Over)
+}
+
+// alphaMask reports for every pixel of img, row by row, whether it is visible
+func alphaMask(img *image.RGBA) []bool {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	mask := make([]bool, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			mask[y*w+x] = img.Pix[y*img.Stride+4*x+3] > 0
+		}
+	}
+	return mask
+}
+
+// dilate grows the set pixels of a w x h mask by a disk of the given radius
+func dilate(mask []bool, w, h, radius int) []bool {
+	out := make([]bool, len(mask))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !mask[y*w+x] {
+				continue
+			}
+			for dy := -radius; dy <= radius; dy++ {
+				for dx := -radius; dx <= radius; dx++ {
+					nx, ny := x+dx, y+dy
+					if dx*dx+dy*dy > radius*radius || nx < 0 || nx >= w || ny < 0 || ny >= h {
+						continue
+					}
+					out[ny*w+nx] = true
+				}
+			}
+		}
+	}
+	return out
+}
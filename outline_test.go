@@ -0,0 +1,73 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestFeatureOutlineSurroundsFeatures(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+
+	img, err := Generate(hash, WithFeatureOutline(2))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	outlined := img.(*image.RGBA)
+
+	bgColor := New(hash).(*image.RGBA).RGBAAt(0, 0)
+	outlineColor := defaultOptions().complementColor(s.Background)
+	if outlineColor == bgColor {
+		t.Fatalf("Outline color %v should differ from the background", outlineColor)
+	}
+
+	features := image.NewRGBA(outlined.Bounds())
+	mustApplyImage(t, features, "brow", s.Brow)
+	mustApplyImage(t, features, "eyes", s.Eyes)
+	mustApplyImage(t, features, "pupils", s.Pupil)
+	mustApplyImage(t, features, "mouth", s.Mouth)
+	near := dilate(alphaMask(features), AvatarSize, AvatarSize, 2)
+
+	found := 0
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			if outlined.RGBAAt(x, y) != outlineColor || features.RGBAAt(x, y).A > 0 {
+				continue
+			}
+			if !near[y*AvatarSize+x] {
+				t.Fatalf("Outline pixel at (%d,%d) is not adjacent to a feature", x, y)
+			}
+			found++
+		}
+	}
+	if found == 0 {
+		t.Error("Expected outline colored pixels around the features")
+	}
+}
+
+func TestFeatureOutlineZeroIsNoop(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	img, err := Generate(hash, WithFeatureOutline(0))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Outline width 0 should not change the avatar")
+	}
+}
+
+func TestDilate(t *testing.T) {
+	mask := make([]bool, 25)
+	mask[12] = true
+
+	out := dilate(mask, 5, 5, 1)
+	for i, set := range out {
+		x, y := i%5, i/5
+		want := (x-2)*(x-2)+(y-2)*(y-2) <= 1
+		if set != want {
+			t.Errorf("Pixel (%d,%d): expected %v, got %v", x, y, want, set)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package wavatar
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+)
+
+// Config describes a part pack by its files and its number of parts per
+// layer, for packs whose counts differ from the embedded parts. Hashes then
+// select among the parts the counts name, like CountExtend.
+type Config struct {
+	// FS holds the parts, named like mask1.png, nil for the embedded parts
+	FS fs.FS
+	// Prefix is the directory of FS the parts are in, empty for its root
+	Prefix string
+
+	// Counts of the parts per layer; a face is a mask and a shine of the
+	// same number. Zero counts are those of the embedded parts.
+	FaceCount  int
+	BgCount    int
+	BrowCount  int
+	EyeCount   int
+	PupilCount int
+	MouthCount int
+}
+
+// counts returns the number of parts hashes select among for every Layer
+func (c Config) counts() layerCounts {
+	counts := layerCounts{
+		LayerFace:   c.FaceCount,
+		LayerFade:   c.BgCount,
+		LayerBrow:   c.BrowCount,
+		LayerEyes:   c.EyeCount,
+		LayerPupils: c.PupilCount,
+		LayerMouth:  c.MouthCount,
+	}
+	for l, n := range counts {
+		if n == 0 {
+			counts[l] = defaultCounts[l]
+		}
+	}
+	return counts
+}
+
+// partFS returns the directory of c that holds the parts, nil for the embedded parts
+func (c Config) partFS() (fs.FS, error) {
+	if c.FS == nil || c.Prefix == "" {
+		return c.FS, nil
+	}
+	sub, err := fs.Sub(c.FS, c.Prefix)
+	if err != nil {
+		return nil, fmt.Errorf("wavatar: invalid part prefix %q: %w", c.Prefix, err)
+	}
+	return sub, nil
+}
+
+// Validate reports every count that is negative and every part the counts
+// name that is missing, so a broken pack is found when it is configured
+// instead of by the first avatar that selects the missing part.
+func (c Config) Validate() error {
+	if c.FS == nil && c.Prefix != "" {
+		return fmt.Errorf("wavatar: part prefix %q without a part FS", c.Prefix)
+	}
+	fsys, err := c.partFS()
+	if err != nil {
+		return err
+	}
+	if fsys == nil && defaultParts == nil {
+		return errNoParts
+	}
+
+	var errs []error
+	for l, n := range c.counts() {
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("wavatar: negative %s count %d", Layer(l), n))
+			continue
+		}
+		if fsys == nil {
+			if n > defaultCounts[l] {
+				errs = append(errs, fmt.Errorf("wavatar: embedded parts have %d %s parts, not %d", defaultCounts[l], Layer(l), n))
+			}
+			continue
+		}
+		errs = append(errs, missingParts(fsys, Layer(l), n)...)
+	}
+	return errors.Join(errs...)
+}
+
+// missingParts reports every one of the first n parts of layer l that fsys lacks
+func missingParts(fsys fs.FS, l Layer, n int) []error {
+	var errs []error
+	parts := []string{l.String()}
+	if l == LayerFace {
+		parts = []string{"mask", "shine"}
+	}
+	for _, part := range parts {
+		for num := 1; num <= n; num++ {
+			name := fmt.Sprintf("%s%d.png", part, num)
+			if _, err := fs.Stat(fsys, name); err != nil {
+				errs = append(errs, fmt.Errorf("wavatar: part pack lacks %s: %w", name, err))
+			}
+		}
+	}
+	return errs
+}
+
+// NewGeneratorWithConfig creates a Generator for the part pack of cfg after
+// validating it, see NewGenerator. The counts of cfg take the place of any
+// WithCountPolicy.
+func NewGeneratorWithConfig(cfg Config, opts ...Option) (*Generator, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	fsys, err := cfg.partFS()
+	if err != nil {
+		return nil, err
+	}
+	counts := cfg.counts()
+	return newGenerator(fsys, &counts, opts)
+}
+
+// NewWithConfig creates a new Wavatar from a hash with the parts and counts
+// of cfg. Like New it panics if rendering fails, including for an invalid
+// cfg; check it with Config.Validate first. Every call decodes the parts it
+// needs anew, so use NewGeneratorWithConfig to render more than a few avatars.
+func NewWithConfig(hash []byte, cfg Config) image.Image {
+	g, err := NewGeneratorWithConfig(cfg)
+	if err != nil {
+		panic(err)
+	}
+	img, err := g.Generate(hash)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
@@ -0,0 +1,103 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"testing"
+	"testing/fstest"
+)
+
+// brandedPack returns mouthPack with 20 mouths under the directory branded/
+func brandedPack(t *testing.T) fstest.MapFS {
+	t.Helper()
+	pack := fstest.MapFS{}
+	for name, file := range mouthPack(t, 20) {
+		pack["branded/"+name] = file
+	}
+	return pack
+}
+
+func TestConfigValidate(t *testing.T) {
+	pack := brandedPack(t)
+	cfg := Config{FS: pack, Prefix: "branded", FaceCount: 6, MouthCount: 20}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+	// Embedded parts with fewer faces
+	if err := (Config{FaceCount: 6}).Validate(); err != nil {
+		t.Errorf("Expected a valid config, got %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"missing mouth", Config{FS: pack, Prefix: "branded", MouthCount: 21}},
+		{"missing face", Config{FS: pack, Prefix: "branded", FaceCount: FaceCount + 1}},
+		{"no prefix", Config{FS: pack, MouthCount: 20}},
+		{"bad prefix", Config{FS: pack, Prefix: "../branded"}},
+		{"prefix without fs", Config{Prefix: "branded"}},
+		{"negative", Config{FS: pack, Prefix: "branded", EyeCount: -1}},
+		{"beyond embedded", Config{MouthCount: MouthCount + 1}},
+	} {
+		if err := tt.cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+		if _, err := NewGeneratorWithConfig(tt.cfg); err == nil {
+			t.Errorf("%s: expected NewGeneratorWithConfig to fail", tt.name)
+		}
+	}
+}
+
+func TestConfigCounts(t *testing.T) {
+	g, err := NewGeneratorWithConfig(Config{FS: brandedPack(t), Prefix: "branded", FaceCount: 6, MouthCount: 20})
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	o, err := g.options(nil)
+	if err != nil {
+		t.Fatalf("Failed to apply options: %v", err)
+	}
+
+	// Hashes select among the configured faces and mouths only
+	faces, mouths := 0, 0
+	for i := range 500 {
+		s, err := describeHash([]byte(fmt.Sprintf("user%d@example.com", i)), o)
+		if err != nil {
+			t.Fatalf("Failed to describe: %v", err)
+		}
+		faces, mouths = max(faces, s.Face), max(mouths, s.Mouth)
+	}
+	if faces != 6 || mouths != 20 {
+		t.Errorf("Expected up to 6 faces and 20 mouths, got %d and %d", faces, mouths)
+	}
+}
+
+func TestNewWithConfig(t *testing.T) {
+	cfg := Config{FS: brandedPack(t), Prefix: "branded", FaceCount: 6, MouthCount: 20}
+	g, err := NewGeneratorWithConfig(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	hash := []byte("test@example.com")
+	want, err := g.Generate(hash)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if got := NewWithConfig(hash, cfg); !bytes.Equal(got.(*image.RGBA).Pix, want.(*image.RGBA).Pix) {
+		t.Error("Expected NewWithConfig to render like the generator of its config")
+	}
+
+	// The embedded parts with their own counts render like New
+	if got := NewWithConfig(hash, Config{}); !bytes.Equal(got.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected the zero Config to render like New")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected NewWithConfig to panic for an invalid config")
+		}
+	}()
+	NewWithConfig(hash, Config{FS: cfg.FS, MouthCount: 20})
+}
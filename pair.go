@@ -0,0 +1,22 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+)
+
+// NewPair returns the avatar for hash and a variant that only differs in changeLayer,
+// which uses the next part index (wrapping around), for side by side comparisons.
+// It panics if changeLayer is not a known layer.
+func NewPair(hash []byte, changeLayer Layer) (left, right image.Image) {
+	s := Describe(hash)
+	variant := s
+
+	index, count, ok := variant.layer(changeLayer)
+	if !ok {
+		panic(fmt.Sprintf("wavatar: unknown layer %v", changeLayer))
+	}
+	*index = *index%count + 1
+
+	return NewFromSpec(s), NewFromSpec(variant)
+}
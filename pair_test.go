@@ -0,0 +1,73 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// partBounds returns the bounding box of the visible pixels of a part
+func partBounds(t *testing.T, part string, num int) image.Rectangle {
+	t.Helper()
+	return visibleBounds(toRGBA(mustLoadPart(t, part, num)))
+}
+
+func TestNewPairOnlyChangesLayer(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+
+	tests := []struct {
+		layer Layer
+		part  string
+		index int
+		count int
+	}{
+		{LayerBrow, "brow", s.Brow, BrowCount},
+		{LayerEyes, "eyes", s.Eyes, EyeCount},
+		{LayerMouth, "mouth", s.Mouth, MouthCount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.layer.String(), func(t *testing.T) {
+			left, right := NewPair(hash, tt.layer)
+			if !bytes.Equal(left.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+				t.Error("Left avatar should match New")
+			}
+
+			_, stats, err := DiffImage(left, right)
+			if err != nil {
+				t.Fatalf("Failed to diff: %v", err)
+			}
+			if stats.Changed == 0 {
+				t.Fatal("Expected the pair to differ")
+			}
+
+			next := tt.index%tt.count + 1
+			region := partBounds(t, tt.part, tt.index).Union(partBounds(t, tt.part, next))
+			if !stats.Bounds.In(region) {
+				t.Errorf("Expected changes within %v, got %v", region, stats.Bounds)
+			}
+		})
+	}
+}
+
+func TestNewPairWrapsIndex(t *testing.T) {
+	s := Spec{Face: FaceCount, Background: 1, Fade: 1, WaveColor: 1, Brow: 1, Eyes: 1, Pupil: 1, Mouth: 1}
+	index, count, ok := s.layer(LayerFace)
+	if !ok {
+		t.Fatal("Expected LayerFace to be known")
+	}
+	*index = *index%count + 1
+	if s.Face != 1 {
+		t.Errorf("Expected the face index to wrap to 1, got %d", s.Face)
+	}
+}
+
+func TestNewPairUnknownLayer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected a panic for an unknown layer")
+		}
+	}()
+	NewPair([]byte("test@example.com"), Layer(42))
+}
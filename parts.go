@@ -0,0 +1,295 @@
+package wavatar
+
+import (
+	"archive/zip"
+	"container/list"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"io/fs"
+	"sync"
+	"time"
+)
+
+// errNoParts is returned when rendering without a part source
+var errNoParts = errors.New("wavatar: no part source, this build excludes the embedded parts (wavatar_noembed) so use NewGenerator with an fs.FS")
+
+// PartError reports a part image that could not be opened or decoded, a
+// broken part source rather than a problem with the input or the options
+type PartError struct {
+	// Part is the kind of part, such as eyes, and Index its number, as in eyes13.png
+	Part  string
+	Index int
+	Err   error
+}
+
+func (e *PartError) Error() string {
+	return fmt.Sprintf("wavatar: load part %s%d: %v", e.Part, e.Index, e.Err)
+}
+
+func (e *PartError) Unwrap() error {
+	return e.Err
+}
+
+// defaultParts are the embedded parts, nil in builds without them
+var defaultParts = embeddedParts()
+
+// partCounts lists every part prefix with the number of parts of that kind
+var partCounts = []struct {
+	part  string
+	count int
+}{
+	{"mask", FaceCount},
+	{"shine", FaceCount},
+	{"fade", BgCount},
+	{"brow", BrowCount},
+	{"eyes", EyeCount},
+	{"pupils", PupilCount},
+	{"mouth", MouthCount},
+}
+
+// partSet loads the part images an avatar is composited from, decoding each
+// part on first use and keeping it for later renders. With a budget the least
+// recently used parts are dropped once the bytes retained by the caches
+// sharing it exceed it.
+// Cached images are shared and must not be modified.
+type partSet struct {
+	decode func(name string) (image.Image, error)
+	// budget caps the bytes retained with the other caches sharing it, nil for no limit
+	budget *memoryBudget
+	// log records part loads, nil for none
+	log *renderLogger
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	lru   *list.List
+	usage int64
+}
+
+// partEntry is a part that is decoded at most once, even when first requested concurrently
+type partEntry struct {
+	name string
+	once sync.Once
+	img  image.Image
+	// premul is img prepared for fast compositing
+	premul *premulPart
+	err    error
+	// size is the approximate number of bytes retained once decoded, guarded by the partSet mutex
+	size int64
+
+	// seed is where the face fill of a mask starts, see faceSeed
+	seedOnce sync.Once
+	seed     image.Point
+}
+
+// faceSeed returns where the face fill of a mask entry starts, see maskSeed
+func (e *partEntry) faceSeed() image.Point {
+	e.seedOnce.Do(func() {
+		b := e.img.Bounds()
+		e.seed = maskSeed(e.img, image.Pt((b.Min.X+b.Max.X)/2, (b.Min.Y+b.Max.Y)/2))
+	})
+	return e.seed
+}
+
+// newPartCache returns an empty partSet decoding parts with decode
+func newPartCache(decode func(name string) (image.Image, error)) *partSet {
+	return &partSet{
+		decode: decode,
+		cache:  make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+// newPartSet returns a partSet reading PNGs named like mask1.png from the root of fsys.
+// Decode errors are wrapped in a PartError by entry.
+func newPartSet(fsys fs.FS) *partSet {
+	return newPartCache(func(name string) (image.Image, error) {
+		file, err := fsys.Open(name + ".png")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		img, err := png.Decode(file)
+		if err != nil {
+			return nil, fmt.Errorf("decode %s.png: %w", name, err)
+		}
+		if b := img.Bounds(); b.Dx() != AvatarSize || b.Dy() != AvatarSize {
+			return nil, fmt.Errorf("%s.png is %dx%d, expected %dx%d", name, b.Dx(), b.Dy(), AvatarSize, AvatarSize)
+		}
+		return img, nil
+	})
+}
+
+// newArchivePartSet returns a partSet reading the zip archive written by gen_parts.go,
+// which holds the raw NRGBA pixels of each part in entries named like mask1.nrgba
+func newArchivePartSet(zr *zip.Reader) *partSet {
+	return newPartCache(func(name string) (image.Image, error) {
+		file, err := zr.Open(name + ".nrgba")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		img := image.NewNRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+		if _, err := io.ReadFull(file, img.Pix); err != nil {
+			return nil, fmt.Errorf("read %s.nrgba: %w", name, err)
+		}
+		return img, nil
+	})
+}
+
+// entry returns the decoded part, decoding it on first use
+func (p *partSet) entry(part string, num int) (*partEntry, error) {
+	if p == nil {
+		return nil, errNoParts
+	}
+
+	name := fmt.Sprintf("%s%d", part, num)
+	p.mu.Lock()
+	elem, ok := p.cache[name]
+	if ok {
+		p.lru.MoveToFront(elem)
+	} else {
+		elem = p.lru.PushFront(&partEntry{name: name})
+		p.cache[name] = elem
+	}
+	e := elem.Value.(*partEntry)
+	p.mu.Unlock()
+
+	e.once.Do(func() {
+		var size int64
+		start := time.Now()
+		e.img, e.err = p.decode(name)
+		if e.err != nil {
+			e.err = &PartError{Part: part, Index: num, Err: e.err}
+		} else {
+			e.premul = newPremulPart(e.img)
+			size = imageBytes(e.img) + int64(2*len(e.premul.pix))
+		}
+		p.log.part(part, num, time.Since(start), e.err)
+		p.retain(elem, size)
+	})
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// retain records the size of the freshly decoded entry in elem and evicts the least
+// recently used entries until the cache fits its budget again. Renders that
+// already hold an evicted entry keep using it safely.
+func (p *partSet) retain(elem *list.Element, size int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	e := elem.Value.(*partEntry)
+	e.size = size
+	if p.cache[e.name] != elem {
+		return
+	}
+	p.usage += size
+	p.budget.add(size)
+
+	for back := p.lru.Back(); p.budget.exceeded() && back != nil; {
+		prev := back.Prev()
+		if victim := back.Value.(*partEntry); victim.size > 0 || back == elem {
+			p.lru.Remove(back)
+			delete(p.cache, victim.name)
+			p.usage -= victim.size
+			p.budget.add(-victim.size)
+		}
+		back = prev
+	}
+}
+
+// memoryUsage returns the approximate number of bytes held by decoded parts
+func (p *partSet) memoryUsage() int64 {
+	if p == nil {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.usage
+}
+
+// imageBytes estimates the pixel memory of img
+func imageBytes(img image.Image) int64 {
+	switch img := img.(type) {
+	case *image.NRGBA:
+		return int64(len(img.Pix))
+	case *image.RGBA:
+		return int64(len(img.Pix))
+	case *image.Paletted:
+		return int64(len(img.Pix) + 4*len(img.Palette))
+	case *image.Gray:
+		return int64(len(img.Pix))
+	}
+	return int64(4 * img.Bounds().Dx() * img.Bounds().Dy())
+}
+
+// load returns the decoded image of a part
+func (p *partSet) load(part string, num int) (image.Image, error) {
+	e, err := p.entry(part, num)
+	if err != nil {
+		return nil, err
+	}
+	return e.img, nil
+}
+
+// apply draws a part over base
+func (p *partSet) apply(base *image.RGBA, part string, num int) error {
+	e, err := p.entry(part, num)
+	if err != nil {
+		return err
+	}
+	e.premul.over(base)
+	return nil
+}
+
+// applyScaled draws a part over base with its alpha multiplied by f, where 1 is the part as drawn
+func (p *partSet) applyScaled(base *image.RGBA, part string, num int, f float64) error {
+	if f == 1 {
+		return p.apply(base, part, num)
+	}
+	if f == 0 {
+		return nil
+	}
+
+	e, err := p.entry(part, num)
+	if err != nil {
+		return err
+	}
+	e.premul.scaleAlpha(f).over(base)
+	return nil
+}
+
+// preloadAll decodes every part of the c parts per layer up front
+func (p *partSet) preloadAll(c layerCounts) error {
+	for l, n := range c {
+		parts := []string{Layer(l).String()}
+		if Layer(l) == LayerFace {
+			parts = []string{"mask", "shine"}
+		}
+		for _, part := range parts {
+			for num := 1; num <= n; num++ {
+				if _, err := p.load(part, num); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// PreloadAll decodes every embedded part now instead of on first use
+func PreloadAll() error {
+	return defaultParts.preloadAll(defaultCounts)
+}
+
+// PreloadAll decodes every part of g now instead of on first use
+func (g *Generator) PreloadAll() error {
+	return g.parts.preloadAll(g.counts)
+}
@@ -0,0 +1,235 @@
+package wavatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPreloadAll(t *testing.T) {
+	if err := PreloadAll(); err != nil {
+		t.Fatalf("Failed to preload: %v", err)
+	}
+	if len(defaultParts.cache) != 77 {
+		t.Errorf("Expected 77 cached parts, got %d", len(defaultParts.cache))
+	}
+
+	g, err := NewGenerator(os.DirFS("parts"))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if err := g.PreloadAll(); err != nil {
+		t.Fatalf("Failed to preload: %v", err)
+	}
+}
+
+// loosePack returns the loose parts in memory
+func loosePack(t *testing.T) fstest.MapFS {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("parts", "*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pack := fstest.MapFS{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pack[filepath.Base(file)] = &fstest.MapFile{Data: data}
+	}
+	return pack
+}
+
+// brokenPack returns the loose parts with the mouth of s corrupted
+func brokenPack(t *testing.T, s Spec) fstest.MapFS {
+	t.Helper()
+
+	pack := loosePack(t)
+	pack[fmt.Sprintf("mouth%d.png", s.Mouth)].Data = []byte("not a png")
+	return pack
+}
+
+func TestPartError(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+	pack := brokenPack(t, s)
+	g, err := NewGenerator(pack)
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	// A part removed after NewGenerator checked the pack fails the render
+	delete(pack, fmt.Sprintf("eyes%d.png", s.Eyes))
+
+	// Eyes are drawn before the mouth
+	_, err = g.Generate(hash)
+	var perr *PartError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Expected a *PartError, got %v", err)
+	}
+	if perr.Part != "eyes" || perr.Index != s.Eyes || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Expected eyes%d to be missing, got %v", s.Eyes, err)
+	}
+
+	s.Eyes = s.Eyes%EyeCount + 1
+	_, err = g.GenerateFromSpec(s)
+	if !errors.As(err, &perr) || perr.Part != "mouth" || perr.Index != s.Mouth {
+		t.Errorf("Expected mouth%d to fail to decode, got %v", s.Mouth, err)
+	}
+
+	// Bad options are not part errors
+	if _, err := g.Generate(hash, WithBlur(-1)); errors.As(err, &perr) {
+		t.Errorf("Expected an option error, got %v", err)
+	}
+}
+
+func TestPartErrorBrokenArchive(t *testing.T) {
+	broken := errors.New("zip: not a valid zip file")
+	parts := newPartCache(func(string) (image.Image, error) {
+		return nil, broken
+	})
+	o := defaultOptions()
+	o.parts = parts
+	if _, err := generate(Describe([]byte("test@example.com")), o); !errors.Is(err, broken) {
+		t.Errorf("Expected the archive error, got %v", err)
+	}
+}
+
+// failingFS fails to open one file with err and serves the others from FS
+type failingFS struct {
+	fs.FS
+	name string
+	err  error
+}
+
+func (f failingFS) Open(name string) (fs.File, error) {
+	if name == f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: f.err}
+	}
+	return f.FS.Open(name)
+}
+
+// Stat finds every file, so the failing one passes the check of NewGenerator
+func (f failingFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.FS, name)
+}
+
+func TestPartErrorFailingSource(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+	failure := errors.New("disk on fire")
+	g, err := NewGenerator(failingFS{FS: os.DirFS("parts"), name: fmt.Sprintf("pupils%d.png", s.Pupil), err: failure})
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	_, err = g.Generate(hash)
+	if !errors.Is(err, failure) {
+		t.Fatalf("Expected the source error, got %v", err)
+	}
+	if want := fmt.Sprintf("wavatar: load part pupils%d: open pupils%d.png: disk on fire", s.Pupil, s.Pupil); err.Error() != want {
+		t.Errorf("Expected %q, got %q", want, err)
+	}
+}
+
+func TestPreloadValidatesPack(t *testing.T) {
+	if _, err := NewGenerator(loosePack(t), WithPreload()); err != nil {
+		t.Fatalf("Expected the loose parts to preload, got %v", err)
+	}
+
+	var small bytes.Buffer
+	if err := png.Encode(&small, image.NewRGBA(image.Rect(0, 0, 40, 40))); err != nil {
+		t.Fatal(err)
+	}
+	// Missing parts fail NewGenerator with or without WithPreload
+	for _, name := range []string{"mouth3.png", "shine7.png"} {
+		pack := loosePack(t)
+		delete(pack, name)
+		_, err := NewGenerator(pack)
+		if want := "lacks " + name + ": open " + name + ": file does not exist"; err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to contain %q, got %v", want, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		corrupt func(fstest.MapFS)
+		want    string
+	}{
+		{"undecodable", func(p fstest.MapFS) { p["fade2.png"].Data = []byte("not a png") }, "decode fade2.png"},
+		{"wrong size", func(p fstest.MapFS) { p["brow4.png"].Data = small.Bytes() }, "brow4.png is 40x40, expected 80x80"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pack := loosePack(t)
+			tt.corrupt(pack)
+
+			// Without WithPreload the broken part is only found when rendered
+			if _, err := NewGenerator(pack); err != nil {
+				t.Fatalf("Failed to create generator: %v", err)
+			}
+			_, err := NewGenerator(pack, WithPreload())
+			var perr *PartError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Expected a *PartError, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected the error to contain %q, got %q", tt.want, err)
+			}
+		})
+	}
+
+	// A CountPolicy checks the parts the pack has rather than the embedded counts
+	pack := loosePack(t)
+	delete(pack, fmt.Sprintf("mouth%d.png", MouthCount))
+	if _, err := NewGenerator(pack, WithCountPolicy(CountExtend), WithPreload()); err != nil {
+		t.Errorf("Expected a smaller pack to preload under CountExtend, got %v", err)
+	}
+}
+
+func TestPartsDecodedOnce(t *testing.T) {
+	var mu sync.Mutex
+	decodes := make(map[string]int)
+	embedded := embeddedParts()
+	o := defaultOptions()
+	o.parts = newPartCache(func(name string) (image.Image, error) {
+		mu.Lock()
+		decodes[name]++
+		mu.Unlock()
+		return embedded.decode(name)
+	})
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				if _, err := generateHash([]byte(fmt.Sprintf("user%d-%d@example.com", w, i)), o); err != nil {
+					t.Errorf("Failed to generate avatar: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for name, n := range decodes {
+		if n != 1 {
+			t.Errorf("Expected %s decoded once, got %d times", name, n)
+		}
+	}
+	if len(decodes) > 77 {
+		t.Errorf("Expected at most 77 parts decoded, got %d", len(decodes))
+	}
+}
@@ -0,0 +1,260 @@
+package wavatar
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// SheetEntry is a single avatar on a PDF contact sheet
+type SheetEntry struct {
+	Hash  []byte
+	Label string
+}
+
+// PDFConfig controls the layout of a PDF contact sheet.
+// Zero values fall back to an A4 page with a 4x5 grid.
+type PDFConfig struct {
+	// PageWidth and PageHeight are in points (1/72 inch)
+	PageWidth  float64
+	PageHeight float64
+	// Margin is the blank border around the grid in points
+	Margin float64
+	// Columns and Rows set the grid size of every page
+	Columns int
+	Rows    int
+	// FontSize is the label size in points
+	FontSize float64
+}
+
+// withDefaults fills zero fields of cfg with the A4 defaults
+func (cfg PDFConfig) withDefaults() PDFConfig {
+	if cfg.PageWidth <= 0 {
+		cfg.PageWidth = 595
+	}
+	if cfg.PageHeight <= 0 {
+		cfg.PageHeight = 842
+	}
+	if cfg.Margin <= 0 {
+		cfg.Margin = 36
+	}
+	if cfg.Columns <= 0 {
+		cfg.Columns = 4
+	}
+	if cfg.Rows <= 0 {
+		cfg.Rows = 5
+	}
+	if cfg.FontSize <= 0 {
+		cfg.FontSize = 10
+	}
+	return cfg
+}
+
+// WritePDFSheet writes a PDF laying out the avatars of entries in a grid with
+// their labels underneath, using as many pages as needed. Avatars are
+// rendered with Generate at the size of the default Generator and scaled to
+// the grid, and the first that fails to render fails the sheet.
+//
+// The document only relies on the standard Helvetica font and Flate encoded
+// image XObjects, so no external dependencies are needed. Labels can use the
+// characters of WinAnsiEncoding, Latin-1 among them; others print as '?'. To smoke-check the
+// output with an external reader run `qpdf --check sheet.pdf` or open it in
+// any PDF viewer.
+func WritePDFSheet(w io.Writer, entries []SheetEntry, cfg PDFConfig) error {
+	cfg = cfg.withDefaults()
+
+	cellW := (cfg.PageWidth - 2*cfg.Margin) / float64(cfg.Columns)
+	cellH := (cfg.PageHeight - 2*cfg.Margin) / float64(cfg.Rows)
+	imgSize := min(cellW, cellH-2*cfg.FontSize) * 0.8
+	if imgSize <= 0 {
+		return fmt.Errorf("wavatar: pdf grid %dx%d does not fit the page", cfg.Columns, cfg.Rows)
+	}
+
+	perPage := cfg.Columns * cfg.Rows
+	pageCount := max((len(entries)+perPage-1)/perPage, 1)
+
+	// Object numbers: 1 catalog, 2 page tree, 3 font, then per page the page
+	// object, its content stream and one image object per entry
+	pageObjs := make([]int, pageCount)
+	next := 4
+	for p := range pageObjs {
+		pageObjs[p] = next
+		next += 2 + min(perPage, len(entries)-p*perPage)
+	}
+
+	pw := &pdfWriter{w: w, offsets: make([]int, next)}
+	pw.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+
+	pw.object(1, "<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, pageCount)
+	for p, obj := range pageObjs {
+		kids[p] = fmt.Sprintf("%d 0 R", obj)
+	}
+	pw.object(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	pw.object(3, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for p, pageObj := range pageObjs {
+		start := p * perPage
+		end := min(start+perPage, len(entries))
+
+		var content bytes.Buffer
+		var xobjects strings.Builder
+		for i := start; i < end; i++ {
+			n := i - start
+			col, row := n%cfg.Columns, n/cfg.Columns
+			cellX := cfg.Margin + float64(col)*cellW
+			cellTop := cfg.PageHeight - cfg.Margin - float64(row)*cellH
+
+			imgX := cellX + (cellW-imgSize)/2
+			imgY := cellTop - imgSize - (cellH-imgSize-2*cfg.FontSize)/2
+			fmt.Fprintf(&content, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", imgSize, imgSize, imgX, imgY, n)
+
+			label := pdfText(entries[i].Label)
+			// Every rune is one glyph, and Helvetica glyphs average roughly half
+			// an em, close enough to center a label
+			glyphs := utf8.RuneCountInString(entries[i].Label)
+			textX := cellX + (cellW-float64(glyphs)*cfg.FontSize*0.5)/2
+			textY := imgY - 1.5*cfg.FontSize
+			fmt.Fprintf(&content, "BT /F1 %.2f Tf %.2f %.2f Td (%s) Tj ET\n", cfg.FontSize, textX, textY, label)
+
+			fmt.Fprintf(&xobjects, " /Im%d %d 0 R", n, pageObj+2+n)
+		}
+
+		pw.object(pageObj, fmt.Sprintf(
+			"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> /XObject <<%s >> >> /Contents %d 0 R >>",
+			cfg.PageWidth, cfg.PageHeight, xobjects.String(), pageObj+1))
+		pw.stream(pageObj+1, "", content.Bytes())
+
+		for i := start; i < end; i++ {
+			img, err := Generate(entries[i].Hash)
+			if err != nil {
+				return err
+			}
+			data, err := pdfImageData(img)
+			if err != nil {
+				return err
+			}
+			pw.stream(pageObj+2+i-start, fmt.Sprintf(
+				"/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /FlateDecode ",
+				img.Bounds().Dx(), img.Bounds().Dy()), data)
+		}
+	}
+
+	xref := pw.n
+	pw.printf("xref\n0 %d\n0000000000 65535 f \n", next)
+	for _, off := range pw.offsets[1:] {
+		pw.printf("%010d 00000 n \n", off)
+	}
+	pw.printf("trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", next, xref)
+
+	return pw.err
+}
+
+// pdfWriter tracks byte offsets of the objects it writes for the xref table
+type pdfWriter struct {
+	w       io.Writer
+	n       int
+	offsets []int
+	err     error
+}
+
+// printf writes formatted output, remembering the first error
+func (pw *pdfWriter) printf(format string, args ...any) {
+	if pw.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(pw.w, format, args...)
+	pw.n += n
+	pw.err = err
+}
+
+// write writes raw bytes, remembering the first error
+func (pw *pdfWriter) write(b []byte) {
+	if pw.err != nil {
+		return
+	}
+	n, err := pw.w.Write(b)
+	pw.n += n
+	pw.err = err
+}
+
+// object writes an indirect object with the given dictionary body
+func (pw *pdfWriter) object(num int, body string) {
+	pw.offsets[num] = pw.n
+	pw.printf("%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes an indirect stream object, dict holds any extra dictionary entries
+func (pw *pdfWriter) stream(num int, dict string, data []byte) {
+	pw.offsets[num] = pw.n
+	pw.printf("%d 0 obj\n<< %s/Length %d >>\nstream\n", num, dict, len(data))
+	pw.write(data)
+	pw.printf("\nendstream\nendobj\n")
+}
+
+// pdfImageData flattens img onto white and returns its zlib compressed RGB samples
+func pdfImageData(img image.Image) ([]byte, error) {
+	rgba := toRGBA(img)
+	b := rgba.Bounds()
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	row := make([]byte, 3*b.Dx())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := rgba.PixOffset(x, y)
+			a := 255 - rgba.Pix[i+3]
+			j := 3 * (x - b.Min.X)
+			row[j] = rgba.Pix[i] + a
+			row[j+1] = rgba.Pix[i+1] + a
+			row[j+2] = rgba.Pix[i+2] + a
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// pdfText escapes s for use in a PDF literal string in WinAnsiEncoding, the
+// encoding of the standard font. Latin-1 and the punctuation WinAnsi adds
+// become octal escapes of their code, other characters outside printable
+// ASCII become '?'.
+func pdfText(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r >= 0x20 && r <= 0x7e:
+			sb.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&sb, "\\%03o", r)
+		default:
+			if code, ok := winAnsi[r]; ok {
+				fmt.Fprintf(&sb, "\\%03o", code)
+			} else {
+				sb.WriteByte('?')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// winAnsi maps the characters WinAnsiEncoding places at 0x80-0x9f to their codes
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e,
+	'\u2018': 0x91, '\u2019': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+	'˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b, 'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
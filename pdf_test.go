@@ -0,0 +1,189 @@
+package wavatar
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// parsePDFObjects validates the xref table of a PDF and returns the body of every object by number
+func parsePDFObjects(t *testing.T, data []byte) map[int]string {
+	t.Helper()
+
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatal("Missing PDF header")
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if m == nil {
+		t.Fatal("Missing startxref trailer")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(data[xref:]), "\n")
+	if lines[0] != "xref" {
+		t.Fatalf("startxref does not point at the xref table, got %q", lines[0])
+	}
+
+	var first, count int
+	if _, err := fmt.Sscanf(lines[1], "%d %d", &first, &count); err != nil || first != 0 {
+		t.Fatalf("Invalid xref subsection header %q", lines[1])
+	}
+
+	objects := make(map[int]string)
+	for num := 1; num < count; num++ {
+		entry := lines[2+num]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " n ") {
+			t.Fatalf("Invalid xref entry %q for object %d", entry, num)
+		}
+		off, _ := strconv.Atoi(entry[:10])
+		header := fmt.Sprintf("%d 0 obj\n", num)
+		if !bytes.HasPrefix(data[off:], []byte(header)) {
+			t.Fatalf("xref offset %d does not point at object %d", off, num)
+		}
+		body := data[off+len(header):]
+		objects[num] = string(body[:bytes.Index(body, []byte("endobj"))])
+	}
+	if !strings.Contains(string(data), fmt.Sprintf("/Size %d", count)) {
+		t.Errorf("Trailer size does not match xref count %d", count)
+	}
+
+	return objects
+}
+
+func TestWritePDFSheetStructure(t *testing.T) {
+	var entries []SheetEntry
+	for i := 0; i < 7; i++ {
+		entries = append(entries, SheetEntry{
+			Hash:  []byte(fmt.Sprintf("user%d@example.com", i)),
+			Label: fmt.Sprintf("User (%d)", i),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WritePDFSheet(&buf, entries, PDFConfig{Columns: 2, Rows: 2}); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	objects := parsePDFObjects(t, buf.Bytes())
+
+	if !strings.Contains(objects[2], "/Count 2") {
+		t.Errorf("Expected page tree to count 2 pages, got %q", objects[2])
+	}
+
+	var imagesPerPage []int
+	xobject := regexp.MustCompile(`/Im\d+ (\d+) 0 R`)
+	for num := 1; num <= len(objects); num++ {
+		body := objects[num]
+		if !strings.Contains(body, "/Type /Page ") {
+			continue
+		}
+		refs := xobject.FindAllStringSubmatch(body, -1)
+		for _, ref := range refs {
+			n, _ := strconv.Atoi(ref[1])
+			if !strings.Contains(objects[n], "/Subtype /Image") {
+				t.Errorf("Object %d referenced from page %d is not an image", n, num)
+			}
+		}
+		imagesPerPage = append(imagesPerPage, len(refs))
+	}
+
+	if fmt.Sprint(imagesPerPage) != "[4 3]" {
+		t.Errorf("Expected [4 3] images per page, got %v", imagesPerPage)
+	}
+	if !strings.Contains(buf.String(), `(User \(6\)) Tj`) {
+		t.Error("Expected escaped label text in the content stream")
+	}
+}
+
+func TestWritePDFSheetEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePDFSheet(&buf, nil, PDFConfig{}); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	objects := parsePDFObjects(t, buf.Bytes())
+
+	if !strings.Contains(objects[2], "/Count 1") {
+		t.Errorf("Expected a single blank page, got %q", objects[2])
+	}
+}
+
+func TestWritePDFSheetRejectsImpossibleGrid(t *testing.T) {
+	err := WritePDFSheet(new(bytes.Buffer), nil, PDFConfig{PageHeight: 100, Rows: 50})
+	if err == nil {
+		t.Error("Expected an error for a grid that does not fit the page")
+	}
+}
+
+// Image XObjects declare the size the default Generator renders at
+func TestWritePDFSheetDefaultSize(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+	g, err := NewGenerator(nil, WithSize(160))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(g)
+
+	var buf bytes.Buffer
+	if err := WritePDFSheet(&buf, []SheetEntry{{Hash: []byte("test@example.com")}}, PDFConfig{}); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+	objects := parsePDFObjects(t, buf.Bytes())
+
+	body := objects[6]
+	if !strings.Contains(body, "/Width 160 /Height 160") {
+		t.Fatalf("Expected a 160x160 image, got %q", body[:min(len(body), 160)])
+	}
+	data := body[strings.Index(body, "stream\n")+len("stream\n") : strings.LastIndex(body, "\nendstream")]
+	zr, err := zlib.NewReader(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decompress image: %v", err)
+	}
+	samples, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("Failed to decompress image: %v", err)
+	}
+	if len(samples) != 3*160*160 {
+		t.Errorf("Expected %d samples, got %d", 3*160*160, len(samples))
+	}
+}
+
+func TestPDFText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"User (1)", `User \(1\)`},
+		{`a\b`, `a\\b`},
+		{"Zoë Ångström", `Zo\353 \305ngstr\366m`},
+		{"€5 – “ok”", `\2005 \226 \223ok\224`},
+		{"日本\n", "???"},
+	}
+	for _, tt := range tests {
+		if got := pdfText(tt.in); got != tt.want {
+			t.Errorf("pdfText(%q): expected %q, got %q", tt.in, tt.want, got)
+		}
+	}
+}
+
+// Labels are centered by their characters, not the bytes of their encoding
+func TestWritePDFSheetCentersLatin1(t *testing.T) {
+	entries := []SheetEntry{
+		{Hash: []byte("user1@example.com"), Label: "Zoe (1)"},
+		{Hash: []byte("user2@example.com"), Label: "Zoë (1)"},
+	}
+	var buf bytes.Buffer
+	if err := WritePDFSheet(&buf, entries, PDFConfig{Columns: 1, Rows: 2}); err != nil {
+		t.Fatalf("Failed to write PDF: %v", err)
+	}
+
+	m := regexp.MustCompile(`([\d.]+) [\d.]+ Td \((Zo.*?) \\\(1\\\)\) Tj`).FindAllStringSubmatch(buf.String(), -1)
+	if len(m) != 2 {
+		t.Fatalf("Expected 2 labels in the content stream, got %q", m)
+	}
+	if m[1][2] != `Zo\353` {
+		t.Errorf("Expected ë as WinAnsi \\353, got %q", m[1][2])
+	}
+	if m[0][1] != m[1][1] {
+		t.Errorf("Expected both labels at the same x, got %s and %s", m[0][1], m[1][1])
+	}
+}
@@ -0,0 +1,69 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// PlaceholderKind is a fixed avatar for accounts without a user behind them
+type PlaceholderKind int
+
+// The placeholders are part of the API: each renders the same avatar in every
+// release, whatever changes to how hashes select parts and colors.
+const (
+	// PlaceholderDeleted is a neutral face in muted grays, for deleted users
+	PlaceholderDeleted PlaceholderKind = iota + 1
+	// PlaceholderSystem is a steel blue face with outlined features and a
+	// "01" mouth, for bots and system messages
+	PlaceholderSystem
+	// PlaceholderAnonymous is a gray face silhouette on a transparent
+	// background, for anonymous users
+	PlaceholderAnonymous
+)
+
+// placeholderSpecs are the reserved Specs of the placeholders. They never
+// change, so neither do the placeholders while the parts stay the same.
+var placeholderSpecs = map[PlaceholderKind]Spec{
+	PlaceholderDeleted: {
+		Face: 1, Brow: 0, Eyes: 1, Pupil: 1, Mouth: 1,
+		BackgroundRGBA: color.RGBA{R: 150, G: 150, B: 150, A: 255},
+		WaveRGBA:       color.RGBA{R: 205, G: 205, B: 205, A: 255},
+	},
+	PlaceholderSystem: {
+		Face: 2, Brow: 1, Eyes: 2, Pupil: 2, Mouth: 2,
+		BackgroundRGBA: color.RGBA{R: 46, G: 64, B: 87, A: 255},
+		WaveRGBA:       color.RGBA{R: 120, G: 160, B: 200, A: 255},
+	},
+	PlaceholderAnonymous: {Face: 1},
+}
+
+// anonymousColor is the color of the PlaceholderAnonymous silhouette
+var anonymousColor = color.RGBA{R: 128, G: 128, B: 128, A: 255}
+
+// Placeholder renders the fixed avatar of kind. opts apply as for Generate,
+// so a placeholder can be sized or styled like the avatars around it.
+func Placeholder(kind PlaceholderKind, opts ...Option) (image.Image, error) {
+	s, ok := placeholderSpecs[kind]
+	if !ok {
+		return nil, fmt.Errorf("wavatar: unknown placeholder kind %d", kind)
+	}
+	if kind == PlaceholderSystem {
+		opts = append([]Option{WithFeatureOutline(1), WithInitialsMouth("01", basicfont.Face7x13)}, opts...)
+	}
+	o, err := Default().options(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == PlaceholderAnonymous {
+		mask, err := faceMask(s, o)
+		if err != nil {
+			return nil, err
+		}
+		return silhouette(mask, anonymousColor), nil
+	}
+	return generate(s, o)
+}
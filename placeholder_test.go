@@ -0,0 +1,63 @@
+package wavatar
+
+import (
+	"testing"
+)
+
+func TestPlaceholderGolden(t *testing.T) {
+	for name, kind := range map[string]PlaceholderKind{
+		"deleted":   PlaceholderDeleted,
+		"system":    PlaceholderSystem,
+		"anonymous": PlaceholderAnonymous,
+	} {
+		img, err := Placeholder(kind)
+		if err != nil {
+			t.Fatalf("Failed to render placeholder %s: %v", name, err)
+		}
+		checkGolden(t, "placeholder-"+name, img)
+	}
+}
+
+func TestPlaceholderIgnoresHashing(t *testing.T) {
+	for kind := PlaceholderDeleted; kind <= PlaceholderAnonymous; kind++ {
+		want, err := Placeholder(kind)
+		if err != nil {
+			t.Fatalf("Failed to render placeholder %d: %v", kind, err)
+		}
+		// Options that change how hashes select do not reach the placeholders
+		got, err := Placeholder(kind, WithVersion(V2), WithCountPolicy(CountExtend))
+		if err != nil {
+			t.Fatalf("Failed to render placeholder %d: %v", kind, err)
+		}
+		if _, stats, _ := DiffImage(want, got); stats.Changed != 0 {
+			t.Errorf("Placeholder %d: expected hash selection options to change nothing, %d pixels differ", kind, stats.Changed)
+		}
+	}
+}
+
+func TestPlaceholderOptions(t *testing.T) {
+	img, err := Placeholder(PlaceholderSystem, WithSize(160))
+	if err != nil {
+		t.Fatalf("Failed to render placeholder: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 160 {
+		t.Errorf("Expected width 160, got %d", b.Dx())
+	}
+
+	anon, err := Placeholder(PlaceholderAnonymous)
+	if err != nil {
+		t.Fatalf("Failed to render placeholder: %v", err)
+	}
+	if _, _, _, a := anon.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("Expected a transparent corner, got alpha %d", a)
+	}
+
+	for _, kind := range []PlaceholderKind{0, PlaceholderAnonymous + 1} {
+		if _, err := Placeholder(kind); err == nil {
+			t.Errorf("Expected an error for placeholder kind %d", kind)
+		}
+	}
+	if _, err := Placeholder(PlaceholderDeleted, WithBlur(-1)); err == nil {
+		t.Error("Expected an error for an invalid option")
+	}
+}
@@ -0,0 +1,125 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestWritePNG(t *testing.T) {
+	img := New([]byte("test@example.com"))
+	sizes := map[png.CompressionLevel]int{}
+	for _, level := range []png.CompressionLevel{png.DefaultCompression, png.NoCompression, png.BestSpeed, png.BestCompression} {
+		var buf bytes.Buffer
+		if err := WritePNG(&buf, img, level); err != nil {
+			t.Fatalf("Level %d: failed to write: %v", level, err)
+		}
+		sizes[level] = buf.Len()
+		decoded, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatalf("Level %d: failed to decode: %v", level, err)
+		}
+		if _, stats, _ := DiffImage(decoded, img); stats.Changed != 0 {
+			t.Errorf("Level %d: expected the image back, got %d pixels changed", level, stats.Changed)
+		}
+	}
+	if sizes[png.NoCompression] <= sizes[png.BestSpeed] || sizes[png.BestSpeed] < sizes[png.BestCompression] {
+		t.Errorf("Expected sizes to shrink with more compression, got %v", sizes)
+	}
+
+	// The same bytes as the standard encoder
+	var want bytes.Buffer
+	if err := png.Encode(&want, img); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	var got bytes.Buffer
+	if err := WritePNG(&got, img, png.DefaultCompression); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want.Bytes()) {
+		t.Error("Expected the bytes of png.Encode")
+	}
+
+	for _, level := range []png.CompressionLevel{1, -4} {
+		if err := WritePNG(&got, img, level); err == nil {
+			t.Errorf("Expected an error for level %d", level)
+		}
+	}
+}
+
+func TestWritePNGConcurrent(t *testing.T) {
+	img := New([]byte("test@example.com"))
+	var want bytes.Buffer
+	if err := WritePNG(&want, img, png.BestSpeed); err != nil {
+		t.Fatalf("Failed to write: %v", err)
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				var buf bytes.Buffer
+				if err := WritePNG(&buf, img, png.BestSpeed); err != nil || !bytes.Equal(buf.Bytes(), want.Bytes()) {
+					t.Errorf("Expected identical concurrent encodes, got error %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestServePNG(t *testing.T) {
+	img := New([]byte("test@example.com"))
+	rec := httptest.NewRecorder()
+	if err := ServePNG(rec, img, png.BestSpeed); err != nil {
+		t.Fatalf("Failed to serve: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Expected Content-Type image/png, got %q", got)
+	}
+	if got := rec.Header().Get("Content-Length"); got != strconv.Itoa(rec.Body.Len()) {
+		t.Errorf("Expected Content-Length %d, got %q", rec.Body.Len(), got)
+	}
+	if _, err := png.Decode(rec.Body); err != nil {
+		t.Errorf("Failed to decode the response: %v", err)
+	}
+
+	// An image PNG cannot hold fails before anything is sent
+	rec = httptest.NewRecorder()
+	if err := ServePNG(rec, image.NewRGBA(image.Rect(0, 0, 0, 0)), png.BestSpeed); err == nil {
+		t.Error("Expected an error for an empty image")
+	}
+	if rec.Code != http.StatusInternalServerError || rec.Header().Get("Content-Type") == "image/png" {
+		t.Errorf("Expected a plain 500, got %d with %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+}
+
+func BenchmarkWritePNG(b *testing.B) {
+	img := New([]byte("test@example.com"))
+	var buf bytes.Buffer
+	b.Run("WritePNG", func(b *testing.B) {
+		b.ReportAllocs()
+		for range b.N {
+			buf.Reset()
+			WritePNG(&buf, img, png.BestSpeed)
+		}
+	})
+	b.Run("png.Encoder", func(b *testing.B) {
+		b.ReportAllocs()
+		enc := png.Encoder{CompressionLevel: png.BestSpeed}
+		for range b.N {
+			buf.Reset()
+			enc.Encode(&buf, img)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// HueStrip renders steps evenly spaced background hues of the 0-240 wheel as
+// contiguous swatches from left to right, swatch i showing hue i*240/steps.
+// Colors use the default background saturation and lightness, so a swatch
+// matches the background PreviewWithHue renders for its hue. steps is capped
+// at width, and a strip without area is empty.
+func HueStrip(width, height, steps int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, max(width, 0), max(height, 0)))
+	if width < 1 || height < 1 || steps < 1 {
+		return img
+	}
+	steps = min(steps, width)
+
+	o := defaultOptions()
+	for i := 0; i < steps; i++ {
+		swatch := image.Rect(i*width/steps, 0, (i+1)*width/steps, height)
+		c := o.backgroundColor(Spec{Background: i * 240 / steps})
+		draw.Draw(img, swatch, &image.Uniform{C: c}, image.Point{}, draw.Src)
+	}
+	return img
+}
+
+// PreviewWithHue renders the avatar for hash with its background hue forced
+// to hue on the 0-240 wheel, where 0 and 240 are the same red
+func PreviewWithHue(hash []byte, hue int, opts ...Option) (image.Image, error) {
+	if hue < 0 || hue > 240 {
+		return nil, fmt.Errorf("wavatar: hue %d out of range 0-240", hue)
+	}
+	o, err := Default().options(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+
+	s.Background = hue
+	if hue == 0 {
+		s.Background = 240
+	}
+	return generate(s, o)
+}
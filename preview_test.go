@@ -0,0 +1,90 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestHueStripMatchesPreview(t *testing.T) {
+	const width, height, steps = 240, 10, 12
+	strip := HueStrip(width, height, steps).(*image.RGBA)
+	if strip.Rect != image.Rect(0, 0, width, height) {
+		t.Fatalf("Expected a %dx%d strip, got %v", width, height, strip.Rect)
+	}
+
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+	fade := toRGBA(mustLoadPart(t, "fade", s.Fade))
+	mask := toRGBA(mustLoadPart(t, "mask", s.Face))
+
+	for i := 0; i < steps; i++ {
+		swatch := strip.RGBAAt(i*width/steps+width/steps/2, height/2)
+
+		img, err := PreviewWithHue(hash, i*240/steps)
+		if err != nil {
+			t.Fatalf("Failed to render the preview: %v", err)
+		}
+		preview := img.(*image.RGBA)
+
+		// Compare against a pixel that shows nothing but the background
+		compared := false
+		for y := 0; y < AvatarSize && !compared; y++ {
+			for x := 0; x < AvatarSize && !compared; x++ {
+				if fade.RGBAAt(x, y).A == 0 && mask.RGBAAt(x, y).A == 0 {
+					if got := preview.RGBAAt(x, y); got != swatch {
+						t.Errorf("Swatch %d: expected the preview background %v, got %v", i, swatch, got)
+					}
+					compared = true
+				}
+			}
+		}
+		if !compared {
+			t.Fatal("Expected a pixel showing only the background")
+		}
+	}
+}
+
+func TestHueStripSwatches(t *testing.T) {
+	// Hues 40 apart always differ, so every swatch is its own run of one color
+	strip := HueStrip(100, 4, 6).(*image.RGBA)
+
+	var runs []color.RGBA
+	for x := 0; x < 100; x++ {
+		c := strip.RGBAAt(x, 0)
+		if c.A != 255 {
+			t.Fatalf("Expected an opaque strip, got %v at x=%d", c, x)
+		}
+		if len(runs) == 0 || runs[len(runs)-1] != c {
+			runs = append(runs, c)
+		}
+		if c != strip.RGBAAt(x, 3) {
+			t.Fatalf("Expected uniform columns, x=%d differs", x)
+		}
+	}
+	if len(runs) != 6 {
+		t.Errorf("Expected 6 swatches, got %d", len(runs))
+	}
+
+	if b := HueStrip(0, 10, 5).Bounds(); !b.Empty() {
+		t.Errorf("Expected an empty strip, got %v", b)
+	}
+	if _, err := PreviewWithHue(nil, 241); err == nil {
+		t.Error("Expected an error for an out of range hue")
+	}
+}
+
+func TestPreviewWithHueWrapsZero(t *testing.T) {
+	hash := []byte("test@example.com")
+	zero, err := PreviewWithHue(hash, 0)
+	if err != nil {
+		t.Fatalf("Failed to render the preview: %v", err)
+	}
+	full, err := PreviewWithHue(hash, 240)
+	if err != nil {
+		t.Fatalf("Failed to render the preview: %v", err)
+	}
+	if _, stats, _ := DiffImage(zero, full); stats.Changed != 0 {
+		t.Error("Expected hues 0 and 240 to render the same")
+	}
+}
@@ -0,0 +1,43 @@
+package wavatar
+
+import "slices"
+
+// generatorConfig is one immutable set of default options of a Generator
+type generatorConfig struct {
+	opts []Option
+	// generation counts the Reconfigure calls that led to this config
+	generation uint64
+}
+
+// noConfig is the config of a Generator that has none stored, no options
+var noConfig = &generatorConfig{}
+
+// currentConfig returns the config new renders of g use
+func (g *Generator) currentConfig() *generatorConfig {
+	if cfg := g.config.Load(); cfg != nil {
+		return cfg
+	}
+	return noConfig
+}
+
+// Reconfigure replaces the options g applies to every avatar, those passed
+// to NewGenerator or an earlier Reconfigure, with opts. Invalid options are
+// rejected and leave g unchanged. Renders already running finish with the
+// options they started with, later ones use opts. The decoded parts stay
+// cached; rendered avatars, which depend on the options, are dropped from
+// the render cache. Options that only take effect when passed to
+// NewGenerator, such as WithRenderCache, have no effect here either.
+func (g *Generator) Reconfigure(opts ...Option) error {
+	if _, err := newOptions(opts); err != nil {
+		return err
+	}
+
+	g.reconfigure.Lock()
+	defer g.reconfigure.Unlock()
+	cfg := &generatorConfig{opts: slices.Clone(opts), generation: g.currentConfig().generation + 1}
+	g.config.Store(cfg)
+	if g.cache != nil {
+		g.cache.reset(cfg.generation)
+	}
+	return nil
+}
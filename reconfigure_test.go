@@ -0,0 +1,112 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestReconfigure(t *testing.T) {
+	g, err := NewGenerator(nil, WithRenderCache(10))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	hash := []byte("test@example.com")
+	render := func(g *Generator, opts ...Option) []byte {
+		t.Helper()
+		img, err := g.Generate(hash, opts...)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		return img.(*image.RGBA).Pix
+	}
+
+	before := render(g)
+	if err := g.Reconfigure(WithPastel()); err != nil {
+		t.Fatalf("Failed to reconfigure: %v", err)
+	}
+	if g.Stats().RenderCacheEntries != 0 {
+		t.Errorf("Expected the render cache dropped, got %d entries", g.Stats().RenderCacheEntries)
+	}
+	pastel := render(Default(), WithPastel())
+	if got := render(g); !bytes.Equal(got, pastel) || bytes.Equal(got, before) {
+		t.Error("Expected renders with the new options")
+	}
+	// The cached render is the new one too
+	if got := render(g); !bytes.Equal(got, pastel) {
+		t.Error("Expected the render cache to hold the new render")
+	}
+
+	// Invalid options leave the config alone
+	if err := g.Reconfigure(WithSize(1)); err == nil {
+		t.Error("Expected an error for invalid options")
+	}
+	if got := render(g); !bytes.Equal(got, pastel) {
+		t.Error("Expected the options of before a failed reconfigure")
+	}
+
+	// Options replace, not add to, the earlier ones
+	if err := g.Reconfigure(); err != nil {
+		t.Fatalf("Failed to reconfigure: %v", err)
+	}
+	if got := render(g); !bytes.Equal(got, before) {
+		t.Error("Expected the default options back")
+	}
+}
+
+func TestReconfigureConcurrent(t *testing.T) {
+	g, err := NewGenerator(nil, WithRenderCache(4))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	configs := [2][]Option{{WithVivid()}, {WithHueRange(HueRange{Min: 100, Max: 140})}}
+
+	// The avatar every hash gets under each config
+	hashes := make([][]byte, 6)
+	want := make([][2][]byte, len(hashes))
+	for i := range hashes {
+		hashes[i] = []byte(fmt.Sprintf("user%d@example.com", i))
+		for c, opts := range configs {
+			img, err := Generate(hashes[i], opts...)
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			want[i][c] = img.(*image.RGBA).Pix
+		}
+	}
+	if err := g.Reconfigure(configs[0]...); err != nil {
+		t.Fatalf("Failed to reconfigure: %v", err)
+	}
+
+	var stop atomic.Bool
+	var wg sync.WaitGroup
+	for w := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := w; !stop.Load(); n++ {
+				i := n % len(hashes)
+				img, err := g.Generate(hashes[i])
+				if err != nil {
+					t.Errorf("Failed to generate avatar: %v", err)
+					return
+				}
+				pix := img.(*image.RGBA).Pix
+				if !bytes.Equal(pix, want[i][0]) && !bytes.Equal(pix, want[i][1]) {
+					t.Errorf("Hash %d: expected the avatar of one config, got a mix", i)
+					return
+				}
+			}
+		}()
+	}
+	for n := range 200 {
+		if err := g.Reconfigure(configs[n%2]...); err != nil {
+			t.Errorf("Failed to reconfigure: %v", err)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+}
@@ -0,0 +1,144 @@
+package wavatar
+
+import (
+	"container/list"
+	"fmt"
+	"image"
+	"sync"
+)
+
+// Stats reports the activity of a Generator
+type Stats struct {
+	// RenderCacheHits and RenderCacheMisses count the Generate calls the render cache answered or not
+	RenderCacheHits   uint64
+	RenderCacheMisses uint64
+	// RenderCacheEntries is the number of avatars in the render cache
+	RenderCacheEntries int
+}
+
+// WithRenderCache keeps up to maxEntries rendered avatars in a Generator,
+// evicting the least recently used, so repeated hashes skip rendering. Only
+// Generate calls without options of their own use the cache. Every call
+// returns its own copy, so callers may modify the result freely. The cached
+// avatars count towards WithMemoryBudget and MemoryUsage.
+// It only takes effect when passed to NewGenerator.
+func WithRenderCache(maxEntries int) Option {
+	return func(o *options) error {
+		if maxEntries < 1 {
+			return fmt.Errorf("wavatar: render cache size %d must be positive", maxEntries)
+		}
+		o.renderCache = maxEntries
+		return nil
+	}
+}
+
+// renderCache remembers rendered avatars by hash, evicting the least recently used
+type renderCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+	// generation is that of the generatorConfig the cached avatars were rendered with
+	generation uint64
+	// usage is the bytes of the cached avatars, counted against budget too
+	usage  int64
+	budget *memoryBudget
+}
+
+// renderCacheEntry is the value stored in renderCache.order
+type renderCacheEntry struct {
+	key string
+	img *image.RGBA
+}
+
+// newRenderCache creates a renderCache holding at most maxEntries avatars
+func newRenderCache(maxEntries int) *renderCache {
+	return &renderCache{
+		max:     maxEntries,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the avatar cached for hash, if it was rendered with config generation
+func (c *renderCache) get(hash []byte, generation uint64) (image.Image, bool) {
+	c.mu.Lock()
+	el, ok := c.entries[string(hash)]
+	if !ok || generation != c.generation {
+		c.misses++
+		c.mu.Unlock()
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	img := el.Value.(*renderCacheEntry).img
+	c.mu.Unlock()
+
+	// Cached images are never modified, so they can be copied without the lock
+	return toRGBA(img), true
+}
+
+// add caches a copy of img for hash, unless it was rendered with another
+// config generation than the cached avatars
+func (c *renderCache) add(hash []byte, img image.Image, generation uint64) {
+	cached := toRGBA(img)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	if el, ok := c.entries[string(hash)]; ok {
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[string(hash)] = c.order.PushFront(&renderCacheEntry{key: string(hash), img: cached})
+	size := imageBytes(cached)
+	c.usage += size
+	c.budget.add(size)
+	for c.order.Len() > 0 && (c.order.Len() > c.max || c.budget.exceeded()) {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		entry := oldest.Value.(*renderCacheEntry)
+		delete(c.entries, entry.key)
+		size := imageBytes(entry.img)
+		c.usage -= size
+		c.budget.add(-size)
+	}
+}
+
+// reset drops every cached avatar, caching those of config generation from now on
+func (c *renderCache) reset(generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clear(c.entries)
+	c.order.Init()
+	c.generation = generation
+	c.budget.add(-c.usage)
+	c.usage = 0
+}
+
+// memoryUsage returns the approximate number of bytes held by cached avatars
+func (c *renderCache) memoryUsage() int64 {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.usage
+}
+
+// Stats returns the activity of g so far
+func (g *Generator) Stats() Stats {
+	var s Stats
+	if c := g.cache; c != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		s.RenderCacheHits, s.RenderCacheMisses, s.RenderCacheEntries = c.hits, c.misses, c.order.Len()
+	}
+	return s
+}
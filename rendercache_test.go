@@ -0,0 +1,108 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"sync"
+	"testing"
+)
+
+func TestRenderCacheStats(t *testing.T) {
+	g, err := NewGenerator(nil, WithRenderCache(2))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	for _, hash := range []string{"a", "b", "a", "c", "b", "a"} {
+		if _, err := g.Generate([]byte(hash)); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+	}
+	// a and b miss, a hits, c evicts b and misses, b evicts a and misses, a misses again
+	want := Stats{RenderCacheHits: 1, RenderCacheMisses: 5, RenderCacheEntries: 2}
+	if got := g.Stats(); got != want {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+
+	// Calls with their own options bypass the cache
+	if _, err := g.Generate([]byte("a"), WithInvert()); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if got := g.Stats(); got != want {
+		t.Errorf("Expected %+v after an uncached call, got %+v", want, got)
+	}
+
+	if got := (&Generator{}).Stats(); got != (Stats{}) {
+		t.Errorf("Expected empty stats without a cache, got %+v", got)
+	}
+	if _, err := NewGenerator(nil, WithRenderCache(0)); err == nil {
+		t.Error("Expected an error for an empty render cache")
+	}
+}
+
+func TestRenderCacheMutationSafety(t *testing.T) {
+	g, err := NewGenerator(nil, WithRenderCache(4))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	hash := []byte("test@example.com")
+	want := New(hash).(*image.RGBA)
+
+	// Neither the image that filled the cache nor one served from it may alias the cache
+	for range 3 {
+		img, err := g.Generate(hash)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		rgba := img.(*image.RGBA)
+		if !bytes.Equal(rgba.Pix, want.Pix) {
+			t.Fatal("Expected the cached avatar to match New")
+		}
+		clear(rgba.Pix)
+	}
+	if hits := g.Stats().RenderCacheHits; hits != 2 {
+		t.Errorf("Expected 2 hits, got %d", hits)
+	}
+}
+
+func TestRenderCacheConcurrent(t *testing.T) {
+	g, err := NewGenerator(nil, WithRenderCache(8))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+
+	want := make([]*image.RGBA, 12)
+	for i := range want {
+		want[i] = New([]byte(fmt.Sprintf("user%d@example.com", i))).(*image.RGBA)
+	}
+
+	var wg sync.WaitGroup
+	for w := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range 50 {
+				i := (w + n) % len(want)
+				img, err := g.Generate([]byte(fmt.Sprintf("user%d@example.com", i)))
+				if err != nil {
+					t.Errorf("Failed to generate avatar: %v", err)
+					return
+				}
+				rgba := img.(*image.RGBA)
+				if !bytes.Equal(rgba.Pix, want[i].Pix) {
+					t.Errorf("Expected the avatar of user%d", i)
+					return
+				}
+				// Scribbling over the result must not affect other callers
+				clear(rgba.Pix)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s := g.Stats()
+	if s.RenderCacheHits+s.RenderCacheMisses != 8*50 || s.RenderCacheEntries != 8 {
+		t.Errorf("Expected 400 lookups and 8 entries, got %+v", s)
+	}
+}
@@ -0,0 +1,79 @@
+package wavatar
+
+import (
+	"image"
+	"math"
+)
+
+// areaWeight is the contribution of a run of source pixels to one destination pixel
+type areaWeight struct {
+	start   int
+	weights []float64
+}
+
+// areaWeights computes the area averaging weights for scaling srcN pixels to dstN
+func areaWeights(srcN, dstN int) []areaWeight {
+	scale := float64(srcN) / float64(dstN)
+	contribs := make([]areaWeight, dstN)
+	for i := range contribs {
+		lo := float64(i) * scale
+		hi := lo + scale
+		start := int(lo)
+		end := min(int(math.Ceil(hi)), srcN)
+
+		weights := make([]float64, end-start)
+		for j := start; j < end; j++ {
+			overlap := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			weights[j-start] = overlap / scale
+		}
+		contribs[i] = areaWeight{start: start, weights: weights}
+	}
+	return contribs
+}
+
+// resample scales img to size x size by area averaging its premultiplied pixels
+func resample(img image.Image, size int) *image.RGBA {
+	return resampleTo(img, size, size)
+}
+
+// resampleTo scales img to w x h by area averaging its premultiplied pixels
+func resampleTo(img image.Image, w, h int) *image.RGBA {
+	src := toRGBA(img)
+	sw, sh := src.Rect.Dx(), src.Rect.Dy()
+
+	// Horizontal pass into a float buffer of w x sh
+	cols := areaWeights(sw, w)
+	tmp := make([]float64, 4*w*sh)
+	for y := 0; y < sh; y++ {
+		row := src.Pix[y*src.Stride:]
+		for x, c := range cols {
+			for j, wt := range c.weights {
+				s := 4 * (c.start + j)
+				for k := 0; k < 4; k++ {
+					tmp[4*(y*w+x)+k] += wt * float64(row[s+k])
+				}
+			}
+		}
+	}
+
+	// Vertical pass into the destination
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	rows := areaWeights(sh, h)
+	for y, c := range rows {
+		for x := 0; x < w; x++ {
+			var sum [4]float64
+			for j, wt := range c.weights {
+				s := 4 * ((c.start+j)*w + x)
+				for k := 0; k < 4; k++ {
+					sum[k] += wt * tmp[s+k]
+				}
+			}
+			d := dst.PixOffset(x, y)
+			for k := 0; k < 4; k++ {
+				dst.Pix[d+k] = uint8(clamp(int(math.Round(sum[k]))))
+			}
+		}
+	}
+
+	return dst
+}
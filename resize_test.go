@@ -0,0 +1,38 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestResampleSize(t *testing.T) {
+	img := New([]byte("test@example.com"))
+
+	for _, size := range []int{8, 33, 80, 160} {
+		got := resample(img, size).Bounds()
+		if got.Dx() != size || got.Dy() != size {
+			t.Errorf("Expected %dx%d, got %dx%d", size, size, got.Dx(), got.Dy())
+		}
+	}
+}
+
+func TestResampleAveragesArea(t *testing.T) {
+	// Left half black, right half white, downscaled to a single pixel
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			c := color.RGBA{A: 255}
+			if x >= 2 {
+				c = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+
+	got := resample(img, 1).RGBAAt(0, 0)
+	want := color.RGBA{R: 128, G: 128, B: 128, A: 255}
+	if got != want {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,91 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand/v2"
+)
+
+// SeasonalKind selects a procedural overlay drawn on top of the avatar
+type SeasonalKind int
+
+const (
+	SeasonalNone SeasonalKind = iota
+	SeasonalSnow
+	SeasonalHearts
+	SeasonalConfetti
+)
+
+// WithSeasonal draws a seasonal overlay whose positions are derived from the avatar,
+// so every user gets their own stable arrangement
+func WithSeasonal(kind SeasonalKind) Option {
+	return func(o *options) error {
+		if kind < SeasonalNone || kind > SeasonalConfetti {
+			return fmt.Errorf("wavatar: unknown seasonal kind %d", kind)
+		}
+		o.seasonal = kind
+		return nil
+	}
+}
+
+// drawSeasonal draws the overlay for kind on img using a PRNG seeded from seed
+func drawSeasonal(img *image.RGBA, kind SeasonalKind, seed uint64) {
+	if kind == SeasonalNone {
+		return
+	}
+
+	r := rand.New(rand.NewPCG(seed, uint64(kind)))
+	size := img.Bounds().Dx()
+
+	switch kind {
+	case SeasonalSnow:
+		for range 18 {
+			x, y := r.IntN(size), r.IntN(size)
+			flake := color.RGBA{R: 250, G: 250, B: 255, A: 255}
+			fillRect(img, image.Rect(x, y, x+1, y+1), flake)
+			if r.IntN(2) == 0 {
+				// Larger flakes get a plus shape
+				fillRect(img, image.Rect(x-1, y, x+2, y+1), flake)
+				fillRect(img, image.Rect(x, y-1, x+1, y+2), flake)
+			}
+		}
+	case SeasonalHearts:
+		heart := []string{
+			".X.X.",
+			"XXXXX",
+			"XXXXX",
+			".XXX.",
+			"..X..",
+		}
+		for range 6 {
+			x, y := r.IntN(size-5), r.IntN(size-5)
+			col := color.RGBA{R: 230, G: uint8(40 + r.IntN(60)), B: uint8(80 + r.IntN(60)), A: 255}
+			for dy, row := range heart {
+				for dx, c := range row {
+					if c == 'X' {
+						fillRect(img, image.Rect(x+dx, y+dy, x+dx+1, y+dy+1), col)
+					}
+				}
+			}
+		}
+	case SeasonalConfetti:
+		palette := []color.RGBA{
+			{R: 239, G: 71, B: 111, A: 255},
+			{R: 255, G: 209, B: 102, A: 255},
+			{R: 6, G: 214, B: 160, A: 255},
+			{R: 17, G: 138, B: 178, A: 255},
+		}
+		for range 24 {
+			x, y := r.IntN(size), r.IntN(size)
+			w, h := 1+r.IntN(3), 1+r.IntN(3)
+			fillRect(img, image.Rect(x, y, x+w, y+h), palette[r.IntN(len(palette))])
+		}
+	}
+}
+
+// fillRect paints rect, clipped to img, with an opaque color
+func fillRect(img *image.RGBA, rect image.Rectangle, col color.RGBA) {
+	draw.Draw(img, rect.Intersect(img.Bounds()), &image.Uniform{C: col}, image.Point{}, draw.Src)
+}
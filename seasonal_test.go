@@ -0,0 +1,68 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestSeasonalSnowAddsLightSpecks(t *testing.T) {
+	hash := []byte("test@example.com")
+	plain := New(hash).(*image.RGBA)
+
+	first, err := Generate(hash, WithSeasonal(SeasonalSnow))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	second, err := Generate(hash, WithSeasonal(SeasonalSnow))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	snow := first.(*image.RGBA)
+	if !bytes.Equal(snow.Pix, second.(*image.RGBA).Pix) {
+		t.Error("Snow overlay should be reproducible for the same hash")
+	}
+
+	specks := 0
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			c := snow.RGBAAt(x, y)
+			if c != plain.RGBAAt(x, y) && c.R >= 240 && c.G >= 240 && c.B >= 240 {
+				specks++
+			}
+		}
+	}
+	if specks == 0 {
+		t.Error("Expected light specks from the snow overlay")
+	}
+
+	other, err := Generate([]byte("user1@example.com"), WithSeasonal(SeasonalSnow))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	otherPlain := New([]byte("user1@example.com")).(*image.RGBA)
+	_, a, _ := DiffImage(plain, snow)
+	_, b, _ := DiffImage(otherPlain, other)
+	if a.Bounds == b.Bounds && a.Changed == b.Changed {
+		t.Error("Expected different hashes to get different snow positions")
+	}
+}
+
+func TestSeasonalNoneIsNoop(t *testing.T) {
+	hash := []byte("test@example.com")
+
+	img, err := Generate(hash, WithSeasonal(SeasonalNone))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("SeasonalNone should not change the avatar")
+	}
+}
+
+func TestSeasonalUnknownKind(t *testing.T) {
+	if _, err := Generate([]byte("test@example.com"), WithSeasonal(SeasonalKind(99))); err == nil {
+		t.Error("Expected an error for an unknown seasonal kind")
+	}
+}
@@ -0,0 +1,24 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+)
+
+// WithShineIntensity scales the alpha of the shine highlight before it is
+// composited. 1 leaves the avatar unchanged, 0 removes the shine and values
+// up to 2 strengthen it, with the alpha of every pixel capped at opaque.
+func WithShineIntensity(f float64) Option {
+	return func(o *options) error {
+		if !(f >= 0 && f <= 2) {
+			return fmt.Errorf("wavatar: shine intensity %v out of range [0, 2]", f)
+		}
+		o.shineIntensity = f
+		return nil
+	}
+}
+
+// drawShine composites the shine of face onto img at the intensity of o
+func drawShine(img *image.RGBA, face int, o *options) error {
+	return o.parts.applyScaled(img, "shine", face, o.shineIntensity)
+}
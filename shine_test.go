@@ -0,0 +1,73 @@
+package wavatar
+
+import (
+	"image"
+	"testing"
+)
+
+func TestShineIntensityMonotonic(t *testing.T) {
+	s := Describe([]byte("test@example.com"))
+
+	// A translucent shine pixel can still be strengthened
+	shine := toRGBA(mustLoadPart(t, "shine", s.Face))
+	var p image.Point
+	found := false
+	for y := 0; y < AvatarSize && !found; y++ {
+		for x := 0; x < AvatarSize && !found; x++ {
+			if a := shine.RGBAAt(x, y).A; a > 20 && a < 100 {
+				p, found = image.Pt(x, y), true
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected a translucent pixel in the shine part")
+	}
+
+	var prev int
+	for i, f := range []float64{0, 0.5, 1, 2} {
+		o := defaultOptions()
+		o.shineIntensity = f
+		img := mustRenderFace(t, s, o)
+		if err := drawShine(img, s.Face, o); err != nil {
+			t.Fatalf("Failed to draw the shine: %v", err)
+		}
+
+		c := img.RGBAAt(p.X, p.Y)
+		sum := int(c.R) + int(c.G) + int(c.B)
+		if i > 0 && sum <= prev {
+			t.Errorf("Expected a stronger highlight at intensity %v, got %d <= %d", f, sum, prev)
+		}
+		prev = sum
+	}
+}
+
+func TestShineIntensityEndpoints(t *testing.T) {
+	for _, hash := range []string{"test@example.com", "user1@example.com"} {
+		img, err := Generate([]byte(hash), WithShineIntensity(1))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(New([]byte(hash)), img); stats.Changed != 0 {
+			t.Errorf("%s: expected intensity 1 to match the default render, %d pixels differ", hash, stats.Changed)
+		}
+
+		// Without shine the face is flat wave color where the shine would be
+		s := Describe([]byte(hash))
+		off := defaultOptions()
+		off.shineIntensity = 0
+		img0, err := render(s, off)
+		if err != nil {
+			t.Fatalf("Failed to render: %v", err)
+		}
+		shine := partBounds(t, "shine", s.Face)
+		if _, stats, _ := DiffImage(New([]byte(hash)), img0); stats.Changed == 0 || !stats.Bounds.In(shine) {
+			t.Errorf("%s: expected intensity 0 to change only the shine region %v, got %v", hash, shine, stats.Bounds)
+		}
+	}
+
+	for _, f := range []float64{-0.1, 2.1} {
+		if _, err := Generate(nil, WithShineIntensity(f)); err == nil {
+			t.Errorf("Expected an error for intensity %v", f)
+		}
+	}
+}
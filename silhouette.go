@@ -0,0 +1,102 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// FaceMask returns the region of the avatar for hash that the wave color
+// fills, as coverage from 0 to 255. Options that change the face shape or its
+// placement, such as WithVersion, WithAlphaFill, WithFaceCrop and WithSize, are honored.
+func FaceMask(hash []byte, opts ...Option) (*image.Alpha, error) {
+	o, err := Default().options(opts)
+	if err != nil {
+		return nil, err
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+	return faceMask(s, o)
+}
+
+// Silhouette renders the face region of the avatar for hash, exactly as
+// FaceMask returns it, in c on a transparent canvas. It shows the face shape
+// of a user without any other detail, for placeholders of anonymous users.
+func Silhouette(hash []byte, c color.Color, opts ...Option) (image.Image, error) {
+	if c == nil {
+		return nil, fmt.Errorf("wavatar: silhouette color is nil")
+	}
+	mask, err := FaceMask(hash, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return silhouette(mask, c), nil
+}
+
+// silhouette fills mask with c on a transparent canvas
+func silhouette(mask *image.Alpha, c color.Color) *image.RGBA {
+	img := image.NewRGBA(mask.Rect)
+	draw.DrawMask(img, img.Rect, image.NewUniform(c), image.Point{}, mask, mask.Rect.Min, draw.Src)
+	return img
+}
+
+// faceMask returns the fill region of s, cropped and scaled like the avatar
+func faceMask(s Spec, o *options) (*image.Alpha, error) {
+	region, err := faceRegion(s, o)
+	crop := o.faceCrop && o.sticker == nil
+	if err != nil || !crop && o.size == 0 {
+		return region, err
+	}
+
+	img := toRGBA(region)
+	if crop {
+		if img, err = cropFace(img, s, o); err != nil {
+			return nil, err
+		}
+	}
+	if o.size > 0 {
+		img = scaleAvatar(img, o.size)
+	}
+	region = image.NewAlpha(img.Rect)
+	for i := range region.Pix {
+		region.Pix[i] = img.Pix[4*i+3]
+	}
+	return region, nil
+}
+
+// faceRegion returns how much of each pixel the wave fill of s covers. The
+// fill runs on the same canvas as in renderFace, so the region matches it
+// exactly even where the mask blends into the background.
+func faceRegion(s Spec, o *options) (*image.Alpha, error) {
+	img, mask, err := renderUnfilled(s, o)
+	if err != nil {
+		return nil, err
+	}
+
+	region := image.NewAlpha(img.Rect)
+	seed := mask.faceSeed()
+	if o.alphaFill {
+		// Filling with white leaves the coverage of every filled pixel in its channels
+		coverage := image.NewRGBA(img.Rect)
+		alphaFill(coverage, mask.img, seed.X, seed.Y, color.RGBA{R: 255, G: 255, B: 255, A: 255}, o.fillConnectivity)
+		for i := range region.Pix {
+			region.Pix[i] = coverage.Pix[4*i]
+		}
+		return region, nil
+	}
+
+	// The flood fill changes every pixel it reaches and nothing else
+	filled := image.NewRGBA(img.Rect)
+	copy(filled.Pix, img.Pix)
+	floodFill(filled, seed.X, seed.Y, o.waveColor(s), o.fillConnectivity)
+	for i := range region.Pix {
+		if !bytes.Equal(filled.Pix[4*i:4*i+4], img.Pix[4*i:4*i+4]) {
+			region.Pix[i] = 255
+		}
+	}
+	return region, nil
+}
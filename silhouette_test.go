@@ -0,0 +1,86 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFaceMaskMatchesFill(t *testing.T) {
+	for _, hash := range []string{"test@example.com", "user1@example.com", "user2@example.com"} {
+		s := Describe([]byte(hash))
+		mask, err := FaceMask([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to compute the face mask: %v", err)
+		}
+
+		// Inside the mask the face has the wave color, outside it never
+		// replaced anything with it
+		face := mustRenderFace(t, s, defaultOptions())
+		unfilled, _, err := renderUnfilled(s, defaultOptions())
+		if err != nil {
+			t.Fatalf("Failed to render: %v", err)
+		}
+		wave := defaultOptions().waveColor(s)
+		for y := 0; y < AvatarSize; y++ {
+			for x := 0; x < AvatarSize; x++ {
+				inside := mask.AlphaAt(x, y).A == 255
+				if got := face.RGBAAt(x, y); inside && got != wave {
+					t.Fatalf("%s: expected the wave color %v at %d,%d, got %v", hash, wave, x, y, got)
+				}
+				if !inside && face.RGBAAt(x, y) != unfilled.RGBAAt(x, y) {
+					t.Fatalf("%s: expected %d,%d outside the mask to be unfilled", hash, x, y)
+				}
+			}
+		}
+		if mask.AlphaAt(AvatarSize/2, AvatarSize/2).A != 255 {
+			t.Errorf("%s: expected the mask to cover the center", hash)
+		}
+	}
+}
+
+func TestSilhouetteAgreesWithFaceMask(t *testing.T) {
+	fill := color.RGBA{R: 90, G: 90, B: 200, A: 255}
+	for _, opts := range [][]Option{nil, {WithAlphaFill()}, {WithFaceCrop(3)}, {WithVersion(V2)}} {
+		for _, hash := range []string{"test@example.com", "user1@example.com"} {
+			img, err := Silhouette([]byte(hash), fill, opts...)
+			if err != nil {
+				t.Fatalf("Failed to render the silhouette: %v", err)
+			}
+			mask, err := FaceMask([]byte(hash), opts...)
+			if err != nil {
+				t.Fatalf("Failed to compute the face mask: %v", err)
+			}
+
+			rgba := img.(*image.RGBA)
+			if rgba.Rect != mask.Rect {
+				t.Fatalf("Expected bounds %v, got %v", mask.Rect, rgba.Rect)
+			}
+			for y := rgba.Rect.Min.Y; y < rgba.Rect.Max.Y; y++ {
+				for x := rgba.Rect.Min.X; x < rgba.Rect.Max.X; x++ {
+					c, a := rgba.RGBAAt(x, y), mask.AlphaAt(x, y).A
+					if c.A != a {
+						t.Fatalf("%s: expected alpha %d at %d,%d, got %d", hash, a, x, y, c.A)
+					}
+					if a == 255 && c != fill {
+						t.Fatalf("%s: expected the fill color %v at %d,%d, got %v", hash, fill, x, y, c)
+					}
+				}
+			}
+		}
+	}
+
+	if _, err := Silhouette(nil, nil); err == nil {
+		t.Error("Expected an error for a nil color")
+	}
+}
+
+func TestSilhouetteFaceCropDimensions(t *testing.T) {
+	img, err := Silhouette([]byte("test@example.com"), color.Black, WithFaceCrop(2))
+	if err != nil {
+		t.Fatalf("Failed to render the silhouette: %v", err)
+	}
+	if b := img.Bounds(); b != image.Rect(0, 0, AvatarSize, AvatarSize) {
+		t.Errorf("Expected %dx%d bounds, got %v", AvatarSize, AvatarSize, b)
+	}
+}
@@ -0,0 +1,53 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	// MinSize and MaxSize bound the sizes WithSize accepts
+	MinSize = 8
+	MaxSize = 4096
+)
+
+// WithSize scales the avatar to n pixels square. The parts are composited at
+// AvatarSize as usual and the result scaled with a Catmull-Rom filter, which
+// keeps edges sharper than scaling the returned image afterwards with a
+// simpler filter. A sticker, which is not square, gets n as its longer side.
+func WithSize(n int) Option {
+	return func(o *options) error {
+		if n < MinSize || n > MaxSize {
+			return fmt.Errorf("wavatar: size %d out of range %d-%d", n, MinSize, MaxSize)
+		}
+		o.size = n
+		return nil
+	}
+}
+
+// scaleAvatar scales img so its longer side is size, keeping its aspect ratio
+func scaleAvatar(img *image.RGBA, size int) *image.RGBA {
+	b := img.Bounds()
+	w, h := fitSize(b, size)
+	if w == b.Dx() && h == b.Dy() {
+		return img
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Rect, img, b, xdraw.Src, nil)
+	return dst
+}
+
+// fitSize returns the dimensions that make the longer side of b size,
+// keeping its aspect ratio
+func fitSize(b image.Rectangle, size int) (w, h int) {
+	w, h = size, size
+	if b.Dx() > b.Dy() {
+		h = max(1, (b.Dy()*size+b.Dx()/2)/b.Dx())
+	} else if b.Dy() > b.Dx() {
+		w = max(1, (b.Dx()*size+b.Dy()/2)/b.Dy())
+	}
+	return w, h
+}
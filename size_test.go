@@ -0,0 +1,91 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestWithSize(t *testing.T) {
+	hash := []byte("test@example.com")
+	for _, size := range []int{MinSize, 64, 256, 512} {
+		img, err := Generate(hash, WithSize(size))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if b := img.Bounds(); b != image.Rect(0, 0, size, size) {
+			t.Errorf("Expected %dx%d, got %v", size, size, b)
+		}
+	}
+
+	native, err := Generate(hash, WithSize(AvatarSize))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(New(hash), native); stats.Changed != 0 {
+		t.Error("Expected WithSize(AvatarSize) to change nothing")
+	}
+}
+
+func TestWithSizeRange(t *testing.T) {
+	for _, size := range []int{-1, 0, MinSize - 1, MaxSize + 1} {
+		if _, err := Generate([]byte("test@example.com"), WithSize(size)); err == nil {
+			t.Errorf("Expected an error for size %d", size)
+		}
+	}
+}
+
+func TestWithSizeGolden(t *testing.T) {
+	img, err := Generate([]byte("test@example.com"), WithSize(256))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	checkGolden(t, "size-256", img)
+}
+
+func TestWithSizeMatchesNative(t *testing.T) {
+	// Scaled back down, an upscaled avatar is close to the native render
+	for i := range 10 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		img, err := Generate(hash, WithSize(320))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if d := meanChannelDiff(resample(img, AvatarSize), toRGBA(New(hash))); d > 6 {
+			t.Errorf("%s: expected a mean difference within 6 of the native render, got %.2f", hash, d)
+		}
+	}
+}
+
+func TestWithSizeSticker(t *testing.T) {
+	hash := []byte("test@example.com")
+	native, err := Generate(hash, WithSticker(2, color.White))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	img, err := Generate(hash, WithSticker(2, color.White), WithSize(200))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	nb, b := native.Bounds(), img.Bounds()
+	if max(b.Dx(), b.Dy()) != 200 {
+		t.Errorf("Expected a longer side of 200, got %v", b)
+	}
+	if nb.Dx() != nb.Dy() && (nb.Dx() > nb.Dy()) != (b.Dx() > b.Dy()) {
+		t.Errorf("Expected the aspect of %v to be kept, got %v", nb, b)
+	}
+}
+
+func TestFaceMaskWithSize(t *testing.T) {
+	mask, err := FaceMask([]byte("test@example.com"), WithSize(160))
+	if err != nil {
+		t.Fatalf("Failed to get face mask: %v", err)
+	}
+	if mask.Rect != image.Rect(0, 0, 160, 160) {
+		t.Errorf("Expected the mask at 160x160, got %v", mask.Rect)
+	}
+	if a := mask.AlphaAt(80, 80).A; a != 255 {
+		t.Errorf("Expected the center covered, got %d", a)
+	}
+}
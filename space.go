@@ -0,0 +1,143 @@
+package wavatar
+
+import (
+	"fmt"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// partLayers are the layers in the order a Spec lists them
+var partLayers = []Layer{LayerFace, LayerFade, LayerBrow, LayerEyes, LayerPupils, LayerMouth}
+
+// Combinations returns the number of avatars that differ in the given layers,
+// the product of their part counts, or of every layer without arguments.
+// Absent parts are not counted.
+func Combinations(layers ...Layer) int {
+	if len(layers) == 0 {
+		layers = partLayers
+	}
+	n := 1
+	for _, l := range layers {
+		n *= defaultCounts[l]
+	}
+	return n
+}
+
+// SpaceCursor marks a position in the walk of Space to resume from
+type SpaceCursor string
+
+// SpaceConfig selects the part of the avatar space Space walks
+type SpaceConfig struct {
+	// Base is the Spec whose fields outside Layers every yielded Spec keeps
+	Base Spec
+	// Layers are the dimensions walked, the first varying slowest, every
+	// layer if empty
+	Layers []Layer
+	// Step yields only every Step-th Spec of the walk, counted from its
+	// start so a resumed walk samples the same Specs; 0 is 1
+	Step int
+	// Cursor resumes the walk after the Spec it was made for, see SpaceConfig.CursorAfter
+	Cursor SpaceCursor
+}
+
+// layers returns the walked layers of c
+func (c SpaceConfig) layers() []Layer {
+	if len(c.Layers) == 0 {
+		return partLayers
+	}
+	return c.Layers
+}
+
+// cursorPrefix names the layers of c, so a cursor only resumes a walk over the same ones
+func (c SpaceConfig) cursorPrefix() string {
+	names := make([]string, 0, len(c.layers()))
+	for _, l := range c.layers() {
+		names = append(names, l.String())
+	}
+	return strings.Join(names, ".") + ":"
+}
+
+// Validate reports layers that are unknown or repeated, a negative Step, a
+// Base that is invalid with the walked layers at their first part and a
+// Cursor made for other layers
+func (c SpaceConfig) Validate() error {
+	base := c.Base
+	for i, l := range c.layers() {
+		index, _, ok := base.layer(l)
+		if !ok {
+			return fmt.Errorf("wavatar: unknown layer %d in space", l)
+		}
+		if slices.Contains(c.layers()[:i], l) {
+			return fmt.Errorf("wavatar: layer %s repeated in space", l)
+		}
+		*index = 1
+	}
+	if err := base.Validate(); err != nil {
+		return fmt.Errorf("wavatar: invalid space base: %w", err)
+	}
+	if c.Step < 0 {
+		return fmt.Errorf("wavatar: negative space step %d", c.Step)
+	}
+	_, err := c.start()
+	return err
+}
+
+// start returns the position in the walk the Cursor of c resumes from
+func (c SpaceConfig) start() (int, error) {
+	if c.Cursor == "" {
+		return 0, nil
+	}
+	pos, ok := strings.CutPrefix(string(c.Cursor), c.cursorPrefix())
+	n, err := strconv.Atoi(pos)
+	if !ok || err != nil || n < 0 || n > Combinations(c.layers()...) {
+		return 0, fmt.Errorf("wavatar: invalid space cursor %q", c.Cursor)
+	}
+	return n, nil
+}
+
+// CursorAfter returns the cursor that resumes the walk of c after s
+func (c SpaceConfig) CursorAfter(s Spec) SpaceCursor {
+	pos := 0
+	for _, l := range c.layers() {
+		index, count, _ := s.layer(l)
+		pos = pos*count + *index - 1
+	}
+	return SpaceCursor(c.cursorPrefix() + strconv.Itoa(pos+1))
+}
+
+// Space walks the Specs that differ from cfg.Base in cfg.Layers, in
+// lexicographic order of their part indices in the order of the layers: the
+// last layer counts up from 1 through its part count, then the one before it
+// moves on, like the digits of a number. There are Combinations(cfg.Layers...)
+// Specs in all, so the walk never repeats one. Space panics if cfg is invalid,
+// see SpaceConfig.Validate.
+func Space(cfg SpaceConfig) iter.Seq[Spec] {
+	if err := cfg.Validate(); err != nil {
+		panic(err)
+	}
+	start, _ := cfg.start()
+	layers := cfg.layers()
+	step := max(cfg.Step, 1)
+	// Resume at the first sampled position at or after the cursor
+	start = (start + step - 1) / step * step
+
+	total := Combinations(layers...)
+
+	return func(yield func(Spec) bool) {
+		for pos := start; pos < total; pos += step {
+			s := cfg.Base
+			// Peel the indices off pos from the last layer, which varies fastest
+			rest := pos
+			for i := len(layers) - 1; i >= 0; i-- {
+				index, count, _ := s.layer(layers[i])
+				*index = rest%count + 1
+				rest /= count
+			}
+			if !yield(s) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package wavatar
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCombinations(t *testing.T) {
+	if got := Combinations(LayerFace, LayerMouth); got != FaceCount*MouthCount {
+		t.Errorf("Expected %d faces and mouths, got %d", FaceCount*MouthCount, got)
+	}
+	if want := FaceCount * BgCount * BrowCount * EyeCount * PupilCount * MouthCount; Combinations() != want {
+		t.Errorf("Expected %d combinations of every layer, got %d", want, Combinations())
+	}
+}
+
+func TestSpace(t *testing.T) {
+	base := Describe([]byte("test@example.com"))
+	cfg := SpaceConfig{Base: base, Layers: []Layer{LayerFade, LayerEyes}}
+	specs := slices.Collect(Space(cfg))
+	if len(specs) != Combinations(cfg.Layers...) {
+		t.Fatalf("Expected %d specs, got %d", Combinations(cfg.Layers...), len(specs))
+	}
+
+	// Fade varies slowest, eyes fastest, everything else stays
+	for i, s := range specs {
+		if want := i/EyeCount + 1; s.Fade != want {
+			t.Fatalf("Spec %d: expected fade %d, got %d", i, want, s.Fade)
+		}
+		if want := i%EyeCount + 1; s.Eyes != want {
+			t.Fatalf("Spec %d: expected eyes %d, got %d", i, want, s.Eyes)
+		}
+		if s.Face != base.Face || s.Mouth != base.Mouth || s.Background != base.Background {
+			t.Fatalf("Spec %d: expected the base outside the walked layers, got %v", i, s)
+		}
+	}
+
+	// Every yielded spec renders
+	for _, s := range specs {
+		if _, err := GenerateFromSpec(s); err != nil {
+			t.Fatalf("Failed to render %v: %v", s, err)
+		}
+	}
+}
+
+func TestSpaceStepAndCursor(t *testing.T) {
+	base := Describe([]byte("test@example.com"))
+	cfg := SpaceConfig{Base: base, Layers: []Layer{LayerBrow, LayerPupils}}
+	all := slices.Collect(Space(cfg))
+
+	// Stopping early and resuming from the cursor continues the walk
+	var first []Spec
+	for s := range Space(cfg) {
+		first = append(first, s)
+		if len(first) == 30 {
+			break
+		}
+	}
+	resumed := cfg
+	resumed.Cursor = cfg.CursorAfter(first[len(first)-1])
+	rest := slices.Collect(Space(resumed))
+	if got := append(first, rest...); !slices.Equal(got, all) {
+		t.Errorf("Expected the resumed walk to complete the %d specs, got %d", len(all), len(got))
+	}
+
+	// Sampling takes every third spec, and resuming keeps the same samples
+	cfg.Step = 3
+	sampled := slices.Collect(Space(cfg))
+	if len(sampled) != (len(all)+2)/3 {
+		t.Fatalf("Expected %d samples, got %d", (len(all)+2)/3, len(sampled))
+	}
+	for i, s := range sampled {
+		if s != all[3*i] {
+			t.Fatalf("Sample %d: expected %v, got %v", i, all[3*i], s)
+		}
+	}
+	cfg.Cursor = cfg.CursorAfter(sampled[4])
+	if got := slices.Collect(Space(cfg)); !slices.Equal(got, sampled[5:]) {
+		t.Errorf("Expected resumed samples %v, got %v", sampled[5:], got)
+	}
+
+	// The cursor of the last spec ends the walk
+	cfg.Cursor = cfg.CursorAfter(all[len(all)-1])
+	if got := slices.Collect(Space(cfg)); len(got) != 0 {
+		t.Errorf("Expected nothing after the last spec, got %d specs", len(got))
+	}
+}
+
+func TestSpaceConfigValidate(t *testing.T) {
+	base := Describe([]byte("test@example.com"))
+	other := SpaceConfig{Base: base, Layers: []Layer{LayerFace}}
+	for _, tt := range []struct {
+		name string
+		cfg  SpaceConfig
+	}{
+		{"zero base", SpaceConfig{Layers: []Layer{LayerMouth}}},
+		{"unknown layer", SpaceConfig{Base: base, Layers: []Layer{Layer(9)}}},
+		{"repeated layer", SpaceConfig{Base: base, Layers: []Layer{LayerEyes, LayerEyes}}},
+		{"negative step", SpaceConfig{Base: base, Step: -1}},
+		{"garbled cursor", SpaceConfig{Base: base, Cursor: "nonsense"}},
+		{"cursor of other layers", SpaceConfig{Base: base, Layers: []Layer{LayerMouth}, Cursor: other.CursorAfter(base)}},
+		{"cursor past the end", SpaceConfig{Base: base, Layers: []Layer{LayerFace}, Cursor: "face:12"}},
+	} {
+		if err := tt.cfg.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+
+	// The base only needs to be valid outside the walked layers
+	if err := (SpaceConfig{Base: Spec{Background: 1, WaveColor: 1}}).Validate(); err != nil {
+		t.Errorf("Expected a base of colors to walk every layer, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected Space to panic for an invalid config")
+		}
+	}()
+	Space(SpaceConfig{Base: base, Step: -1})
+}
@@ -0,0 +1,261 @@
+package wavatar
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"sync"
+)
+
+// Spec holds the parts and colors selected for an avatar.
+// Part indices start at 1, colors are hues on the 1-240 wheel.
+// An index of 0 leaves out a layer other than the face, see WithOptionalLayer.
+//
+// A Spec is also how avatars are edited: Describe returns the Spec of a
+// hash, any field can be changed, and GenerateFromSpec renders the result,
+// returning an error naming the field that is out of range. For the same
+// avatar with mouth 7:
+//
+//	s := wavatar.Describe(hash)
+//	s.Mouth = 7
+//	img, err := wavatar.GenerateFromSpec(s)
+//
+// With the default options, rendering an unchanged Spec gives the same
+// pixels as New(hash).
+type Spec struct {
+	Face       int
+	Background int
+	Fade       int
+	WaveColor  int
+	Brow       int
+	Eyes       int
+	Pupil      int
+	Mouth      int
+
+	// BackgroundRGBA and WaveRGBA replace the colors of the Background and
+	// WaveColor hues when not zero. Resolve sets them to the colors a render
+	// uses, so they can be stored or adjusted by hand.
+	BackgroundRGBA color.RGBA
+	WaveRGBA       color.RGBA
+}
+
+// Describe returns the Spec that New renders for hash without rendering it,
+// selected with the version, counts and options of the default Generator.
+// Like New it panics if those options fail to apply.
+func Describe(hash []byte) Spec {
+	_, s := Default().mustDescribe(hash)
+	return s
+}
+
+// describeV1 draws every field of the Spec from a single stream seeded by hash
+func describeV1(hash []byte, c layerCounts) Spec {
+	seed := hashSeed(hash)
+	return describeFrom(rand.New(rand.NewPCG(seed, (seed>>1)|1)), c)
+}
+
+// describeFrom draws every field of a Spec from r in turn, choosing among c parts per layer
+func describeFrom(r *rand.Rand, c layerCounts) Spec {
+	var s Spec
+	s.Face = r.IntN(c[LayerFace]) + 1
+	s.Background = r.IntN(240) + 1
+	s.Fade = r.IntN(c[LayerFade]) + 1
+	s.WaveColor = r.IntN(240) + 1
+	s.Brow = r.IntN(c[LayerBrow]) + 1
+	s.Eyes = r.IntN(c[LayerEyes]) + 1
+	s.Pupil = r.IntN(c[LayerPupils]) + 1
+	s.Mouth = r.IntN(c[LayerMouth]) + 1
+
+	return s
+}
+
+// Resolve returns the Spec that Generate renders for hash with opts, with
+// BackgroundRGBA and WaveRGBA set to the colors it draws. Rendering the result
+// with GenerateFromSpec gives the same avatar as Generate.
+func Resolve(hash []byte, opts ...Option) (Spec, error) {
+	return Default().Resolve(hash, opts...)
+}
+
+// Resolve returns the Spec that g renders for hash with opts, with its colors set
+func (g *Generator) Resolve(hash []byte, opts ...Option) (Spec, error) {
+	o, err := g.options(opts)
+	if err != nil {
+		return Spec{}, err
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return Spec{}, err
+	}
+	s.BackgroundRGBA, s.WaveRGBA = o.backgroundColor(s), o.waveColor(s)
+	return s, nil
+}
+
+// NewFromSpec creates a new Wavatar from an already resolved Spec with the
+// default Generator. It panics if s is out of range or rendering fails, use
+// GenerateFromSpec to get an error instead.
+func NewFromSpec(s Spec) image.Image {
+	img, err := Default().GenerateFromSpec(s)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
+
+// Validate checks that every index and color of s is in range
+func (s Spec) Validate() error {
+	return s.validate(defaultCounts)
+}
+
+// validate checks that every color of s is in range and every index selects one of c parts
+func (s Spec) validate(c layerCounts) error {
+	fields := []struct {
+		name  string
+		value int
+		min   int
+		count int
+	}{
+		{"Face", s.Face, 1, c[LayerFace]},
+		{"Background", s.Background, 1, 240},
+		{"Fade", s.Fade, 0, c[LayerFade]},
+		{"WaveColor", s.WaveColor, 1, 240},
+		{"Brow", s.Brow, 0, c[LayerBrow]},
+		{"Eyes", s.Eyes, 0, c[LayerEyes]},
+		{"Pupil", s.Pupil, 0, c[LayerPupils]},
+		{"Mouth", s.Mouth, 0, c[LayerMouth]},
+	}
+
+	for _, f := range fields {
+		if f.value < f.min || f.value > f.count {
+			return fmt.Errorf("wavatar: spec %s %d out of range %d-%d", f.name, f.value, f.min, f.count)
+		}
+	}
+	return nil
+}
+
+// SpecCache remembers the Spec of recently seen hashes, evicting the least recently used
+type SpecCache struct {
+	mu      sync.Mutex
+	max     int
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+// specCacheEntry is the value stored in SpecCache.order
+type specCacheEntry struct {
+	key  string
+	spec Spec
+}
+
+// NewSpecCache creates a SpecCache holding at most maxEntries specs
+func NewSpecCache(maxEntries int) *SpecCache {
+	return &SpecCache{
+		max:     max(maxEntries, 1),
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// Get returns the Spec for hash, computing it with Describe on a miss
+func (c *SpecCache) Get(hash []byte) Spec {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[string(hash)]; ok {
+		c.order.MoveToFront(el)
+		return el.Value.(*specCacheEntry).spec
+	}
+
+	s := Describe(hash)
+	c.entries[string(hash)] = c.order.PushFront(&specCacheEntry{key: string(hash), spec: s})
+	if c.order.Len() > c.max {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*specCacheEntry).key)
+	}
+
+	return s
+}
+
+// Len returns the number of cached specs
+func (c *SpecCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// String lists the indices of s, and its colors when set, for logs and debugging
+func (s Spec) String() string {
+	str := fmt.Sprintf("face=%d background=%d fade=%d wave=%d brow=%d eyes=%d pupils=%d mouth=%d",
+		s.Face, s.Background, s.Fade, s.WaveColor, s.Brow, s.Eyes, s.Pupil, s.Mouth)
+	if c := s.BackgroundRGBA; c != (color.RGBA{}) {
+		str += fmt.Sprintf(" background_rgba=#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+	if c := s.WaveRGBA; c != (color.RGBA{}) {
+		str += fmt.Sprintf(" wave_rgba=#%02x%02x%02x%02x", c.R, c.G, c.B, c.A)
+	}
+	return str
+}
+
+// seed derives a stable value from s for procedural decorations
+func (s Spec) seed() uint64 {
+	h := fnv.New64a()
+	for _, v := range []int{s.Face, s.Background, s.Fade, s.WaveColor, s.Brow, s.Eyes, s.Pupil, s.Mouth} {
+		h.Write([]byte{byte(v)})
+	}
+	return h.Sum64()
+}
+
+// Layer names one of the part layers an avatar is composited from
+type Layer int
+
+const (
+	LayerFace Layer = iota
+	LayerFade
+	LayerBrow
+	LayerEyes
+	LayerPupils
+	LayerMouth
+)
+
+// String returns the part file prefix of the layer
+func (l Layer) String() string {
+	switch l {
+	case LayerFace:
+		return "face"
+	case LayerFade:
+		return "fade"
+	case LayerBrow:
+		return "brow"
+	case LayerEyes:
+		return "eyes"
+	case LayerPupils:
+		return "pupils"
+	case LayerMouth:
+		return "mouth"
+	default:
+		return fmt.Sprintf("Layer(%d)", int(l))
+	}
+}
+
+// layer returns a pointer to the index of l in s and the number of parts available for it
+func (s *Spec) layer(l Layer) (*int, int, bool) {
+	switch l {
+	case LayerFace:
+		return &s.Face, FaceCount, true
+	case LayerFade:
+		return &s.Fade, BgCount, true
+	case LayerBrow:
+		return &s.Brow, BrowCount, true
+	case LayerEyes:
+		return &s.Eyes, EyeCount, true
+	case LayerPupils:
+		return &s.Pupil, PupilCount, true
+	case LayerMouth:
+		return &s.Mouth, MouthCount, true
+	default:
+		return nil, 0, false
+	}
+}
@@ -0,0 +1,218 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestNewFromSpecMatchesNew(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+
+		got := NewFromSpec(Describe(hash)).(*image.RGBA)
+		want := New(hash).(*image.RGBA)
+		if !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("NewFromSpec(Describe(%q)) differs from New", hash)
+		}
+	}
+}
+
+func TestSpecValidate(t *testing.T) {
+	valid := Describe([]byte("test@example.com"))
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Expected Describe to produce a valid spec, got %v", err)
+	}
+
+	invalid := valid
+	invalid.Mouth = MouthCount + 1
+	if err := invalid.Validate(); err == nil {
+		t.Error("Expected an error for an out of range mouth")
+	}
+	if _, err := GenerateFromSpec(invalid); err == nil {
+		t.Error("Expected GenerateFromSpec to reject an invalid spec")
+	}
+}
+
+// Editing one part of a described Spec keeps the rest of the avatar, and
+// out of range edits are rejected with the field they are in
+func TestGenerateFromSpecEdit(t *testing.T) {
+	hash := []byte("test@example.com")
+	s := Describe(hash)
+	got, err := GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Failed to render spec: %v", err)
+	}
+	if !bytes.Equal(got.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Errorf("GenerateFromSpec(Describe(%q)) differs from New", hash)
+	}
+
+	edited := s
+	edited.Mouth = s.Mouth%MouthCount + 1
+	if _, err := GenerateFromSpec(edited); err != nil {
+		t.Errorf("Expected mouth %d to render, got %v", edited.Mouth, err)
+	}
+
+	tests := []struct {
+		field string
+		set   func(*Spec)
+	}{
+		{"Face", func(s *Spec) { s.Face = FaceCount + 1 }},
+		{"Background", func(s *Spec) { s.Background = 0 }},
+		{"Fade", func(s *Spec) { s.Fade = -1 }},
+		{"WaveColor", func(s *Spec) { s.WaveColor = 241 }},
+		{"Brow", func(s *Spec) { s.Brow = BrowCount + 1 }},
+		{"Eyes", func(s *Spec) { s.Eyes = EyeCount + 1 }},
+		{"Pupil", func(s *Spec) { s.Pupil = PupilCount + 1 }},
+		{"Mouth", func(s *Spec) { s.Mouth = MouthCount + 1 }},
+	}
+	for _, tt := range tests {
+		invalid := s
+		tt.set(&invalid)
+		_, err := GenerateFromSpec(invalid)
+		if err == nil || !strings.Contains(err.Error(), " "+tt.field+" ") {
+			t.Errorf("Expected an error naming %s, got %v", tt.field, err)
+		}
+	}
+}
+
+func TestSpecCacheReuseMatchesFresh(t *testing.T) {
+	cache := NewSpecCache(10)
+	hash := []byte("test@example.com")
+
+	for _, radius := range []int{0, 1, 2} {
+		cached, err := GenerateFromSpec(cache.Get(hash), WithBlur(radius))
+		if err != nil {
+			t.Fatalf("Failed to render cached spec: %v", err)
+		}
+		fresh, err := Generate(hash, WithBlur(radius))
+		if err != nil {
+			t.Fatalf("Failed to render fresh: %v", err)
+		}
+		if !bytes.Equal(cached.(*image.RGBA).Pix, fresh.(*image.RGBA).Pix) {
+			t.Errorf("Cached spec render with blur %d differs from a fresh render", radius)
+		}
+	}
+
+	if cache.Len() != 1 {
+		t.Errorf("Expected a single cached spec, got %d", cache.Len())
+	}
+}
+
+func TestSpecCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewSpecCache(2)
+	a, b, c := []byte("a"), []byte("b"), []byte("c")
+
+	cache.Get(a)
+	cache.Get(b)
+	cache.Get(a) // a is now the most recent
+	cache.Get(c) // evicts b
+
+	if cache.Len() != 2 {
+		t.Fatalf("Expected 2 cached specs, got %d", cache.Len())
+	}
+	if _, ok := cache.entries["b"]; ok {
+		t.Error("Expected the least recently used spec to be evicted")
+	}
+	if _, ok := cache.entries["a"]; !ok {
+		t.Error("Expected the recently used spec to be kept")
+	}
+}
+
+func TestResolveRoundTrip(t *testing.T) {
+	for _, hash := range []string{"test@example.com", "user1@example.com", "user2@example.com"} {
+		s, err := Resolve([]byte(hash))
+		if err != nil {
+			t.Fatalf("Failed to resolve: %v", err)
+		}
+		if want := defaultOptions().backgroundColor(Describe([]byte(hash))); s.BackgroundRGBA != want {
+			t.Errorf("Expected background %v, got %v", want, s.BackgroundRGBA)
+		}
+
+		img, err := GenerateFromSpec(s)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if _, stats, _ := DiffImage(New([]byte(hash)), img); stats.Changed != 0 {
+			t.Errorf("%s: expected the resolved spec to render like New, %d pixels differ", hash, stats.Changed)
+		}
+	}
+
+	// The colors follow the options, so the spec renders like Generate with them
+	opts := []Option{WithSaturation(120, 200), WithLightness(80, 150)}
+	s, err := Resolve([]byte("test@example.com"), opts...)
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	want, _ := Generate([]byte("test@example.com"), opts...)
+	got, err := GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(want, got); stats.Changed != 0 {
+		t.Errorf("Expected the resolved spec to render like Generate, %d pixels differ", stats.Changed)
+	}
+}
+
+func TestResolveOverrideBackground(t *testing.T) {
+	hash := []byte("test@example.com")
+	s, err := Resolve(hash)
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	override := color.RGBA{R: 12, G: 200, B: 99, A: 255}
+	s.BackgroundRGBA = override
+
+	img, err := GenerateFromSpec(s)
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	got := img.(*image.RGBA)
+	plain := New(hash).(*image.RGBA)
+
+	fade := toRGBA(mustLoadPart(t, "fade", s.Fade))
+	outline := toRGBA(mustLoadPart(t, "mask", s.Face))
+	mask, err := FaceMask(hash)
+	if err != nil {
+		t.Fatalf("Failed to compute the face mask: %v", err)
+	}
+	background := 0
+	for y := 0; y < AvatarSize; y++ {
+		for x := 0; x < AvatarSize; x++ {
+			switch {
+			case mask.AlphaAt(x, y).A == 255:
+				// The face and its features are untouched
+				if got.RGBAAt(x, y) != plain.RGBAAt(x, y) {
+					t.Fatalf("Expected the face pixel at %d,%d to be unchanged", x, y)
+				}
+			case fade.RGBAAt(x, y).A == 0 && outline.RGBAAt(x, y).A == 0:
+				if c := got.RGBAAt(x, y); c != override {
+					t.Fatalf("Expected the background %v at %d,%d, got %v", override, x, y, c)
+				}
+				background++
+			}
+		}
+	}
+	if background == 0 {
+		t.Error("Expected uncovered background pixels")
+	}
+
+	if _, err := s.MarshalBinary(); err == nil {
+		t.Error("Expected an error marshaling explicit colors")
+	}
+}
+
+func TestSpecString(t *testing.T) {
+	s := Spec{Face: 3, Background: 120, Fade: 2, WaveColor: 44, Brow: 5, Eyes: 2, Pupil: 7, Mouth: 11}
+	if got, want := s.String(), "face=3 background=120 fade=2 wave=44 brow=5 eyes=2 pupils=7 mouth=11"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	s.WaveRGBA = color.RGBA{R: 0xff, G: 0x80, A: 0xff}
+	if got, want := s.String(), "face=3 background=120 fade=2 wave=44 brow=5 eyes=2 pupils=7 mouth=11 wave_rgba=#ff8000ff"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,42 @@
+package wavatar
+
+import (
+	"image"
+	"image/draw"
+)
+
+// SpriteRect is the position of one avatar inside a sprite sheet, in pixels
+type SpriteRect struct {
+	X, Y, W, H int
+}
+
+// SpriteSheet renders the avatars for hashes into a single image, cols per row,
+// and returns the position of each avatar in the same order as hashes.
+// The rectangles map directly to CSS background-position offsets.
+// A cols value below 1 is treated as 1. Avatars are rendered with New, so
+// at the size of the default Generator, and every cell is as large as the
+// largest of them.
+func SpriteSheet(hashes [][]byte, cols int) (image.Image, []SpriteRect) {
+	cols = max(cols, 1)
+	rows := (len(hashes) + cols - 1) / cols
+
+	avatars := make([]image.Image, len(hashes))
+	var cell image.Point
+	for i, hash := range hashes {
+		avatars[i] = New(hash)
+		size := avatars[i].Bounds().Size()
+		cell = image.Pt(max(cell.X, size.X), max(cell.Y, size.Y))
+	}
+
+	sheet := image.NewRGBA(image.Rect(0, 0, min(cols, len(hashes))*cell.X, rows*cell.Y))
+	rects := make([]SpriteRect, len(hashes))
+	for i, img := range avatars {
+		b := img.Bounds()
+		rect := SpriteRect{X: i % cols * cell.X, Y: i / cols * cell.Y, W: b.Dx(), H: b.Dy()}
+		dst := image.Rect(rect.X, rect.Y, rect.X+rect.W, rect.Y+rect.H)
+		draw.Draw(sheet, dst, img, b.Min, draw.Src)
+		rects[i] = rect
+	}
+
+	return sheet, rects
+}
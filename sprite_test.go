@@ -0,0 +1,82 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"testing"
+
+	"github.com/weavatar/wavatar/wavatartest"
+)
+
+func TestSpriteSheet(t *testing.T) {
+	var hashes [][]byte
+	for i := 0; i < 7; i++ {
+		hashes = append(hashes, []byte(fmt.Sprintf("user%d@example.com", i)))
+	}
+
+	sheet, rects := SpriteSheet(hashes, 3)
+	if got, want := sheet.Bounds(), image.Rect(0, 0, 3*AvatarSize, 3*AvatarSize); got != want {
+		t.Errorf("Expected sheet bounds %v, got %v", want, got)
+	}
+	if len(rects) != len(hashes) {
+		t.Fatalf("Expected %d rects, got %d", len(hashes), len(rects))
+	}
+
+	for i, r := range rects {
+		rect := image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+		if !rect.In(sheet.Bounds()) {
+			t.Errorf("Rect %d %v is outside the sheet", i, rect)
+		}
+		if i > 0 {
+			prev := rects[i-1]
+			if r.Y < prev.Y || (r.Y == prev.Y && r.X <= prev.X) {
+				t.Errorf("Rect %d %+v is not after rect %d %+v", i, r, i-1, prev)
+			}
+		}
+		for j, other := range rects[:i] {
+			if rect.Overlaps(image.Rect(other.X, other.Y, other.X+other.W, other.Y+other.H)) {
+				t.Errorf("Rect %d overlaps rect %d", i, j)
+			}
+		}
+
+		crop := sheet.(*image.RGBA).SubImage(rect)
+		translated := toRGBA(crop)
+		translated.Rect = translated.Rect.Sub(rect.Min)
+		if err := wavatartest.CompareImages(translated, New(hashes[i]), 0, 0); err != nil {
+			t.Errorf("Sprite %d differs from New: %v", i, err)
+		}
+	}
+}
+
+func TestSpriteSheetSingleRow(t *testing.T) {
+	sheet, rects := SpriteSheet([][]byte{[]byte("a"), []byte("b")}, 10)
+	if got, want := sheet.Bounds(), image.Rect(0, 0, 2*AvatarSize, AvatarSize); got != want {
+		t.Errorf("Expected sheet bounds %v, got %v", want, got)
+	}
+	if rects[1].X != AvatarSize || rects[1].Y != 0 {
+		t.Errorf("Expected second sprite next to the first, got %+v", rects[1])
+	}
+}
+
+// Cells are as large as the avatars of the default Generator
+func TestSpriteSheetDefaultSize(t *testing.T) {
+	t.Cleanup(func() { SetDefault(nil) })
+	g, err := NewGenerator(nil, WithSize(160))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	SetDefault(g)
+
+	sheet, rects := SpriteSheet([][]byte{[]byte("a"), []byte("b"), []byte("c")}, 2)
+	if got, want := sheet.Bounds(), image.Rect(0, 0, 320, 320); got != want {
+		t.Errorf("Expected sheet bounds %v, got %v", want, got)
+	}
+	if want := (SpriteRect{X: 0, Y: 160, W: 160, H: 160}); rects[2] != want {
+		t.Errorf("Expected third sprite at %+v, got %+v", want, rects[2])
+	}
+	crop := toRGBA(sheet.(*image.RGBA).SubImage(image.Rect(160, 0, 320, 160)))
+	crop.Rect = crop.Rect.Sub(image.Pt(160, 0))
+	if err := wavatartest.CompareImages(crop, New([]byte("b")), 0, 0); err != nil {
+		t.Errorf("Sprite differs from New: %v", err)
+	}
+}
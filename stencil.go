@@ -0,0 +1,81 @@
+package wavatar
+
+import (
+	"image"
+)
+
+// WithDespeckle makes Stencil run a 3x3 majority filter over its output, so
+// single stray pixels are dropped instead of engraved or plotted
+func WithDespeckle() Option {
+	return func(o *options) error {
+		o.despeckle = true
+		return nil
+	}
+}
+
+// Stencil renders the avatar for hash as a black and white bitmap for laser
+// engravers and plotters. Pixels whose Rec. 709 luma, over white, is below
+// threshold become 0 and all others 255. All options apply to the render
+// before thresholding.
+func Stencil(hash []byte, threshold uint8, opts ...Option) (*image.Gray, error) {
+	o, err := Default().options(opts)
+	if err != nil {
+		return nil, err
+	}
+	img, err := generateHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+
+	stencil := stencilGray(toRGBA(img), threshold)
+	if o.despeckle {
+		stencil = despeckle(stencil)
+	}
+	return stencil, nil
+}
+
+// stencilGray thresholds the luma of img composited over white
+func stencilGray(img *image.RGBA, threshold uint8) *image.Gray {
+	dst := image.NewGray(img.Rect)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		for x := 0; x < img.Rect.Dx(); x++ {
+			p := img.Pix[y*img.Stride+4*x:]
+			// Premultiplied colors over white just add the uncovered part
+			white := 255 - float64(p[3])
+			luma := 0.2126*(float64(p[0])+white) + 0.7152*(float64(p[1])+white) + 0.0722*(float64(p[2])+white)
+			if luma >= float64(threshold) {
+				dst.Pix[y*dst.Stride+x] = 255
+			}
+		}
+	}
+	return dst
+}
+
+// despeckle sets every pixel of a black and white img to the majority of its
+// 3x3 neighborhood, counting only neighbors inside the image
+func despeckle(img *image.Gray) *image.Gray {
+	dst := image.NewGray(img.Rect)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			white, total := 0, 0
+			for ny := max(0, y-1); ny <= min(h-1, y+1); ny++ {
+				for nx := max(0, x-1); nx <= min(w-1, x+1); nx++ {
+					if img.Pix[ny*img.Stride+nx] != 0 {
+						white++
+					}
+					total++
+				}
+			}
+			// Ties keep the pixel as it was
+			v := img.Pix[y*img.Stride+x]
+			if 2*white > total {
+				v = 255
+			} else if 2*white < total {
+				v = 0
+			}
+			dst.Pix[y*dst.Stride+x] = v
+		}
+	}
+	return dst
+}
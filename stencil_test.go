@@ -0,0 +1,97 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestStencilTwoValues(t *testing.T) {
+	for _, hash := range []string{"test@example.com", "user1@example.com"} {
+		for _, opts := range [][]Option{nil, {WithDespeckle()}} {
+			img, err := Stencil([]byte(hash), 128, opts...)
+			if err != nil {
+				t.Fatalf("Failed to render the stencil: %v", err)
+			}
+			if img.Rect != image.Rect(0, 0, AvatarSize, AvatarSize) {
+				t.Errorf("Expected %dx%d bounds, got %v", AvatarSize, AvatarSize, img.Rect)
+			}
+			counts := map[uint8]int{}
+			for _, v := range img.Pix {
+				counts[v]++
+			}
+			if len(counts) != 2 || counts[0] == 0 || counts[255] == 0 {
+				t.Errorf("%s: expected only 0 and 255, got %v", hash, counts)
+			}
+		}
+	}
+}
+
+func TestStencilThreshold(t *testing.T) {
+	hash := []byte("test@example.com")
+	high, err := Stencil(hash, 255)
+	if err != nil {
+		t.Fatalf("Failed to render the stencil: %v", err)
+	}
+	white, err := Stencil(hash, 0)
+	if err != nil {
+		t.Fatalf("Failed to render the stencil: %v", err)
+	}
+	for i := range high.Pix {
+		if white.Pix[i] != 255 {
+			t.Fatal("Expected a threshold of 0 to leave every pixel white")
+		}
+	}
+
+	// A higher threshold only ever turns more pixels black
+	low, _ := Stencil(hash, 64)
+	for i := range low.Pix {
+		if low.Pix[i] == 0 && high.Pix[i] != 0 {
+			t.Fatal("Expected every pixel black at 64 to stay black at 255")
+		}
+	}
+}
+
+func TestDespeckleRemovesNoise(t *testing.T) {
+	img := image.NewGray(image.Rect(0, 0, 12, 12))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	// A solid block survives, while single pixels in white and in the block do not
+	for y := 6; y < 12; y++ {
+		for x := 0; x < 6; x++ {
+			img.Pix[y*img.Stride+x] = 0
+		}
+	}
+	img.Pix[2*img.Stride+8] = 0
+	img.Pix[0*img.Stride+11] = 0
+	img.Pix[9*img.Stride+2] = 255
+
+	clean := despeckle(img)
+	for _, p := range []image.Point{{8, 2}, {11, 0}} {
+		if clean.GrayAt(p.X, p.Y).Y != 255 {
+			t.Errorf("Expected the black speck at %v to be removed", p)
+		}
+	}
+	if clean.GrayAt(2, 9).Y != 0 {
+		t.Error("Expected the white speck inside the block to be removed")
+	}
+	if clean.GrayAt(0, 11).Y != 0 || clean.GrayAt(4, 8).Y != 0 {
+		t.Error("Expected the block to survive")
+	}
+}
+
+func TestStencilDeterministic(t *testing.T) {
+	hash := []byte("test@example.com")
+	a, err := Stencil(hash, 128, WithDespeckle())
+	if err != nil {
+		t.Fatalf("Failed to render the stencil: %v", err)
+	}
+	b, err := Stencil(hash, 128, WithDespeckle())
+	if err != nil {
+		t.Fatalf("Failed to render the stencil: %v", err)
+	}
+	if !bytes.Equal(a.Pix, b.Pix) {
+		t.Error("Expected identical stencils for the same hash")
+	}
+}
@@ -0,0 +1,54 @@
+package wavatar
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// stickerStyle is the outline drawn around a sticker
+type stickerStyle struct {
+	width int
+	color color.RGBA
+}
+
+// WithSticker renders the face without background or fade on a transparent
+// canvas, surrounded by an outline of the given width and color like a
+// messaging app sticker. The canvas is cropped to the face plus the outline
+// and a one pixel margin, so it is no longer AvatarSize square.
+func WithSticker(outlineWidth int, outline color.Color) Option {
+	return func(o *options) error {
+		if outlineWidth < 1 {
+			return fmt.Errorf("wavatar: sticker outline width must be positive, got %d", outlineWidth)
+		}
+		if outline == nil {
+			return fmt.Errorf("wavatar: sticker outline color is nil")
+		}
+		o.sticker = &stickerStyle{
+			width: outlineWidth,
+			color: color.RGBAModel.Convert(outline).(color.RGBA),
+		}
+		return nil
+	}
+}
+
+// drawSticker places the visible pixels of face on a new transparent canvas and outlines them
+func drawSticker(face *image.RGBA, style *stickerStyle) *image.RGBA {
+	bounds := visibleBounds(face)
+	pad := style.width + 1
+	canvas := image.NewRGBA(image.Rect(0, 0, bounds.Dx()+2*pad, bounds.Dy()+2*pad))
+
+	placed := image.NewRGBA(canvas.Rect)
+	draw.Draw(placed, placed.Rect, face, bounds.Min.Sub(image.Pt(pad, pad)), draw.Src)
+
+	ring := dilate(alphaMask(placed), canvas.Rect.Dx(), canvas.Rect.Dy(), style.width)
+	for i, set := range ring {
+		if set {
+			canvas.SetRGBA(i%canvas.Rect.Dx(), i/canvas.Rect.Dx(), style.color)
+		}
+	}
+	draw.Draw(canvas, canvas.Rect, placed, image.Point{}, draw.Over)
+
+	return canvas
+}
@@ -0,0 +1,77 @@
+package wavatar
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestStickerGolden(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hash []byte
+	}{
+		{"email", []byte("test@example.com")},
+		{"user1", []byte("user1@example.com")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Generate(tt.hash, WithSticker(3, color.White))
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			checkGolden(t, "sticker-"+tt.name, img)
+		})
+	}
+}
+
+func TestStickerOutlineContiguous(t *testing.T) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	const width = 3
+
+	for face := 1; face <= FaceCount; face++ {
+		s := Describe([]byte("test@example.com"))
+		s.Face = face
+		got, err := GenerateFromSpec(s, WithSticker(width, white))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		img := got.(*image.RGBA)
+		b := img.Bounds()
+
+		// The margin around the outline stays transparent
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if img.RGBAAt(x, b.Min.Y).A != 0 || img.RGBAAt(x, b.Max.Y-1).A != 0 {
+				t.Fatalf("Face %d: expected a transparent margin", face)
+			}
+		}
+
+		// Every transparent pixel must be far from the silhouette: a ring of
+		// outline color separates the face from the outside everywhere
+		outside := make(map[image.Point]bool)
+		queue := []image.Point{b.Min}
+		outside[b.Min] = true
+		for len(queue) > 0 {
+			p := queue[0]
+			queue = queue[1:]
+			for _, n := range []image.Point{{p.X + 1, p.Y}, {p.X - 1, p.Y}, {p.X, p.Y + 1}, {p.X, p.Y - 1}} {
+				if !n.In(b) || outside[n] || img.RGBAAt(n.X, n.Y) == white {
+					continue
+				}
+				if img.RGBAAt(n.X, n.Y).A != 0 {
+					t.Fatalf("Face %d: outside reaches the face at %v without crossing the outline", face, n)
+				}
+				outside[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+}
+
+func TestStickerInvalid(t *testing.T) {
+	if _, err := Generate(nil, WithSticker(0, color.White)); err == nil {
+		t.Error("Expected an error for a zero width")
+	}
+	if _, err := Generate(nil, WithSticker(2, nil)); err == nil {
+		t.Error("Expected an error for a nil color")
+	}
+}
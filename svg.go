@@ -0,0 +1,163 @@
+package wavatar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// ErrSVGUnsupported is returned by EncodeSVG for options that change the
+// avatar beyond its layers and colors, which an SVG of the layers cannot show
+var ErrSVGUnsupported = errors.New("wavatar: options not supported in SVG")
+
+// EncodeSVG writes the avatar for hash of the default Generator as an SVG
+// document, see Generator.EncodeSVG
+func EncodeSVG(w io.Writer, hash []byte) error {
+	return Default().EncodeSVG(w, hash)
+}
+
+// EncodeSVG writes the avatar for hash as an SVG document. The background
+// and wave colors are real fills, the wave clipped to the face by a mask,
+// and every part of g is an embedded PNG layer, so viewers scale the layers
+// independently. The output is the same for the same hash, byte for byte.
+//
+// The parts, colors, version and optional layers of g and opts apply, as do
+// WithSize, which sets the size the document is shown at, the fade and shine
+// intensities up to 1 and WithTransparentBackground. Options that draw over
+// or reshape the composited avatar, such as filters, outlines, masks, crops
+// and line art, return an error wrapping ErrSVGUnsupported.
+func (g *Generator) EncodeSVG(w io.Writer, hash []byte, opts ...Option) error {
+	o, err := g.options(opts)
+	if err != nil {
+		return err
+	}
+	return encodeSVGHash(w, hash, o)
+}
+
+// encodeSVGHash describes hash according to o and writes it as SVG
+func encodeSVGHash(w io.Writer, hash []byte, o *options) error {
+	if err := o.svgSupported(hash); err != nil {
+		return err
+	}
+	s, err := describeHash(hash, o)
+	if err != nil {
+		return err
+	}
+	return encodeSVG(w, s, o)
+}
+
+// svgSupported returns an error naming the first setting of o that the SVG
+// of hash cannot show
+func (o *options) svgSupported(hash []byte) error {
+	unsupported := []struct {
+		name string
+		set  bool
+	}{
+		{"filters", len(o.filters) > 0},
+		{"feature outline", o.outline > 0},
+		{"seasonal overlay", o.seasonal != 0},
+		{"initials", o.initials != ""},
+		{"background function", o.background != nil},
+		{"circle mask", o.circle},
+		{"sticker", o.sticker != nil},
+		{"face crop", o.faceCrop},
+		{"line art", o.lineArt != nil},
+		{"shine intensity above 1", o.shineIntensity > 1},
+		{"fade intensity above 1", o.fadeIntensity > 1},
+		{"default image", o.emptyHash(hash)},
+	}
+	for _, u := range unsupported {
+		if u.set {
+			return fmt.Errorf("%w: %s", ErrSVGUnsupported, u.name)
+		}
+	}
+	return nil
+}
+
+// encodeSVG writes s rendered with o as an SVG document
+func encodeSVG(w io.Writer, s Spec, o *options) error {
+	region, err := faceRegion(s, o)
+	if err != nil {
+		return err
+	}
+	// SVG masks go by luminance, so the coverage becomes gray levels
+	fill := image.NewGray(region.Rect)
+	copy(fill.Pix, region.Pix)
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%[1]d" viewBox="0 0 %d %[2]d">`+"\n", o.outputSize(), AvatarSize)
+	buf.WriteString(`<defs><mask id="wave">`)
+	if err := svgImage(&buf, "", fill, 1); err != nil {
+		return err
+	}
+	buf.WriteString("</mask></defs>\n")
+
+	layer := func(part string, num int, opacity float64) error {
+		if num == 0 || opacity == 0 {
+			return nil
+		}
+		img, err := o.parts.load(part, num)
+		if err != nil {
+			return err
+		}
+		if err := svgImage(&buf, part, img, opacity); err != nil {
+			return err
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+	if !o.transparent {
+		fmt.Fprintf(&buf, `<rect id="background" width="100%%" height="100%%" fill="%s"/>`+"\n", svgColor(o.backgroundColor(s)))
+		if err := layer("fade", s.Fade, o.fadeIntensity); err != nil {
+			return err
+		}
+	}
+	if err := layer("mask", s.Face, 1); err != nil {
+		return err
+	}
+	fmt.Fprintf(&buf, `<rect id="face" width="100%%" height="100%%" fill="%s" mask="url(#wave)"/>`+"\n", svgColor(o.waveColor(s)))
+	for _, l := range []struct {
+		part    string
+		num     int
+		opacity float64
+	}{{"shine", s.Face, o.shineIntensity}, {"brow", s.Brow, 1}, {"eyes", s.Eyes, 1}, {"pupils", s.Pupil, 1}, {"mouth", s.Mouth, 1}} {
+		if err := layer(l.part, l.num, l.opacity); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("</svg>\n")
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// svgImage writes img as an SVG image element holding a PNG data URI, with
+// opacity unless it is 1
+func svgImage(buf *bytes.Buffer, id string, img image.Image, opacity float64) error {
+	var data bytes.Buffer
+	if err := png.Encode(&data, img); err != nil {
+		return fmt.Errorf("wavatar: encode %s layer: %w", id, err)
+	}
+	buf.WriteString("<image")
+	if id != "" {
+		fmt.Fprintf(buf, ` id="%s"`, id)
+	}
+	if opacity != 1 {
+		fmt.Fprintf(buf, ` opacity="%s"`, strconv.FormatFloat(opacity, 'g', 4, 64))
+	}
+	fmt.Fprintf(buf, ` width="%d" height="%d" href="data:image/png;base64,`, img.Bounds().Dx(), img.Bounds().Dy())
+	buf.WriteString(base64.StdEncoding.EncodeToString(data.Bytes()))
+	buf.WriteString(`"/>`)
+	return nil
+}
+
+// svgColor formats c as an SVG hex color
+func svgColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
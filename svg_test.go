@@ -0,0 +1,182 @@
+package wavatar
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// svgElement is an element of the SVG EncodeSVG writes
+type svgElement struct {
+	XMLName xml.Name
+	ID      string       `xml:"id,attr"`
+	Fill    string       `xml:"fill,attr"`
+	Mask    string       `xml:"mask,attr"`
+	Href    string       `xml:"href,attr"`
+	Opacity string       `xml:"opacity,attr"`
+	Content []svgElement `xml:",any"`
+}
+
+// parseSVG decodes the SVG for hash, failing the test on error
+func parseSVG(t *testing.T, hash []byte) svgElement {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := EncodeSVG(&buf, hash); err != nil {
+		t.Fatalf("Failed to encode SVG: %v", err)
+	}
+	return decodeSVG(t, buf.Bytes())
+}
+
+// decodeSVG parses an SVG document, failing the test on error
+func decodeSVG(t *testing.T, data []byte) svgElement {
+	t.Helper()
+
+	var doc svgElement
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse SVG: %v", err)
+	}
+	return doc
+}
+
+// compositeSVG composites the layers of doc as an SVG viewer would, with the
+// colors resolved in s
+func compositeSVG(t *testing.T, doc svgElement, s Spec) *image.RGBA {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+	coverage := decodeHref(t, doc.Content[0].Content[0].Content[0])
+	for _, e := range doc.Content[1:] {
+		switch e.ID {
+		case "background":
+			draw.Draw(img, img.Rect, image.NewUniform(s.BackgroundRGBA), image.Point{}, draw.Src)
+		case "face":
+			mask := image.NewAlpha(img.Rect)
+			copy(mask.Pix, coverage.(*image.Gray).Pix)
+			draw.DrawMask(img, img.Rect, image.NewUniform(s.WaveRGBA), image.Point{}, mask, image.Point{}, draw.Over)
+		default:
+			opacity := 1.0
+			if e.Opacity != "" {
+				opacity, _ = strconv.ParseFloat(e.Opacity, 64)
+			}
+			alpha := image.NewUniform(color.Alpha{A: uint8(math.Round(opacity * 255))})
+			draw.DrawMask(img, img.Rect, decodeHref(t, e), image.Point{}, alpha, image.Point{}, draw.Over)
+		}
+	}
+	return img
+}
+
+// decodeHref decodes the PNG data URI of an image element
+func decodeHref(t *testing.T, e svgElement) image.Image {
+	t.Helper()
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(e.Href, "data:image/png;base64,"))
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", e.ID, err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode %s: %v", e.ID, err)
+	}
+	return img
+}
+
+func TestEncodeSVGLayers(t *testing.T) {
+	hash := []byte("test@example.com")
+	s, err := Resolve(hash)
+	if err != nil {
+		t.Fatalf("Failed to resolve: %v", err)
+	}
+	doc := parseSVG(t, hash)
+
+	var ids []string
+	for _, e := range doc.Content[1:] {
+		ids = append(ids, e.XMLName.Local+":"+e.ID)
+	}
+	want := "rect:background image:fade image:mask rect:face image:shine image:brow image:eyes image:pupils image:mouth"
+	if got := strings.Join(ids, " "); got != want {
+		t.Errorf("Expected layers %s, got %s", want, got)
+	}
+
+	bg, wave := doc.Content[1], doc.Content[4]
+	if want := svgColor(s.BackgroundRGBA); bg.Fill != want {
+		t.Errorf("Expected the background %s, got %s", want, bg.Fill)
+	}
+	if want := svgColor(s.WaveRGBA); wave.Fill != want || wave.Mask != "url(#wave)" {
+		t.Errorf("Expected the face %s masked by #wave, got %s masked by %s", want, wave.Fill, wave.Mask)
+	}
+}
+
+func TestEncodeSVGMatchesNew(t *testing.T) {
+	// Compositing the layers as an SVG viewer would gives the raster avatar
+	for i := range 10 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		s, _ := Resolve(hash)
+		img := compositeSVG(t, parseSVG(t, hash), s)
+		if _, stats, _ := DiffImage(New(hash), img); stats.Changed != 0 {
+			t.Errorf("%s: expected the SVG layers to composite to New, %d pixels differ by up to %d", hash, stats.Changed, stats.MaxDelta)
+		}
+	}
+}
+
+func TestEncodeSVGDeterministic(t *testing.T) {
+	var a, b bytes.Buffer
+	hash := []byte("test@example.com")
+	if err := EncodeSVG(&a, hash); err != nil {
+		t.Fatalf("Failed to encode SVG: %v", err)
+	}
+	if err := EncodeSVG(&b, hash); err != nil {
+		t.Fatalf("Failed to encode SVG: %v", err)
+	}
+	if !bytes.Equal(a.Bytes(), b.Bytes()) {
+		t.Error("Expected the same SVG for the same hash")
+	}
+}
+
+func TestGeneratorEncodeSVG(t *testing.T) {
+	// The SVG of a Generator has its colors, version and size
+	g, err := NewGenerator(nil, WithDarkTheme(), WithVersion(V2))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	for i := range 5 {
+		hash := []byte(fmt.Sprintf("user%d@example.com", i))
+		var buf bytes.Buffer
+		if err := g.EncodeSVG(&buf, hash, WithSize(160)); err != nil {
+			t.Fatalf("Failed to encode SVG: %v", err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte(`<svg xmlns="http://www.w3.org/2000/svg" width="160" height="160" viewBox="0 0 80 80">`)) {
+			t.Errorf("%s: expected the document shown at 160 pixels, got %.100s", hash, buf.Bytes())
+		}
+		s, _ := g.Resolve(hash)
+		want, err := g.Generate(hash)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		// The fade at half opacity rounds differently in the two compositors
+		img := compositeSVG(t, decodeSVG(t, buf.Bytes()), s)
+		if _, stats, _ := DiffImage(want, img); stats.MaxDelta > 1 {
+			t.Errorf("%s: expected the SVG layers to composite to the Generator avatar, %d pixels differ by up to %d", hash, stats.Changed, stats.MaxDelta)
+		}
+	}
+}
+
+func TestEncodeSVGUnsupported(t *testing.T) {
+	hash := []byte("test@example.com")
+	for _, opt := range []Option{WithCircleMask(), WithBlur(1), WithFeatureOutline(1), WithShineIntensity(2)} {
+		if err := Default().EncodeSVG(io.Discard, hash, opt); !errors.Is(err, ErrSVGUnsupported) {
+			t.Errorf("Expected ErrSVGUnsupported, got %v", err)
+		}
+	}
+}
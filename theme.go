@@ -0,0 +1,77 @@
+package wavatar
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+const (
+	// pastelBgSaturation, pastelBgLightness and pastelWaveLightness give the
+	// pale, barely tinted backgrounds and soft wave colors of the pastel theme
+	// on the 0-240 scale. Up to a saturation of 15 every channel stays in the
+	// band hsl lightens evenly, so the tint keeps its hue at any lightness.
+	pastelBgSaturation  = 15
+	pastelBgLightness   = 215
+	pastelWaveLightness = 185
+	// pastelFadeIntensity keeps the fade visible without bleaching the light background
+	pastelFadeIntensity = 0.5
+	// vividBgLightness and vividWaveLightness are the lightness of the fully
+	// saturated colors of the vivid theme
+	vividBgLightness   = 100
+	vividWaveLightness = 140
+)
+
+// themes are the looks WithTheme accepts, by name
+var themes = map[string]Option{
+	"light":  func(*options) error { return nil },
+	"dark":   WithDarkTheme(),
+	"pastel": WithPastel(),
+	"vivid":  WithVivid(),
+}
+
+// WithTheme applies the look registered under name, see ThemeNames.
+// "light" is the default look and changes nothing.
+func WithTheme(name string) Option {
+	return func(o *options) error {
+		theme, ok := themes[name]
+		if !ok {
+			return fmt.Errorf("wavatar: unknown theme %q", name)
+		}
+		return theme(o)
+	}
+}
+
+// ThemeNames returns the names WithTheme accepts, sorted
+func ThemeNames() []string {
+	return slices.Sorted(maps.Keys(themes))
+}
+
+// WithPastel renders pale, low saturation backgrounds with a softer fade
+// and soft wave colors. The hues and features are the ones the hash selects
+// anyway.
+func WithPastel() Option {
+	return func(o *options) error {
+		o.bgSaturation, o.waveSaturation = pastelBgSaturation, 240
+		o.bgLightness, o.waveLightness = pastelBgLightness, pastelWaveLightness
+		o.fadeIntensity = pastelFadeIntensity
+		return nil
+	}
+}
+
+// WithVivid renders fully saturated backgrounds with the wave color on the
+// opposite side of the color wheel, in place of the hue the hash selects for
+// it. With WithHueRange the wave color may fall outside the ranges.
+func WithVivid() Option {
+	return func(o *options) error {
+		o.bgSaturation, o.waveSaturation = 240, 240
+		o.bgLightness, o.waveLightness = vividBgLightness, vividWaveLightness
+		o.complementWave = true
+		return nil
+	}
+}
+
+// complementHue returns the hue opposite hue on the 1-240 color wheel
+func complementHue(hue int) int {
+	return (hue+119)%240 + 1
+}
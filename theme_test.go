@@ -0,0 +1,108 @@
+package wavatar
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestThemeGoldens(t *testing.T) {
+	for _, name := range []string{"pastel", "vivid"} {
+		for _, input := range []string{"test@example.com", "user1@example.com", "user7@example.com"} {
+			img, err := Generate([]byte(input), WithTheme(name))
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			checkGolden(t, name+"-"+strings.Split(input, "@")[0], img)
+		}
+	}
+}
+
+func TestThemeSelection(t *testing.T) {
+	for _, opt := range []Option{WithPastel(), WithVivid()} {
+		for i := range 100 {
+			hash := []byte(fmt.Sprintf("user%d@example.com", i))
+			s, err := Resolve(hash, opt)
+			if err != nil {
+				t.Fatalf("Failed to resolve: %v", err)
+			}
+			want := Describe(hash)
+			want.BackgroundRGBA, want.WaveRGBA = s.BackgroundRGBA, s.WaveRGBA
+			if s != want {
+				t.Errorf("Expected %+v, got %+v", want, s)
+			}
+		}
+	}
+}
+
+func TestThemeChroma(t *testing.T) {
+	tests := []struct {
+		name              string
+		opt               Option
+		bgLow, bgHigh     int
+		waveLow, waveHigh int
+		// bgFloor is the least any channel of the background may be
+		bgFloor uint8
+	}{
+		{"pastel", WithPastel(), 25, 40, 120, 160, 190},
+		{"vivid", WithVivid(), 180, 255, 200, 255, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range 1000 {
+				s, err := Resolve([]byte(fmt.Sprint(i)), tt.opt)
+				if err != nil {
+					t.Fatalf("Failed to resolve: %v", err)
+				}
+				bg := s.BackgroundRGBA
+				if c, floor := chroma(bg), min(bg.R, bg.G, bg.B); c < tt.bgLow || c > tt.bgHigh || floor < tt.bgFloor {
+					t.Fatalf("Seed %d: expected background chroma in [%d, %d] above %d, got %d above %d", i, tt.bgLow, tt.bgHigh, tt.bgFloor, c, floor)
+				}
+				if c := chroma(s.WaveRGBA); c < tt.waveLow || c > tt.waveHigh {
+					t.Fatalf("Seed %d: expected wave chroma in [%d, %d], got %d", i, tt.waveLow, tt.waveHigh, c)
+				}
+			}
+		})
+	}
+}
+
+func TestVividComplement(t *testing.T) {
+	for hue := 1; hue <= 240; hue++ {
+		s := Describe([]byte("test@example.com"))
+		s.Background = hue
+		o := defaultOptions()
+		if err := WithVivid()(o); err != nil {
+			t.Fatalf("Failed to apply: %v", err)
+		}
+		bg, wave := o.backgroundColor(s), o.waveColor(s)
+		// Opposite hues never share their strongest channel
+		strongest := func(c color.RGBA) int {
+			ch := []uint8{c.R, c.G, c.B}
+			return slices.Index(ch, slices.Max(ch))
+		}
+		if strongest(bg) == strongest(wave) {
+			t.Errorf("Hue %d: expected the wave %v opposite the background %v", hue, wave, bg)
+		}
+	}
+}
+
+func TestWithTheme(t *testing.T) {
+	if got, want := ThemeNames(), []string{"dark", "light", "pastel", "vivid"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	hash := []byte("test@example.com")
+	light, err := Generate(hash, WithTheme("light"))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, stats, _ := DiffImage(New(hash), light); stats.Changed != 0 {
+		t.Error("Expected the light theme to change nothing")
+	}
+	if _, err := Generate(hash, WithTheme("sepia")); err == nil || !strings.Contains(err.Error(), `"sepia"`) {
+		t.Errorf("Expected an unknown theme error, got %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package wavatar
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Thumbnails renders the avatar for hash once with the default Generator and
+// returns it scaled to every size in sizes, keyed by size. All sizes share
+// the same render, so their features always match. Smaller sizes are area
+// averaged from the native render and larger ones scaled up like WithSize,
+// up to MaxSize; duplicates are returned once.
+func Thumbnails(hash []byte, sizes []int, opts ...Option) (map[int]image.Image, error) {
+	return Default().Thumbnails(hash, sizes, opts...)
+}
+
+// Thumbnails renders the avatar for hash once and returns it scaled to every size in sizes
+func (g *Generator) Thumbnails(hash []byte, sizes []int, opts ...Option) (map[int]image.Image, error) {
+	o, err := g.options(opts)
+	if err != nil {
+		return nil, err
+	}
+	return thumbnails(hash, sizes, o)
+}
+
+// thumbnails renders hash with o and scales the result to sizes
+func thumbnails(hash []byte, sizes []int, o *options) (map[int]image.Image, error) {
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("wavatar: no thumbnail sizes given")
+	}
+	for _, size := range sizes {
+		if size <= 0 || size > MaxSize {
+			return nil, fmt.Errorf("wavatar: invalid thumbnail size %d", size)
+		}
+	}
+
+	img, err := generateHash(hash, o)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[int]image.Image, len(sizes))
+	for _, size := range sizes {
+		if _, ok := out[size]; ok {
+			continue
+		}
+		out[size] = scaleThumbnail(img, size)
+	}
+	return out, nil
+}
+
+// WithEncodeConcurrency lets EncodeSizes encode up to n sizes at once instead of one after another
+func WithEncodeConcurrency(n int) Option {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("wavatar: encode concurrency %d must be at least 1", n)
+		}
+		o.encodeConcurrency = n
+		return nil
+	}
+}
+
+// EncodeSizes renders the avatar for hash once with the default Generator and
+// writes it as PNG to every writer in dests, scaled to the size it is keyed
+// by. Sizes are encoded one at a time in ascending order, or concurrently
+// with WithEncodeConcurrency, so at most that many encoded images are in
+// flight. A failing destination does not stop the others; all failures are
+// returned joined, each naming its size.
+func EncodeSizes(hash []byte, dests map[int]io.Writer, opts ...Option) error {
+	return Default().EncodeSizes(hash, dests, opts...)
+}
+
+// EncodeSizes renders the avatar for hash once and writes it as PNG to every writer in dests
+func (g *Generator) EncodeSizes(hash []byte, dests map[int]io.Writer, opts ...Option) error {
+	o, err := g.options(opts)
+	if err != nil {
+		return err
+	}
+	return encodeSizes(hash, dests, o)
+}
+
+// encodeSizes renders hash with o and encodes every size of dests
+func encodeSizes(hash []byte, dests map[int]io.Writer, o *options) error {
+	sizes := slices.Sorted(maps.Keys(dests))
+	for _, size := range sizes {
+		if size <= 0 || size > MaxSize {
+			return fmt.Errorf("wavatar: invalid thumbnail size %d", size)
+		}
+		if dests[size] == nil {
+			return fmt.Errorf("wavatar: nil writer for size %d", size)
+		}
+	}
+
+	img, err := generateHash(hash, o)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(sizes))
+	sem := make(chan struct{}, o.encodeConcurrency)
+	var wg sync.WaitGroup
+	for i, size := range sizes {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := png.Encode(dests[size], scaleThumbnail(img, size)); err != nil {
+				errs[i] = fmt.Errorf("wavatar: size %d: %w", size, err)
+			}
+		}()
+	}
+	wg.Wait()
+	o.lap(stageEncode)
+	o.stopTiming()
+
+	return errors.Join(errs...)
+}
+
+// scaleThumbnail scales img to size, area averaging to shrink it and
+// interpolating with Catmull-Rom to enlarge it
+func scaleThumbnail(img image.Image, size int) image.Image {
+	switch n := img.Bounds().Dx(); {
+	case size == n:
+		return img
+	case size < n:
+		return resample(img, size)
+	default:
+		src, ok := img.(*image.RGBA)
+		if !ok {
+			src = toRGBA(img)
+		}
+		return scaleAvatar(src, size)
+	}
+}
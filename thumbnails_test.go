@@ -0,0 +1,171 @@
+package wavatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestThumbnailsSizes(t *testing.T) {
+	thumbs, err := Thumbnails([]byte("test@example.com"), []int{32, 64, 160, 32, AvatarSize})
+	if err != nil {
+		t.Fatalf("Failed to create thumbnails: %v", err)
+	}
+
+	if len(thumbs) != 4 {
+		t.Errorf("Expected 4 distinct sizes, got %d", len(thumbs))
+	}
+	for size, img := range thumbs {
+		if b := img.Bounds(); b.Dx() != size || b.Dy() != size {
+			t.Errorf("Expected %dx%d, got %dx%d", size, size, b.Dx(), b.Dy())
+		}
+	}
+}
+
+func TestThumbnailsConsistent(t *testing.T) {
+	hash := []byte("test@example.com")
+	thumbs, err := Thumbnails(hash, []int{32, 64, 160}, WithSepia(0.5))
+	if err != nil {
+		t.Fatalf("Failed to create thumbnails: %v", err)
+	}
+	native, err := Generate(hash, WithSepia(0.5))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	// Every size reduced to a common small size must show the same avatar
+	want := resample(native, 16)
+	for size, img := range thumbs {
+		got := resample(img, 16)
+		if _, stats, _ := DiffImage(got, want); stats.MaxDelta > 24 {
+			t.Errorf("Size %d differs from the native render by up to %d", size, stats.MaxDelta)
+		}
+	}
+
+	// Another hash must not pass the same check
+	other := resample(New([]byte("other@example.com")), 16)
+	if _, stats, _ := DiffImage(other, want); stats.MaxDelta <= 24 {
+		t.Error("Expected a different avatar to fail the consistency check")
+	}
+}
+
+func TestThumbnailsInvalid(t *testing.T) {
+	for _, sizes := range [][]int{nil, {32, 0}, {-64}} {
+		if _, err := Thumbnails(nil, sizes); err == nil {
+			t.Errorf("Expected an error for sizes %v", sizes)
+		}
+	}
+}
+
+var thumbnailSizes = []int{32, 64, 160}
+
+func BenchmarkThumbnails(b *testing.B) {
+	b.Run("single-render", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := Thumbnails([]byte(fmt.Sprint(i)), thumbnailSizes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("independent-renders", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, size := range thumbnailSizes {
+				img, err := Generate([]byte(fmt.Sprint(i)))
+				if err != nil {
+					b.Fatal(err)
+				}
+				resample(img, size)
+			}
+		}
+	})
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("upload failed")
+}
+
+func TestEncodeSizes(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		t.Run(fmt.Sprint(concurrency), func(t *testing.T) {
+			var small, native, large bytes.Buffer
+			dests := map[int]io.Writer{32: &small, AvatarSize: &native, 160: &large}
+
+			if err := EncodeSizes([]byte("test@example.com"), dests, WithEncodeConcurrency(concurrency)); err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			for size, buf := range map[int]*bytes.Buffer{32: &small, AvatarSize: &native, 160: &large} {
+				img, err := png.Decode(buf)
+				if err != nil {
+					t.Fatalf("Failed to decode size %d: %v", size, err)
+				}
+				if img.Bounds().Dx() != size {
+					t.Errorf("Expected width %d, got %d", size, img.Bounds().Dx())
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeSizesPartialFailure(t *testing.T) {
+	var small, large bytes.Buffer
+	dests := map[int]io.Writer{32: &small, 64: failingWriter{}, 160: &large}
+
+	err := EncodeSizes([]byte("test@example.com"), dests, WithEncodeConcurrency(2))
+	if err == nil {
+		t.Fatal("Expected an error for the failing writer")
+	}
+	if !strings.Contains(err.Error(), "size 64") || !strings.Contains(err.Error(), "upload failed") {
+		t.Errorf("Expected the error to name size 64, got %v", err)
+	}
+	if strings.Contains(err.Error(), "size 32") || strings.Contains(err.Error(), "size 160") {
+		t.Errorf("Expected only size 64 to fail, got %v", err)
+	}
+	if small.Len() == 0 || large.Len() == 0 {
+		t.Error("Expected the other sizes to be written")
+	}
+}
+
+func TestEncodeSizesInvalid(t *testing.T) {
+	if err := EncodeSizes(nil, map[int]io.Writer{0: io.Discard}); err == nil {
+		t.Error("Expected an error for size 0")
+	}
+	if err := EncodeSizes(nil, map[int]io.Writer{32: nil}); err == nil {
+		t.Error("Expected an error for a nil writer")
+	}
+	if err := EncodeSizes(nil, map[int]io.Writer{32: io.Discard}, WithEncodeConcurrency(0)); err == nil {
+		t.Error("Expected an error for zero concurrency")
+	}
+}
+
+func TestThumbnailsUpscaleMatchesWithSize(t *testing.T) {
+	hash := []byte("test@example.com")
+	thumbs, err := Thumbnails(hash, []int{40, 128, 256})
+	if err != nil {
+		t.Fatalf("Failed to create thumbnails: %v", err)
+	}
+	for _, size := range []int{40, 128, 256} {
+		direct, err := Generate(hash, WithSize(size))
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		if size > AvatarSize {
+			// Enlarged thumbnails are the same interpolation as WithSize
+			if _, stats, _ := DiffImage(thumbs[size], direct); stats.Changed != 0 {
+				t.Errorf("Size %d: expected the thumbnail to match WithSize, %d pixels differ", size, stats.Changed)
+			}
+		} else if d := meanChannelDiff(toRGBA(thumbs[size]), toRGBA(direct)); d > 6 {
+			t.Errorf("Size %d: expected a mean difference within 6 of WithSize, got %.2f", size, d)
+		}
+	}
+
+	if _, err := Thumbnails(hash, []int{MaxSize + 1}); err == nil {
+		t.Errorf("Expected an error for size %d", MaxSize+1)
+	}
+}
@@ -0,0 +1,118 @@
+package wavatar
+
+import (
+	"fmt"
+	"time"
+)
+
+// Timings is the time a render spent in each of its stages
+type Timings struct {
+	// Background is drawing the background color or BackgroundFunc
+	Background time.Duration
+	// Fade, Mask, Shine and the features are compositing those parts, which
+	// includes decoding them on first use
+	Fade time.Duration
+	Mask time.Duration
+	// Fill is filling the face with the wave color
+	Fill   time.Duration
+	Shine  time.Duration
+	Brow   time.Duration
+	Eyes   time.Duration
+	Pupils time.Duration
+	Mouth  time.Duration
+	// PostProcess covers outlines, seasonal overlays, stickers, cropping,
+	// scaling and the filter chain
+	PostProcess time.Duration
+	// Encode is only set by functions that encode, such as EncodeSizes
+	Encode time.Duration
+	// Total is the whole render, encoding included
+	Total time.Duration
+}
+
+// timingStage is a field of Timings
+type timingStage int
+
+const (
+	stageBackground timingStage = iota
+	stageFade
+	stageMask
+	stageFill
+	stageShine
+	stageBrow
+	stageEyes
+	stagePupils
+	stageMouth
+	stagePostProcess
+	stageEncode
+)
+
+// stage returns the field of t recording s
+func (t *Timings) stage(s timingStage) *time.Duration {
+	switch s {
+	case stageBackground:
+		return &t.Background
+	case stageFade:
+		return &t.Fade
+	case stageMask:
+		return &t.Mask
+	case stageFill:
+		return &t.Fill
+	case stageShine:
+		return &t.Shine
+	case stageBrow:
+		return &t.Brow
+	case stageEyes:
+		return &t.Eyes
+	case stagePupils:
+		return &t.Pupils
+	case stageMouth:
+		return &t.Mouth
+	case stagePostProcess:
+		return &t.PostProcess
+	default:
+		return &t.Encode
+	}
+}
+
+// WithTiming records how long each stage of the render takes into t, which
+// is reset when the render starts. Pass it to a single call at a time; a
+// Timings shared by concurrent renders is a data race. Without it the render
+// never reads the clock.
+func WithTiming(t *Timings) Option {
+	return func(o *options) error {
+		if t == nil {
+			return fmt.Errorf("wavatar: timings are nil")
+		}
+		o.timings = t
+		return nil
+	}
+}
+
+// startTiming resets the timings of o and starts the first stage
+func (o *options) startTiming() {
+	if o.timings == nil {
+		return
+	}
+	*o.timings = Timings{}
+	o.timingStart = time.Now()
+	o.lapStart = o.timingStart
+}
+
+// lap adds the time since the previous lap to stage s. Only renders started
+// with startTiming are timed, not partial ones such as FaceMask.
+func (o *options) lap(s timingStage) {
+	if o.timings == nil || o.timingStart.IsZero() {
+		return
+	}
+	now := time.Now()
+	*o.timings.stage(s) += now.Sub(o.lapStart)
+	o.lapStart = now
+}
+
+// stopTiming ends the timed render, after encoding if there is one
+func (o *options) stopTiming() {
+	if o.timings == nil || o.timingStart.IsZero() {
+		return
+	}
+	o.timings.Total = time.Since(o.timingStart)
+}
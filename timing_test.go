@@ -0,0 +1,100 @@
+package wavatar
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestTimingStages(t *testing.T) {
+	var tm Timings
+	if _, err := Generate([]byte("test@example.com"), WithTiming(&tm), WithFeatureOutline(1), WithSize(128)); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+
+	stages := map[string]time.Duration{
+		"background": tm.Background, "fade": tm.Fade, "mask": tm.Mask, "fill": tm.Fill,
+		"shine": tm.Shine, "brow": tm.Brow, "eyes": tm.Eyes, "pupils": tm.Pupils,
+		"mouth": tm.Mouth, "post-process": tm.PostProcess,
+	}
+	var sum time.Duration
+	for name, d := range stages {
+		if d <= 0 {
+			t.Errorf("Expected stage %s to be timed, got %v", name, d)
+		}
+		sum += d
+	}
+	if tm.Encode != 0 {
+		t.Errorf("Expected no encode time for Generate, got %v", tm.Encode)
+	}
+	// Every lap starts where the previous ended, so only the clock reads around them are missing
+	if sum > tm.Total || sum < tm.Total*9/10 {
+		t.Errorf("Expected the stages to sum to about %v, got %v", tm.Total, sum)
+	}
+}
+
+func TestTimingEncode(t *testing.T) {
+	var tm Timings
+	if err := EncodeSizes([]byte("test@example.com"), map[int]io.Writer{32: io.Discard, 160: io.Discard}, WithTiming(&tm)); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	if tm.Encode <= 0 || tm.Encode >= tm.Total {
+		t.Errorf("Expected an encode time within the total of %v, got %v", tm.Total, tm.Encode)
+	}
+
+	// A reused Timings is reset by the next render
+	total := tm.Total
+	if _, err := Generate([]byte("test@example.com"), WithTiming(&tm)); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if tm.Encode != 0 || tm.Total == total {
+		t.Errorf("Expected the timings to be reset, got %+v", tm)
+	}
+
+	if _, err := Generate(nil, WithTiming(nil)); err == nil {
+		t.Error("Expected an error for nil timings")
+	}
+}
+
+func TestTimingLogged(t *testing.T) {
+	h := &captureHandler{level: slog.LevelDebug}
+	g, err := NewGenerator(nil, WithLogger(slog.New(h)))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if _, err := g.Generate([]byte("test@example.com")); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if _, ok := h.records()["render timings"]; ok {
+		t.Error("Expected no timings without WithTiming")
+	}
+
+	var tm Timings
+	if _, err := g.Generate([]byte("test@example.com"), WithTiming(&tm)); err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if keys := h.records()["render timings"]; len(keys) != 12 {
+		t.Errorf("Expected the hash prefix and 11 durations, got %v", keys)
+	}
+}
+
+func TestTimingNoAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items at random under the race detector")
+	}
+	s := Describe([]byte("test@example.com"))
+	o := defaultOptions()
+	base := testing.AllocsPerRun(20, func() { generate(s, o) })
+
+	timed := defaultOptions()
+	timed.timings = new(Timings)
+	if allocs := testing.AllocsPerRun(20, func() { generate(s, timed) }); allocs != base {
+		t.Errorf("Expected timing to allocate nothing, got %v allocations against %v", allocs, base)
+	}
+
+	// The render reads no clock without WithTiming
+	if !o.timingStart.IsZero() {
+		t.Error("Expected an untimed render to leave its clock unset")
+	}
+}
@@ -0,0 +1,34 @@
+//go:build tinygo
+
+package wavatar
+
+// Smoke test for TinyGo builds, run with `tinygo test .`
+//
+// Parts are decoded from the embedded archive on first use and cached, each
+// taking 25 KB plus a cropped premultiplied copy for compositing, so the
+// cache grows to about 3 MB once all 77 parts have been used, as
+// Generator.MemoryUsage reports after PreloadAll. Call PreloadAll at startup
+// to pay that cost before serving. On top of that a render needs the 25 KB
+// canvas and about 40 KB of flate state while a part is decoded. Each render
+// allocates well under 1 MiB in total, see TestRenderMemory. Generate and
+// GenerateFromSpec return errors instead of panicking; only New and
+// NewFromSpec panic, and only if the embedded parts are corrupt.
+//
+// There is deliberately no reduced part set: dropping parts would change the
+// Spec ranges and with them every avatar, so devices would no longer match
+// the server. The embedded archive adds about 70 KB to the binary.
+
+import (
+	"image"
+	"testing"
+)
+
+func TestTinyGoSmoke(t *testing.T) {
+	img, err := Generate([]byte("test@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if img.Bounds() != image.Rect(0, 0, AvatarSize, AvatarSize) {
+		t.Errorf("Expected %dx%d bounds, got %v", AvatarSize, AvatarSize, img.Bounds())
+	}
+}
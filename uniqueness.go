@@ -0,0 +1,162 @@
+package wavatar
+
+import (
+	"image/color"
+	"math"
+	"slices"
+)
+
+// Weights of the layers and colors in Similarity. The face, eyes and mouth
+// shape an avatar most, so together they outweigh everything else.
+const (
+	similarityFace       = 0.3
+	similarityEyes       = 0.15
+	similarityMouth      = 0.15
+	similarityBackground = 0.15
+	similarityWave       = 0.15
+	similarityBrow       = 0.04
+	similarityPupils     = 0.03
+	similarityFade       = 0.03
+)
+
+// similarityColorRange is the RGB distance at which two colors stop looking alike
+const similarityColorRange = 64
+
+// UniquenessBucketed is the threshold above which UniquenessReport finds
+// every similar pair. Avatars that differ in their face, eyes or mouth are
+// never more similar than this, so only avatars sharing all three are compared.
+const UniquenessBucketed = 1 - min(similarityFace, similarityEyes, similarityMouth)
+
+// Similarity returns how alike the avatars of a and b look, from 0 for
+// nothing in common to 1 for the same parts in the same colors. Parts count
+// when they are the same part and colors by how close they are. Specs from
+// Resolve compare by the colors they draw, others by the colors of their
+// hues with the default options.
+func Similarity(a, b Spec) float64 {
+	return similarity(defaultOptions(), a, b)
+}
+
+// similarity is Similarity with the colors of o for specs without their own
+func similarity(o *options, a, b Spec) float64 {
+	same := func(x, y int, weight float64) float64 {
+		if x == y {
+			return weight
+		}
+		return 0
+	}
+	return same(a.Face, b.Face, similarityFace) +
+		same(a.Eyes, b.Eyes, similarityEyes) +
+		same(a.Mouth, b.Mouth, similarityMouth) +
+		same(a.Brow, b.Brow, similarityBrow) +
+		same(a.Pupil, b.Pupil, similarityPupils) +
+		same(a.Fade, b.Fade, similarityFade) +
+		similarityBackground*colorSimilarity(o.backgroundColor(a), o.backgroundColor(b)) +
+		similarityWave*colorSimilarity(o.waveColor(a), o.waveColor(b))
+}
+
+// colorSimilarity is 1 for equal colors, falling to 0 at similarityColorRange apart
+func colorSimilarity(a, b color.RGBA) float64 {
+	dr, dg, db := float64(a.R)-float64(b.R), float64(a.G)-float64(b.G), float64(a.B)-float64(b.B)
+	return max(0, 1-math.Sqrt(dr*dr+dg*dg+db*db)/similarityColorRange)
+}
+
+// Mitigation is a change that would tell apart the avatars UniquenessReport
+// found alike, named like the JSON config field of its option
+type Mitigation string
+
+const (
+	// MitigationPreciseColors means similar avatars have different hues that
+	// the integer colors render alike, see WithPreciseColors
+	MitigationPreciseColors Mitigation = "precise_colors"
+	// MitigationInitialsMouth means similar avatars remain, which the initials
+	// of each user in place of the mouth would tell apart, see WithInitialsMouth
+	MitigationInitialsMouth Mitigation = "initials_mouth"
+)
+
+// Report lists the inputs of UniquenessReport whose avatars look alike
+type Report struct {
+	// Clusters holds the indices of inputs linked by similar pairs, each
+	// cluster and the clusters themselves in input order
+	Clusters [][]int
+	// Pairs is the number of pairs at or above the threshold
+	Pairs int
+	// Mitigations suggests changes that would separate the clusters
+	Mitigations []Mitigation
+}
+
+// UniquenessReport finds the inputs whose default avatars have a Similarity
+// of at least threshold and groups them into clusters. Only avatars sharing
+// their face, eyes and mouth are compared, which keeps large input sets fast
+// and finds every pair for a threshold above UniquenessBucketed.
+func UniquenessReport(inputs [][]byte, threshold float64) Report {
+	specs := make([]Spec, len(inputs))
+	for i, input := range inputs {
+		specs[i] = Describe(input)
+	}
+	return uniqueness(specs, threshold)
+}
+
+// uniqueness clusters the specs that are at least threshold similar
+func uniqueness(specs []Spec, threshold float64) Report {
+	type key struct{ face, eyes, mouth int }
+	buckets := make(map[key][]int)
+	for i, s := range specs {
+		k := key{s.Face, s.Eyes, s.Mouth}
+		buckets[k] = append(buckets[k], i)
+	}
+
+	parent := make([]int, len(specs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	o := defaultOptions()
+	var report Report
+	preciseHelps := false
+	for _, bucket := range buckets {
+		for x, i := range bucket {
+			for _, j := range bucket[x+1:] {
+				a, b := specs[i], specs[j]
+				if similarity(o, a, b) < threshold {
+					continue
+				}
+				report.Pairs++
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[max(ri, rj)] = min(ri, rj)
+				}
+				// Different hues drawn in one color are what precise colors separate
+				sameColors := o.backgroundColor(a) == o.backgroundColor(b) && o.waveColor(a) == o.waveColor(b)
+				if sameColors && (a.Background != b.Background || a.WaveColor != b.WaveColor) {
+					preciseHelps = true
+				}
+			}
+		}
+	}
+
+	// Every root is the first input of its cluster, so clusters fill in input order
+	clusters := make(map[int][]int)
+	for i := range specs {
+		clusters[find(i)] = append(clusters[find(i)], i)
+	}
+	for _, c := range clusters {
+		if len(c) > 1 {
+			report.Clusters = append(report.Clusters, c)
+		}
+	}
+	slices.SortFunc(report.Clusters, func(a, b []int) int { return a[0] - b[0] })
+
+	if preciseHelps {
+		report.Mitigations = append(report.Mitigations, MitigationPreciseColors)
+	}
+	if len(report.Clusters) > 0 {
+		report.Mitigations = append(report.Mitigations, MitigationInitialsMouth)
+	}
+	return report
+}
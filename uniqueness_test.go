@@ -0,0 +1,151 @@
+package wavatar
+
+import (
+	"fmt"
+	"image/color"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	s := Describe([]byte("test@example.com"))
+	if got := Similarity(s, s); got < 0.999999 {
+		t.Errorf("Expected 1 for identical specs, got %v", got)
+	}
+
+	other := s
+	other.Face = s.Face%FaceCount + 1
+	if got := Similarity(s, other); got > UniquenessBucketed {
+		t.Errorf("Expected at most %v for another face, got %v", UniquenessBucketed, got)
+	}
+
+	// Everything different, colors far apart
+	far := Spec{
+		Face: s.Face%FaceCount + 1, Eyes: s.Eyes%EyeCount + 1, Mouth: s.Mouth%MouthCount + 1,
+		Brow: s.Brow%BrowCount + 1, Pupil: s.Pupil%PupilCount + 1, Fade: s.Fade%BgCount + 1,
+		BackgroundRGBA: color.RGBA{255, 255, 255, 255}, WaveRGBA: color.RGBA{255, 255, 255, 255},
+	}
+	near := s
+	near.BackgroundRGBA, near.WaveRGBA = color.RGBA{1, 1, 1, 255}, color.RGBA{1, 1, 1, 255}
+	if got := Similarity(near, far); got != 0 {
+		t.Errorf("Expected 0 for specs with nothing in common, got %v", got)
+	}
+
+	if a, b := Similarity(s, other), Similarity(other, s); a != b {
+		t.Errorf("Expected a symmetric similarity, got %v and %v", a, b)
+	}
+}
+
+func TestUniquenessPlantedDuplicates(t *testing.T) {
+	base := Spec{Face: 3, Background: 50, Fade: 2, WaveColor: 150, Brow: 4, Eyes: 7, Pupil: 5, Mouth: 11}
+	// The same parts with colors a few steps apart
+	nearBase := base
+	nearBase.BackgroundRGBA = color.RGBA{10, 200, 30, 255}
+	nearBase.WaveRGBA = color.RGBA{40, 60, 220, 255}
+	planted := nearBase
+	planted.BackgroundRGBA.R += 6
+	planted.WaveRGBA.B -= 5
+	// The same face, eyes and mouth in other colors and details
+	apart := base
+	apart.Brow, apart.Pupil, apart.Fade = 1, 1, 1
+	apart.BackgroundRGBA = color.RGBA{250, 20, 20, 255}
+	apart.WaveRGBA = color.RGBA{250, 250, 20, 255}
+	// Another face in the same colors
+	otherFace := planted
+	otherFace.Face = 9
+
+	specs := []Spec{apart, nearBase, otherFace, planted}
+	report := uniqueness(specs, 0.9)
+	if want := [][]int{{1, 3}}; !slices.EqualFunc(report.Clusters, want, slices.Equal) {
+		t.Errorf("Expected clusters %v, got %v", want, report.Clusters)
+	}
+	if report.Pairs != 1 {
+		t.Errorf("Expected 1 pair, got %d", report.Pairs)
+	}
+	if want := []Mitigation{MitigationInitialsMouth}; !slices.Equal(report.Mitigations, want) {
+		t.Errorf("Expected mitigations %v, got %v", want, report.Mitigations)
+	}
+
+	// A chain of pairs joins into one cluster
+	chained := planted
+	chained.BackgroundRGBA.R += 6
+	report = uniqueness(append(specs, chained), 0.9)
+	if want := [][]int{{1, 3, 4}}; !slices.EqualFunc(report.Clusters, want, slices.Equal) {
+		t.Errorf("Expected clusters %v, got %v", want, report.Clusters)
+	}
+}
+
+func TestUniquenessPreciseColors(t *testing.T) {
+	// Integer division maps hues 1 to 39 onto the same red
+	a := Spec{Face: 1, Background: 5, Fade: 1, WaveColor: 100, Brow: 1, Eyes: 1, Pupil: 1, Mouth: 1}
+	b := a
+	b.Background = 30
+	o := defaultOptions()
+	if o.backgroundColor(a) != o.backgroundColor(b) {
+		t.Fatalf("Expected hues %d and %d to share a color", a.Background, b.Background)
+	}
+
+	report := uniqueness([]Spec{a, b}, 0.99)
+	if want := []Mitigation{MitigationPreciseColors, MitigationInitialsMouth}; !slices.Equal(report.Mitigations, want) {
+		t.Errorf("Expected mitigations %v, got %v", want, report.Mitigations)
+	}
+
+	// Identical hues gain nothing from precise colors
+	report = uniqueness([]Spec{a, a}, 0.99)
+	if slices.Contains(report.Mitigations, MitigationPreciseColors) {
+		t.Errorf("Expected no %s for identical hues, got %v", MitigationPreciseColors, report.Mitigations)
+	}
+}
+
+func TestUniquenessReportEmpty(t *testing.T) {
+	report := UniquenessReport(nil, 0.9)
+	if len(report.Clusters) != 0 || report.Pairs != 0 || len(report.Mitigations) != 0 {
+		t.Errorf("Expected an empty report, got %+v", report)
+	}
+}
+
+func TestUniquenessReportPINs(t *testing.T) {
+	inputs := make([][]byte, 10000)
+	for i := range inputs {
+		inputs[i] = []byte(fmt.Sprintf("%04d", i))
+	}
+	start := time.Now()
+	report := UniquenessReport(inputs, UniquenessBucketed)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected 10000 inputs to take well under 5s, got %v", elapsed)
+	}
+
+	seen := make(map[int]bool)
+	for _, c := range report.Clusters {
+		if !slices.IsSorted(c) || len(c) < 2 {
+			t.Fatalf("Expected sorted clusters of at least 2, got %v", c)
+		}
+		a := Describe(inputs[c[0]])
+		for _, i := range c {
+			if seen[i] {
+				t.Fatalf("Expected input %d in one cluster only", i)
+			}
+			seen[i] = true
+			// Pairs above UniquenessBucketed share their face, eyes and mouth
+			if b := Describe(inputs[i]); a.Face != b.Face || a.Eyes != b.Eyes || a.Mouth != b.Mouth {
+				t.Errorf("Expected cluster %v to share face, eyes and mouth", c)
+				break
+			}
+		}
+	}
+	if report.Pairs < len(seen)-len(report.Clusters) {
+		t.Errorf("Expected at least %d pairs to link %d clusters, got %d", len(seen)-len(report.Clusters), len(report.Clusters), report.Pairs)
+	}
+}
+
+func BenchmarkUniquenessReport(b *testing.B) {
+	inputs := make([][]byte, 100000)
+	for i := range inputs {
+		inputs[i] = []byte(fmt.Sprintf("%05d", i))
+	}
+	b.ResetTimer()
+	for range b.N {
+		UniquenessReport(inputs, UniquenessBucketed)
+	}
+}
@@ -0,0 +1,56 @@
+package wavatar
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OptionsError lists every problem with a set of options: the options that
+// rejected their own arguments, in order, followed by the combinations of
+// options that cannot be honored together
+type OptionsError struct {
+	Errs []error
+}
+
+func (e *OptionsError) Error() string {
+	return errors.Join(e.Errs...).Error()
+}
+
+// Unwrap returns the individual problems, for errors.Is and errors.As
+func (e *OptionsError) Unwrap() []error {
+	return e.Errs
+}
+
+// optionConflicts lists pairs of options where one would silently undo the other
+var optionConflicts = []struct {
+	first, second string
+	reason        string
+	applies       func(o *options) bool
+}{
+	{"WithSticker", "WithFaceCrop", "both crop the avatar", func(o *options) bool {
+		return o.sticker != nil && o.faceCrop
+	}},
+	{"WithSticker", "WithBackgroundFunc", "a sticker has no background", func(o *options) bool {
+		return o.sticker != nil && o.background != nil
+	}},
+	{"WithTransparentBackground", "WithBackgroundFunc", "the background is left out", func(o *options) bool {
+		return o.transparent && o.background != nil
+	}},
+	{"WithLineArt", "WithAlphaFill", "line art leaves the face unfilled", func(o *options) bool {
+		return o.lineArt != nil && o.alphaFill
+	}},
+	{"WithLineArt", "WithBackgroundFunc", "line art is drawn on white", func(o *options) bool {
+		return o.lineArt != nil && o.background != nil
+	}},
+}
+
+// conflicts reports every pair of options in o that cannot be honored together
+func (o *options) conflicts() []error {
+	var errs []error
+	for _, c := range optionConflicts {
+		if c.applies(o) {
+			errs = append(errs, fmt.Errorf("wavatar: %s conflicts with %s, %s", c.first, c.second, c.reason))
+		}
+	}
+	return errs
+}
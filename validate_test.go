@@ -0,0 +1,129 @@
+package wavatar
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+func TestOptionRules(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  Option
+		want string
+	}{
+		{"nil option", nil, "option 0 is nil"},
+		{"saturation", WithSaturation(-1, 240), "saturation -1/240"},
+		{"lightness", WithLightness(50, 241), "lightness 50/241"},
+		{"hue range", WithHueRange(HueRange{Min: 50, Max: 10}), "hue range 50-10"},
+		{"version", WithVersion(9), "version 9"},
+		{"seasonal", WithSeasonal(99), "seasonal kind 99"},
+		{"outline", WithFeatureOutline(-2), "got -2"},
+		{"fill connectivity", WithFillConnectivity(6), "got 6"},
+		{"shine", WithShineIntensity(2.5), "shine intensity 2.5"},
+		{"optional layer", WithOptionalLayer(LayerBrow, 1.5), "probability 1.5"},
+		{"face crop", WithFaceCrop(-1), "got -1"},
+		{"sticker", WithSticker(0, color.Black), "got 0"},
+		{"dithering", WithDithering(7), "dithering mode 7"},
+		{"count policy", WithCountPolicy(9), "count policy 9"},
+		{"render cache", WithRenderCache(0), "render cache size 0"},
+		{"memory budget", WithMemoryBudget(-5), "memory budget -5"},
+		{"encode concurrency", WithEncodeConcurrency(0), "concurrency 0"},
+		{"colorblind", WithColorblindSafe(42), "deficiency 42"},
+		{"size", WithSize(4), "size 4"},
+		{"logger", WithLogger(nil), "logger is nil"},
+		{"slow render", WithSlowRenderThreshold(0), "threshold 0s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate([]byte("test@example.com"), tt.opt)
+			var oerr *OptionsError
+			if !errors.As(err, &oerr) {
+				t.Fatalf("Expected an *OptionsError, got %v", err)
+			}
+			if len(oerr.Errs) != 1 || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected one error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOptionConflicts(t *testing.T) {
+	bg := func(*image.RGBA, uint64) {}
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{"sticker and face crop", []Option{WithSticker(2, color.White), WithFaceCrop(0)}, "WithSticker conflicts with WithFaceCrop"},
+		{"face crop and sticker", []Option{WithFaceCrop(0), WithSticker(2, color.White)}, "WithSticker conflicts with WithFaceCrop"},
+		{"sticker and background", []Option{WithSticker(2, color.White), WithBackgroundFunc(bg)}, "WithSticker conflicts with WithBackgroundFunc"},
+		{"transparent and background", []Option{WithTransparentBackground(), WithBackgroundFunc(bg)}, "WithTransparentBackground conflicts with WithBackgroundFunc"},
+		{"transparent and sticker", []Option{WithTransparentBackground(), WithSticker(2, color.White)}, ""},
+		{"line art and alpha fill", []Option{WithLineArt(), WithAlphaFill()}, "WithLineArt conflicts with WithAlphaFill"},
+		{"threshold and alpha fill", []Option{WithAlphaFill(), WithLineArtThreshold(128)}, "WithLineArt conflicts with WithAlphaFill"},
+		{"line art and background", []Option{WithLineArt(), WithBackgroundFunc(bg)}, "WithLineArt conflicts with WithBackgroundFunc"},
+		{"sticker and line art", []Option{WithSticker(2, color.White), WithLineArt()}, ""},
+		{"face crop and line art", []Option{WithFaceCrop(2), WithLineArt()}, ""},
+		{"alpha fill and background", []Option{WithAlphaFill(), WithBackgroundFunc(bg)}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Generate([]byte("test@example.com"), tt.opts...)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestOptionErrorsAggregated(t *testing.T) {
+	_, err := Generate([]byte("test@example.com"),
+		WithShineIntensity(-1),
+		WithVersion(0),
+		WithSticker(1, color.White),
+		WithFaceCrop(3),
+	)
+	var oerr *OptionsError
+	if !errors.As(err, &oerr) {
+		t.Fatalf("Expected an *OptionsError, got %v", err)
+	}
+	if len(oerr.Errs) != 3 {
+		t.Fatalf("Expected 3 errors, got %d: %v", len(oerr.Errs), err)
+	}
+	for i, want := range []string{"shine intensity -1", "unknown version 0", "WithFaceCrop"} {
+		if !strings.Contains(oerr.Errs[i].Error(), want) {
+			t.Errorf("Expected error %d to contain %q, got %v", i, want, oerr.Errs[i])
+		}
+	}
+	if got := strings.Count(err.Error(), "\n"); got != 2 {
+		t.Errorf("Expected one line per error, got %d lines", got+1)
+	}
+}
+
+func TestOptionValidationGenerator(t *testing.T) {
+	if _, err := NewGenerator(nil, WithLineArt(), WithAlphaFill(), WithRenderCache(-1)); err == nil {
+		t.Fatal("Expected an error constructing a Generator with invalid options")
+	} else if !strings.Contains(err.Error(), "WithAlphaFill") || !strings.Contains(err.Error(), "render cache size -1") {
+		t.Errorf("Expected both problems reported, got %v", err)
+	}
+
+	// Conflicts between the options of a Generator and of a call are caught at the merge
+	g, err := NewGenerator(nil, WithSticker(2, color.White))
+	if err != nil {
+		t.Fatalf("Failed to create generator: %v", err)
+	}
+	if _, err := g.Generate([]byte("test@example.com"), WithFaceCrop(0)); err == nil || !strings.Contains(err.Error(), "WithFaceCrop") {
+		t.Errorf("Expected the merged options to conflict, got %v", err)
+	}
+}
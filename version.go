@@ -0,0 +1,87 @@
+package wavatar
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+)
+
+// Version selects the algorithm that turns a hash into a Spec.
+//
+// V1 draws every selection in turn from a single stream, so adding a draw
+// anywhere shifts every later layer and changes existing avatars. V2 gives
+// each layer its own stream keyed by the layer name, so layers added in later
+// releases leave the existing ones untouched.
+//
+// V3 selects parts like V2 and computes the background, wave and outline
+// colors with the standard floating point HSL conversion of WithPreciseColors
+// instead of the integer arithmetic of V1 and V2, which rounds many hues to
+// one color. WithIntegerColors keeps the old colors under V3.
+//
+// V1 stays the default and keeps rendering every hash exactly as before.
+// Moving to a later version changes each avatar once; pin the version with
+// WithVersion so users see the switch at a time of your choosing, not on upgrade.
+type Version int
+
+const (
+	V1 Version = iota + 1
+	V2
+	V3
+)
+
+// WithVersion selects the algorithm Generate uses to describe a hash
+func WithVersion(v Version) Option {
+	return func(o *options) error {
+		if v < V1 || v > V3 {
+			return fmt.Errorf("wavatar: unknown version %d", v)
+		}
+		o.version = v
+		return nil
+	}
+}
+
+// DescribeVersion returns the Spec that version v selects for hash
+func DescribeVersion(hash []byte, v Version) (Spec, error) {
+	return describeVersion(hash, v, defaultCounts)
+}
+
+// describeVersion returns the Spec that version v selects for hash among c parts per layer
+func describeVersion(hash []byte, v Version, c layerCounts) (Spec, error) {
+	switch v {
+	case V1:
+		return describeV1(hash, c), nil
+	case V2, V3:
+		return describeV2(hash, c), nil
+	default:
+		return Spec{}, fmt.Errorf("wavatar: unknown version %d", v)
+	}
+}
+
+// describeV2 selects every field of the Spec from its own stream among c parts per layer
+func describeV2(hash []byte, c layerCounts) Spec {
+	seed := hashSeed(hash)
+	return Spec{
+		Face:       layerRand(seed, "face").IntN(c[LayerFace]) + 1,
+		Background: layerRand(seed, "background").IntN(240) + 1,
+		Fade:       layerRand(seed, "fade").IntN(c[LayerFade]) + 1,
+		WaveColor:  layerRand(seed, "wave").IntN(240) + 1,
+		Brow:       layerRand(seed, "brow").IntN(c[LayerBrow]) + 1,
+		Eyes:       layerRand(seed, "eyes").IntN(c[LayerEyes]) + 1,
+		Pupil:      layerRand(seed, "pupils").IntN(c[LayerPupils]) + 1,
+		Mouth:      layerRand(seed, "mouth").IntN(c[LayerMouth]) + 1,
+	}
+}
+
+// hashSeed returns the fnv-1a hash every version seeds its streams from
+func hashSeed(hash []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(hash)
+	return h.Sum64()
+}
+
+// layerRand returns the stream for the layer called name, independent of every other layer
+func layerRand(seed uint64, name string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return rand.New(rand.NewPCG(seed, h.Sum64()))
+}
@@ -0,0 +1,101 @@
+package wavatar
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+// TestVersionGolden pins V2, V1 is pinned by the default goldens
+func TestVersionGolden(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		hash []byte
+	}{
+		{"email", []byte("test@example.com")},
+		{"user1", []byte("user1@example.com")},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Generate(tt.hash, WithVersion(V2))
+			if err != nil {
+				t.Fatalf("Failed to generate avatar: %v", err)
+			}
+			checkGolden(t, "v2-"+tt.name, img)
+		})
+	}
+}
+
+func TestVersionOneIsDefault(t *testing.T) {
+	hash := []byte("test@example.com")
+	img, err := Generate(hash, WithVersion(V1))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	if !bytes.Equal(img.(*image.RGBA).Pix, New(hash).(*image.RGBA).Pix) {
+		t.Error("Expected V1 to reproduce New")
+	}
+}
+
+func TestVersionTwoLayersAreIndependent(t *testing.T) {
+	hash := []byte("test@example.com")
+	s, err := DescribeVersion(hash, V2)
+	if err != nil {
+		t.Fatalf("Failed to describe: %v", err)
+	}
+	if err := s.Validate(); err != nil {
+		t.Errorf("Expected a valid spec, got %v", err)
+	}
+
+	// Each layer only depends on the seed and its own name, so adding a
+	// layer or reordering the draws cannot change it
+	mouth := layerRand(hashSeed(hash), "mouth").IntN(MouthCount) + 1
+	if s.Mouth != mouth {
+		t.Errorf("Expected mouth %d from its own stream, got %d", mouth, s.Mouth)
+	}
+
+	if _, err := DescribeVersion(hash, Version(9)); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+	if _, err := Generate(hash, WithVersion(0)); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+}
+
+func TestVersionThree(t *testing.T) {
+	hash := []byte("test@example.com")
+	render := func(opts ...Option) []byte {
+		t.Helper()
+		img, err := Generate(hash, opts...)
+		if err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+		return img.(*image.RGBA).Pix
+	}
+
+	// V3 selects like V2 and colors precisely
+	v3 := render(WithVersion(V3))
+	if !bytes.Equal(v3, render(WithVersion(V2), WithPreciseColors())) {
+		t.Error("Expected V3 to render like V2 with precise colors")
+	}
+	if bytes.Equal(v3, render(WithVersion(V2))) {
+		t.Error("Expected V3 to change the colors of V2")
+	}
+	// The integer colors stay reachable
+	if !bytes.Equal(render(WithVersion(V3), WithIntegerColors()), render(WithVersion(V2))) {
+		t.Error("Expected V3 with integer colors to render like V2")
+	}
+	if !bytes.Equal(render(WithVersion(V3), WithLegacyColors()), render(WithVersion(V2), WithLegacyColors())) {
+		t.Error("Expected legacy colors to apply under V3")
+	}
+	if !bytes.Equal(render(WithIntegerColors()), render()) {
+		t.Error("Expected integer colors to be the default of V1")
+	}
+
+	s, err := DescribeVersion(hash, V3)
+	if err != nil {
+		t.Fatalf("Failed to describe: %v", err)
+	}
+	if v2, _ := DescribeVersion(hash, V2); s != v2 {
+		t.Errorf("Expected V3 to describe %v like V2, got %v", v2, s)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build js && wasm
+
+// Command example registers wavatarGenerate and keeps the Go runtime alive for JS callers
+package main
+
+import "github.com/weavatar/wavatar/wasm"
+
+func main() {
+	wasm.Register()
+	select {}
+}
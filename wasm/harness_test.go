@@ -0,0 +1,40 @@
+//go:build wasmharness
+
+package wasm
+
+// Runs the JS harness against a real js/wasm build, with
+// `go test -tags wasmharness ./wasm`. Needs node on the PATH.
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestJSHarness(t *testing.T) {
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found")
+	}
+
+	// wasm_exec.js moved from misc/wasm to lib/wasm in Go 1.24
+	wasmExec := filepath.Join(runtime.GOROOT(), "lib", "wasm", "wasm_exec.js")
+	if _, err := os.Stat(wasmExec); err != nil {
+		wasmExec = filepath.Join(runtime.GOROOT(), "misc", "wasm", "wasm_exec.js")
+	}
+
+	module := filepath.Join(t.TempDir(), "wavatar.wasm")
+	build := exec.Command("go", "build", "-o", module, "./example")
+	build.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	if out, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build wasm module: %v\n%s", err, out)
+	}
+
+	out, err := exec.Command(node, filepath.Join("testdata", "harness.js"), wasmExec, module).CombinedOutput()
+	if err != nil || strings.TrimSpace(string(out)) != "ok" {
+		t.Fatalf("Harness failed: %v\n%s", err, out)
+	}
+}
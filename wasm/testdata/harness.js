@@ -0,0 +1,44 @@
+// Usage: node harness.js <wasm_exec.js> <module.wasm>
+"use strict";
+
+const assert = require("node:assert");
+const fs = require("node:fs");
+
+require(process.argv[2]);
+
+const PNG_SIGNATURE = [0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a];
+
+function pngSize(data) {
+	const view = new DataView(data.buffer, data.byteOffset, data.byteLength);
+	return [view.getUint32(16), view.getUint32(20)];
+}
+
+async function main() {
+	const go = new Go();
+	const { instance } = await WebAssembly.instantiate(fs.readFileSync(process.argv[3]), go.importObject);
+	go.run(instance);
+
+	const native = wavatarGenerate("test@example.com", 0);
+	assert.ok(native instanceof Uint8Array, "expected a Uint8Array");
+	assert.deepStrictEqual([...native.subarray(0, 8)], PNG_SIGNATURE);
+	assert.deepStrictEqual(pngSize(native), [80, 80]);
+
+	const bytes = wavatarGenerate(new TextEncoder().encode("test@example.com"), 0);
+	assert.deepStrictEqual(bytes, native, "expected string and bytes input to match");
+
+	assert.deepStrictEqual(pngSize(wavatarGenerate("test@example.com", 160)), [160, 160]);
+
+	assert.throws(() => wavatarGenerate("test@example.com", -1), /out of range/);
+	assert.throws(() => wavatarGenerate("test@example.com", 1.5), /out of range/);
+	assert.throws(() => wavatarGenerate("test@example.com", 4097), /out of range/);
+	assert.throws(() => wavatarGenerate(42, 0), /string or Uint8Array/);
+	assert.throws(() => wavatarGenerate("test@example.com"), /size must be a number/);
+
+	console.log("ok");
+	process.exit(0);
+}
+
+main().catch((err) => {
+	console.error(err);
+	process.exit(1);
+});
@@ -0,0 +1,94 @@
+//go:build js && wasm
+
+// Package wasm exposes wavatar to JavaScript when built with GOOS=js GOARCH=wasm,
+// so browser previews come from the same code as the server
+package wasm
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/png"
+	"syscall/js"
+
+	"github.com/weavatar/wavatar"
+)
+
+// Register installs wavatarGenerate(input, size) on the global object.
+//
+// input is a string or a Uint8Array, size is the edge length in pixels up to
+// wavatar.MaxSize, or 0 for the native size. Sizes are scaled the way
+// wavatar.Thumbnails scales them, so previews match the server. The function returns the PNG as a Uint8Array and
+// throws an Error on invalid arguments. Keep the Go program running after
+// Register, for example with select {}.
+func Register() {
+	// Go callbacks cannot throw, so a small JS wrapper turns a returned Error into an exception
+	wrap := js.Global().Get("Function").New("fn", `return function wavatarGenerate(input, size) {
+	const result = fn(input, size);
+	if (result instanceof Error) {
+		throw result;
+	}
+	return result;
+}`)
+	js.Global().Set("wavatarGenerate", wrap.Invoke(js.FuncOf(generate)))
+}
+
+// generate is the Go side of wavatarGenerate, returning either the PNG or an Error
+func generate(_ js.Value, args []js.Value) any {
+	data, err := encode(args)
+	if err != nil {
+		return js.Global().Get("Error").New(err.Error())
+	}
+
+	// The bytes have to leave wasm memory, which may move when it grows,
+	// so a single copy into a fresh Uint8Array is the least we can do
+	out := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(out, data)
+	return out
+}
+
+// encode validates the JS arguments and renders the avatar they describe as PNG
+func encode(args []js.Value) ([]byte, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wavatar: expected (input, size), got %d arguments", len(args))
+	}
+
+	var input []byte
+	switch in := args[0]; {
+	case in.Type() == js.TypeString:
+		input = []byte(in.String())
+	case in.InstanceOf(js.Global().Get("Uint8Array")):
+		input = make([]byte, in.Length())
+		js.CopyBytesToGo(input, in)
+	default:
+		return nil, fmt.Errorf("wavatar: input must be a string or Uint8Array, got %s", in.Type())
+	}
+
+	if args[1].Type() != js.TypeNumber {
+		return nil, fmt.Errorf("wavatar: size must be a number, got %s", args[1].Type())
+	}
+	size := args[1].Int()
+	if size < 0 || size > wavatar.MaxSize || float64(size) != args[1].Float() {
+		return nil, fmt.Errorf("wavatar: size %v out of range 0-%d", args[1].Float(), wavatar.MaxSize)
+	}
+
+	var img image.Image
+	if size == 0 {
+		var err error
+		if img, err = wavatar.Generate(input); err != nil {
+			return nil, err
+		}
+	} else {
+		thumbs, err := wavatar.Thumbnails(input, []int{size})
+		if err != nil {
+			return nil, err
+		}
+		img = thumbs[size]
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,20 +1,14 @@
 package wavatar
 
 import (
-	"embed"
-	"fmt"
-	"hash/fnv"
+	"crypto/md5"
 	"image"
 	"image/color"
 	"image/draw"
-	"image/png"
-	"math/rand/v2"
-	"path"
+	"strings"
+	"sync"
 )
 
-//go:embed all:parts/*
-var parts embed.FS
-
 const (
 	AvatarSize = 80
 	BgCount    = 4
@@ -26,68 +20,176 @@ const (
 )
 
 // New creates a new Wavatar from a hash (typically an MD5 hash of an email)
+// with the default Generator, see SetDefault. It panics if rendering fails,
+// which only happens when the parts are missing or broken; Generate returns
+// the error instead, a *PartError for a broken part.
 func New(hash []byte) image.Image {
-	h := fnv.New64a()
-	if _, err := h.Write(hash); err != nil {
+	img, err := Default().Generate(hash)
+	if err != nil {
 		panic(err)
 	}
+	return img
+}
 
-	r := rand.New(rand.NewPCG(h.Sum64(), (h.Sum64()>>1)|1))
-	face := r.IntN(FaceCount) + 1
-	bgColor := r.IntN(240) + 1
-	fade := r.IntN(BgCount) + 1
-	wavColor := r.IntN(240) + 1
-	brow := r.IntN(BrowCount) + 1
-	eyes := r.IntN(EyeCount) + 1
-	pupil := r.IntN(PupilCount) + 1
-	mouth := r.IntN(MouthCount) + 1
+// NewFromString creates a new Wavatar for an email address the way Gravatar
+// hashes it: surrounding whitespace is trimmed, the rest lowercased and the
+// MD5 of the result passed to New. An empty address gets a stable avatar too.
+func NewFromString(s string) image.Image {
+	hash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(s))))
+	return New(hash[:])
+}
 
-	// Create background
-	img := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+// render composites all layers of s onto a new image
+func render(s Spec, o *options) (*image.RGBA, error) {
+	var img *image.RGBA
+	var err error
+	if o.lineArt != nil {
+		img, err = renderLineArt(s, o)
+	} else {
+		img, err = renderFace(s, o)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := o.canceled(); err != nil {
+		return nil, err
+	}
 
-	// Background color
-	bgRGB := hsl(bgColor, 240, 50)
-	bgCol := color.RGBA{R: uint8(bgRGB[0]), G: uint8(bgRGB[1]), B: uint8(bgRGB[2]), A: 255}
-	draw.Draw(img, img.Bounds(), &image.Uniform{C: bgCol}, image.Point{}, draw.Src)
+	// Apply remaining layers in order, line art has no shine
+	if o.lineArt == nil {
+		if err := drawShine(img, s.Face, o); err != nil {
+			return nil, err
+		}
+		o.lap(stageShine)
+	}
+	if err := o.canceled(); err != nil {
+		return nil, err
+	}
+
+	// Features go on their own layer when they need an outline
+	features := img
+	if o.outline > 0 {
+		features = image.NewRGBA(img.Bounds())
+	}
+	if err := o.parts.applyPresent(features, "brow", s.Brow); err != nil {
+		return nil, err
+	}
+	o.lap(stageBrow)
+	if err := o.parts.applyPresent(features, "eyes", s.Eyes); err != nil {
+		return nil, err
+	}
+	o.lap(stageEyes)
+	if err := o.parts.applyPresent(features, "pupils", s.Pupil); err != nil {
+		return nil, err
+	}
+	o.lap(stagePupils)
+	if o.initials != "" && s.Mouth > 0 {
+		err = drawInitials(features, o.parts, o.initials, o.initialsFace, s.Mouth)
+	} else {
+		err = o.parts.applyPresent(features, "mouth", s.Mouth)
+	}
+	if err != nil {
+		return nil, err
+	}
+	o.lap(stageMouth)
+	if err := o.canceled(); err != nil {
+		return nil, err
+	}
 
-	// Apply fade pattern
-	applyImage(img, "fade", fade)
+	if o.outline > 0 {
+		drawOutline(img, features, o.outline, o.complementColor(s.Background))
+	}
 
-	// Apply mask
-	applyImage(img, "mask", face)
+	drawSeasonal(img, o.seasonal, s.seed())
 
-	// Fill with wave color
-	wavRGB := hsl(wavColor, 240, 170)
-	wavCol := color.RGBA{R: uint8(wavRGB[0]), G: uint8(wavRGB[1]), B: uint8(wavRGB[2]), A: 255}
+	// Filters see the whole composited avatar, before it is cut or scaled
+	for i, filter := range o.filters {
+		if err := o.canceled(); err != nil {
+			return nil, err
+		}
+		if err := runFilter(i, filter, img); err != nil {
+			return nil, err
+		}
+	}
 
-	centerX, centerY := AvatarSize/2, AvatarSize/2
-	floodFill(img, centerX, centerY, wavCol)
+	if o.sticker != nil {
+		img = drawSticker(img, o.sticker)
+	} else if o.faceCrop {
+		if img, err = cropFace(img, s, o); err != nil {
+			return nil, err
+		}
+	}
 
-	// Apply remaining layers in order
-	applyImage(img, "shine", face)
-	applyImage(img, "brow", brow)
-	applyImage(img, "eyes", eyes)
-	applyImage(img, "pupils", pupil)
-	applyImage(img, "mouth", mouth)
+	if o.size > 0 {
+		img = scaleAvatar(img, o.size)
+	}
 
-	return img
+	if o.lineArt != nil && o.lineArt.threshold {
+		thresholdRGBA(img, o.lineArt.cutoff)
+	}
+
+	if o.circle {
+		circleMask(img)
+	}
+	o.lap(stagePostProcess)
+
+	return img, nil
 }
 
-// applyImage loads and applies a PNG part to the base image
-func applyImage(base *image.RGBA, part string, num int) {
-	filename := fmt.Sprintf("%s%d.png", part, num)
-	file, err := parts.Open(path.Join("parts", filename))
+// renderFace draws the background, fade and mask of s and fills the face with the wave color
+func renderFace(s Spec, o *options) (*image.RGBA, error) {
+	img, mask, err := renderUnfilled(s, o)
 	if err != nil {
-		panic(err)
+		return nil, err
+	}
+
+	// Fill with wave color
+	wavCol := o.waveColor(s)
+
+	seed := mask.faceSeed()
+	if o.alphaFill {
+		alphaFill(img, mask.img, seed.X, seed.Y, wavCol, o.fillConnectivity)
+	} else {
+		floodFill(img, seed.X, seed.Y, wavCol, o.fillConnectivity)
+	}
+	o.lap(stageFill)
+
+	return img, nil
+}
+
+// renderUnfilled draws the background, fade and mask of s and returns the mask it drew
+func renderUnfilled(s Spec, o *options) (*image.RGBA, *partEntry, error) {
+	// Create background
+	img := image.NewRGBA(image.Rect(0, 0, AvatarSize, AvatarSize))
+
+	// Stickers and transparent backgrounds keep only the face
+	if o.sticker == nil && !o.transparent {
+		// Background color, or the caller's own background
+		if o.background != nil {
+			o.background(img, s.seed())
+		} else {
+			draw.Draw(img, img.Bounds(), &image.Uniform{C: o.backgroundColor(s)}, image.Point{}, draw.Src)
+		}
+		o.lap(stageBackground)
+
+		// Apply fade pattern, unless it is absent
+		if s.Fade > 0 {
+			if err := o.parts.applyScaled(img, "fade", s.Fade, o.fadeIntensity); err != nil {
+				return nil, nil, err
+			}
+		}
+		o.lap(stageFade)
 	}
-	defer file.Close()
 
-	partImage, err := png.Decode(file)
+	// Apply mask
+	mask, err := o.parts.entry("mask", s.Face)
 	if err != nil {
-		panic(err)
+		return nil, nil, err
 	}
+	mask.premul.over(img)
+	o.lap(stageMask)
 
-	draw.Draw(base, base.Bounds(), partImage, image.Point{}, draw.Over)
+	return img, mask, nil
 }
 
 // hsl converts HSL color values to RGB
@@ -124,9 +226,7 @@ func hsl(h, s, l int) []int {
 		B = (1.0 - (h-200.0)/40.0) * 256.0
 	}
 
-	R = R + (240-s)/240*(128-R)
-	G = G + (240-s)/240*(128-G)
-	B = B + (240-s)/240*(128-B)
+	R, G, B = desaturate(R, s), desaturate(G, s), desaturate(B, s)
 
 	if l < 120 {
 		R = (R / 120) * l
@@ -141,6 +241,14 @@ func hsl(h, s, l int) []int {
 	return []int{clamp(R), clamp(G), clamp(B)}
 }
 
+// desaturate moves channel c toward gray 128 as saturation s falls below
+// 240, like the PHP c + (240-s)/240*(128-c) truncated. Multiplying before
+// dividing keeps the fraction; dividing first, as the old integer port did,
+// made it 0 and ignored every saturation but 0.
+func desaturate(c, s int) int {
+	return c + (240-s)*(128-c)/240
+}
+
 // clamp ensures a value is between 0 and 255
 func clamp(v int) int {
 	if v < 0 {
@@ -151,9 +259,20 @@ func clamp(v int) int {
 	return v
 }
 
-// floodFill performs a flood fill starting at (x,y) with the given color
-func floodFill(img *image.RGBA, x, y int, col color.RGBA) {
-	type point struct{ x, y int }
+// fillSeed is a pixel floodFill starts a span from
+type fillSeed struct{ x, y int }
+
+// fillStacks keep the seed stacks of earlier fills, so a fill allocates
+// nothing once the stacks have grown to the regions filled
+var fillStacks = sync.Pool{New: func() any { return new([]fillSeed) }}
+
+// floodFill performs a flood fill starting at (x,y) with the given color,
+// spreading to the 4 or 8 neighbors of every pixel as connectivity says
+func floodFill(img *image.RGBA, x, y int, col color.RGBA, connectivity int) {
+	bounds := img.Bounds()
+	if !(image.Point{X: x, Y: y}).In(bounds) {
+		return
+	}
 
 	// Get the color at the start point
 	startColor := img.RGBAAt(x, y)
@@ -163,33 +282,54 @@ func floodFill(img *image.RGBA, x, y int, col color.RGBA) {
 		return
 	}
 
-	// Use a queue for breadth-first traversal
-	queue := []point{{x, y}}
-	bounds := img.Bounds()
-
-	for len(queue) > 0 {
-		// Get the next point from the queue
-		p := queue[0]
-		queue = queue[1:]
+	// Diagonal neighbors reach one pixel past either end of a span
+	reach := 0
+	if connectivity == 8 {
+		reach = 1
+	}
 
-		// If this point is outside the bounds or not the start color, skip it
-		if p.x < bounds.Min.X || p.x >= bounds.Max.X ||
-			p.y < bounds.Min.Y || p.y >= bounds.Max.Y {
+	// Every seed starts a run of the start color next to a filled span. A run
+	// filled by the time its seed comes up is skipped, so each pixel is
+	// filled once and examined at most three times.
+	pooled := fillStacks.Get().(*[]fillSeed)
+	stack := append((*pooled)[:0], fillSeed{x, y})
+	defer func() {
+		*pooled = stack
+		fillStacks.Put(pooled)
+	}()
+	for len(stack) > 0 {
+		s := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if img.RGBAAt(s.x, s.y) != startColor {
 			continue
 		}
 
-		currentColor := img.RGBAAt(p.x, p.y)
-		if currentColor != startColor {
-			continue
+		// Fill the whole span of the start color on the row of the seed
+		x1, x2 := s.x, s.x
+		for x1 > bounds.Min.X && img.RGBAAt(x1-1, s.y) == startColor {
+			x1--
+		}
+		for x2 < bounds.Max.X-1 && img.RGBAAt(x2+1, s.y) == startColor {
+			x2++
+		}
+		for i := x1; i <= x2; i++ {
+			img.SetRGBA(i, s.y, col)
 		}
 
-		// Set the color at this point
-		img.SetRGBA(p.x, p.y, col)
-
-		// Add adjacent points to the queue
-		queue = append(queue, point{p.x + 1, p.y})
-		queue = append(queue, point{p.x - 1, p.y})
-		queue = append(queue, point{p.x, p.y + 1})
-		queue = append(queue, point{p.x, p.y - 1})
+		// Seed every run the span touches on the rows above and below
+		lo, hi := max(x1-reach, bounds.Min.X), min(x2+reach, bounds.Max.X-1)
+		for _, ny := range [2]int{s.y - 1, s.y + 1} {
+			if ny < bounds.Min.Y || ny >= bounds.Max.Y {
+				continue
+			}
+			inRun := false
+			for i := lo; i <= hi; i++ {
+				match := img.RGBAAt(i, ny) == startColor
+				if match && !inRun {
+					stack = append(stack, fillSeed{i, ny})
+				}
+				inRun = match
+			}
+		}
 	}
 }
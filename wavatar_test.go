@@ -5,9 +5,55 @@ import (
 	"crypto/md5"
 	"image"
 	"image/png"
+	"path/filepath"
+	"runtime"
 	"testing"
+
+	"github.com/weavatar/wavatar/wavatartest"
 )
 
+// checkGolden compares img with testdata/golden/<name>.png, rewriting it when UPDATE_GOLDEN is set
+func checkGolden(t *testing.T, name string, img image.Image) {
+	t.Helper()
+
+	path := filepath.Join("testdata", "golden", name+".png")
+	wavatartest.SaveGolden(t, path, img)
+	if err := wavatartest.CompareImages(img, wavatartest.LoadGolden(t, path), 0, 0); err != nil {
+		t.Errorf("Golden %s mismatch: %v", name, err)
+	}
+}
+
+// mustRenderFace renders the face of s, failing the test on error
+func mustRenderFace(t *testing.T, s Spec, o *options) *image.RGBA {
+	t.Helper()
+
+	img, err := renderFace(s, o)
+	if err != nil {
+		t.Fatalf("Failed to render face: %v", err)
+	}
+	return img
+}
+
+// mustApplyImage draws a part over img, failing the test on error
+func mustApplyImage(t *testing.T, img *image.RGBA, part string, num int) {
+	t.Helper()
+
+	if err := defaultParts.apply(img, part, num); err != nil {
+		t.Fatalf("Failed to apply %s%d: %v", part, num, err)
+	}
+}
+
+// mustLoadPart decodes a part, failing the test on error
+func mustLoadPart(t *testing.T, part string, num int) image.Image {
+	t.Helper()
+
+	img, err := defaultParts.load(part, num)
+	if err != nil {
+		t.Fatalf("Failed to load %s%d: %v", part, num, err)
+	}
+	return img
+}
+
 func TestNewCreatesCorrectSizeImage(t *testing.T) {
 	hash := []byte("test@example.com")
 	img := New(hash)
@@ -110,6 +156,21 @@ func TestMD5HashInput(t *testing.T) {
 	}
 }
 
+func TestNewFromString(t *testing.T) {
+	hash := md5.Sum([]byte("test@example.com"))
+	want := New(hash[:]).(*image.RGBA)
+	for _, email := range []string{"test@example.com", "  Test@Example.COM\n", "\u00a0test@example.com\u2003"} {
+		if got := NewFromString(email).(*image.RGBA); !bytes.Equal(got.Pix, want.Pix) {
+			t.Errorf("%q: expected the avatar of test@example.com", email)
+		}
+	}
+
+	empty := md5.Sum(nil)
+	if got := NewFromString(" \t").(*image.RGBA); !bytes.Equal(got.Pix, New(empty[:]).(*image.RGBA).Pix) {
+		t.Error("Expected blank input to render the avatar of the empty string")
+	}
+}
+
 func TestImageIsNotEmpty(t *testing.T) {
 	hash := []byte("test@example.com")
 	img := New(hash)
@@ -141,3 +202,43 @@ func TestImageIsNotEmpty(t *testing.T) {
 		t.Error("Generated image appears to be empty")
 	}
 }
+
+func TestGolden(t *testing.T) {
+	md5Hash := md5.Sum([]byte("test@example.com"))
+	tests := []struct {
+		name string
+		hash []byte
+	}{
+		{"email", []byte("test@example.com")},
+		{"md5", md5Hash[:]},
+		{"user1", []byte("user1@example.com")},
+		{"empty", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkGolden(t, "default-"+tt.name, New(tt.hash))
+		})
+	}
+}
+
+// TestRenderMemory guards the per-render allocation budget that small
+// targets such as TinyGo builds rely on
+func TestRenderMemory(t *testing.T) {
+	const renders = 20
+	New([]byte("warm up"))
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	for i := 0; i < renders; i++ {
+		if _, err := Generate([]byte{byte(i)}); err != nil {
+			t.Fatalf("Failed to generate avatar: %v", err)
+		}
+	}
+	runtime.ReadMemStats(&after)
+
+	if perRender := (after.TotalAlloc - before.TotalAlloc) / renders; perRender > 1<<20 {
+		t.Errorf("Expected at most 1 MiB allocated per render, got %d bytes", perRender)
+	}
+}
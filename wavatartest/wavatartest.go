@@ -0,0 +1,107 @@
+// Package wavatartest provides helpers for golden image tests of avatars
+package wavatartest
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateEnv is the environment variable that makes SaveGolden rewrite golden files
+const UpdateEnv = "UPDATE_GOLDEN"
+
+// CompareImages reports whether got matches want within a tolerance.
+// Pixels whose channels differ by more than maxPerChannelDelta count as
+// differing, and an error is returned when more than maxDifferingPixels of them
+// are found. The error names the first offending coordinate and the totals.
+func CompareImages(got, want image.Image, maxPerChannelDelta uint8, maxDifferingPixels int) error {
+	if got.Bounds() != want.Bounds() {
+		return fmt.Errorf("bounds differ: got %v, want %v", got.Bounds(), want.Bounds())
+	}
+
+	var (
+		differing int
+		maxDelta  uint8
+		first     image.Point
+		firstGot  color.RGBA
+		firstWant color.RGBA
+	)
+
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			g := color.RGBAModel.Convert(got.At(x, y)).(color.RGBA)
+			w := color.RGBAModel.Convert(want.At(x, y)).(color.RGBA)
+			delta := max(absDiff(g.R, w.R), absDiff(g.G, w.G), absDiff(g.B, w.B), absDiff(g.A, w.A))
+			maxDelta = max(maxDelta, delta)
+			if delta <= maxPerChannelDelta {
+				continue
+			}
+			if differing == 0 {
+				first, firstGot, firstWant = image.Pt(x, y), g, w
+			}
+			differing++
+		}
+	}
+
+	if differing > maxDifferingPixels {
+		return fmt.Errorf("%d of %d pixels differ by more than %d (at most %d allowed, max delta %d); first at %v: got %v, want %v",
+			differing, b.Dx()*b.Dy(), maxPerChannelDelta, maxDifferingPixels, maxDelta, first, firstGot, firstWant)
+	}
+
+	return nil
+}
+
+// LoadGolden reads the PNG golden image at path, failing the test if it can't be read
+func LoadGolden(t testing.TB, path string) image.Image {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open golden file (run with %s=1 to create it): %v", UpdateEnv, err)
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode golden file %s: %v", path, err)
+	}
+
+	return img
+}
+
+// SaveGolden writes img as a PNG golden image at path when UPDATE_GOLDEN is set,
+// and does nothing otherwise
+func SaveGolden(t testing.TB, path string, img image.Image) {
+	t.Helper()
+
+	if os.Getenv(UpdateEnv) == "" {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("Failed to create golden directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Failed to create golden file: %v", err)
+	}
+	defer file.Close()
+
+	if err = png.Encode(file, img); err != nil {
+		t.Fatalf("Failed to encode golden file %s: %v", path, err)
+	}
+	t.Logf("Updated golden file %s", path)
+}
+
+// absDiff returns the absolute difference between two channel values
+func absDiff(a, b uint8) uint8 {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
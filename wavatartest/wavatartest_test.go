@@ -0,0 +1,78 @@
+package wavatartest
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newImage(c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestCompareImagesEqual(t *testing.T) {
+	a := newImage(color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	if err := CompareImages(a, a, 0, 0); err != nil {
+		t.Errorf("Expected identical images to match, got %v", err)
+	}
+}
+
+func TestCompareImagesTolerance(t *testing.T) {
+	want := newImage(color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	got := newImage(color.RGBA{R: 10, G: 20, B: 30, A: 255})
+	got.SetRGBA(1, 2, color.RGBA{R: 12, G: 20, B: 30, A: 255})
+	got.SetRGBA(3, 3, color.RGBA{R: 10, G: 90, B: 30, A: 255})
+
+	if err := CompareImages(got, want, 2, 1); err != nil {
+		t.Errorf("Expected images to match within tolerance, got %v", err)
+	}
+
+	err := CompareImages(got, want, 1, 1)
+	if err == nil {
+		t.Fatal("Expected an error when too many pixels differ")
+	}
+	if !strings.Contains(err.Error(), "2 of 16 pixels") || !strings.Contains(err.Error(), "(1,2)") {
+		t.Errorf("Expected counts and first coordinate in error, got %v", err)
+	}
+}
+
+func TestCompareImagesBounds(t *testing.T) {
+	a := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	b := image.NewRGBA(image.Rect(0, 0, 4, 5))
+	if err := CompareImages(a, b, 255, 100); err == nil {
+		t.Error("Expected an error for mismatched bounds")
+	}
+}
+
+func TestSaveAndLoadGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden", "img.png")
+	img := newImage(color.RGBA{R: 1, G: 2, B: 3, A: 255})
+
+	t.Setenv(UpdateEnv, "1")
+	SaveGolden(t, path, img)
+
+	got := LoadGolden(t, path)
+	if err := CompareImages(got, img, 0, 0); err != nil {
+		t.Errorf("Expected loaded golden to match, got %v", err)
+	}
+}
+
+func TestSaveGoldenWithoutUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "img.png")
+
+	t.Setenv(UpdateEnv, "")
+	SaveGolden(t, path, newImage(color.RGBA{A: 255}))
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("SaveGolden should not write without UPDATE_GOLDEN set")
+	}
+}
@@ -0,0 +1,464 @@
+package wavatar
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"slices"
+)
+
+// EncodeWebP writes img to w as a WebP image. A negative quality encodes it
+// losslessly. A quality from 0 to 100 first drops up to 5 bits of precision
+// from every color channel, fewer the higher the quality, and then encodes
+// losslessly, which shrinks the flat regions of avatars about as much as
+// lossy compression would. Quality 100 is lossless as well.
+func EncodeWebP(w io.Writer, img image.Image, quality float32) error {
+	if quality > 100 {
+		return fmt.Errorf("wavatar: webp quality %v is above 100", quality)
+	}
+	b := img.Bounds()
+	if b.Empty() || b.Dx() > 1<<14 || b.Dy() > 1<<14 {
+		return fmt.Errorf("wavatar: cannot encode a %dx%d image as webp", b.Dx(), b.Dy())
+	}
+
+	drop := 0
+	if quality >= 0 {
+		drop = int((100 - quality + 10) / 20)
+	}
+	data := encodeVP8L(webpPixels(img, drop), b.Dx(), b.Dy())
+
+	pad := len(data) & 1
+	header := make([]byte, 20)
+	copy(header, "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(12+len(data)+pad))
+	copy(header[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(header[16:], uint32(len(data)))
+	if pad == 1 {
+		data = append(data, 0)
+	}
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(data)
+	return err
+}
+
+// webpPixels returns the unpremultiplied pixels of img in ARGB order,
+// rounding each color channel to a multiple of 1<<drop
+func webpPixels(img image.Image, drop int) []uint32 {
+	b := img.Bounds()
+	px := make([]uint32, 0, b.Dx()*b.Dy())
+	round := func(v uint8) uint32 {
+		if drop == 0 {
+			return uint32(v)
+		}
+		return uint32(min((int(v)+1<<(drop-1))>>drop<<drop, 255))
+	}
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			if c.A == 0 {
+				c = color.NRGBA{}
+			}
+			px = append(px, uint32(c.A)<<24|round(c.R)<<16|round(c.G)<<8|round(c.B))
+		}
+	}
+	return px
+}
+
+const (
+	// vp8lLiterals, vp8lLengthCodes and vp8lDistanceCodes are the alphabet sizes of VP8L
+	vp8lLiterals      = 256
+	vp8lLengthCodes   = 24
+	vp8lDistanceCodes = 40
+	// vp8lCacheBits sizes the color cache, which holds 1<<vp8lCacheBits recent colors
+	vp8lCacheBits = 8
+	// vp8lMinMatch and vp8lMaxMatch bound the length of backward references
+	vp8lMinMatch = 3
+	vp8lMaxMatch = 4096
+	// vp8lMaxDistance is the farthest back a reference reaches, the largest distance code less the 120 plane codes
+	vp8lMaxDistance = 1<<20 - 120
+	// vp8lChainDepth is how many earlier occurrences of a pixel triple are tried as matches
+	vp8lChainDepth = 32
+)
+
+// vp8lToken is a literal pixel, a color cache hit when cache is at least
+// 0, or, when length is set, a copy of the length pixels dist back
+type vp8lToken struct {
+	argb   uint32
+	cache  int
+	length int
+	dist   int
+}
+
+// encodeVP8L returns the VP8L bitstream of the w×h ARGB pixels px. Green is
+// subtracted from red and blue, repeated runs become backward references and
+// recent colors come from the color cache, all coded with one set of Huffman codes.
+func encodeVP8L(px []uint32, w, h int) []byte {
+	px = slices.Clone(px)
+	for i, c := range px {
+		g := c >> 8 & 0xff
+		px[i] = c&0xff00ff00 | (c>>16-g)&0xff<<16 | (c-g)&0xff
+	}
+	tokens := vp8lTokens(px, w)
+
+	green := make([]int, vp8lLiterals+vp8lLengthCodes+1<<vp8lCacheBits)
+	red, blue, alpha := make([]int, 256), make([]int, 256), make([]int, 256)
+	distance := make([]int, vp8lDistanceCodes)
+	hasAlpha := false
+	for _, c := range px {
+		hasAlpha = hasAlpha || c>>24 != 0xff
+	}
+	for _, t := range tokens {
+		switch {
+		case t.length > 0:
+			code, _, _ := vp8lPrefix(t.length)
+			green[vp8lLiterals+code]++
+			code, _, _ = vp8lPrefix(vp8lDistance(t.dist, w))
+			distance[code]++
+		case t.cache >= 0:
+			green[vp8lLiterals+vp8lLengthCodes+t.cache]++
+		default:
+			green[t.argb>>8&0xff]++
+			red[t.argb>>16&0xff]++
+			blue[t.argb&0xff]++
+			alpha[t.argb>>24]++
+		}
+	}
+	codes := [5]*huffmanCode{
+		newHuffmanCode(green, 15), newHuffmanCode(red, 15), newHuffmanCode(blue, 15),
+		newHuffmanCode(alpha, 15), newHuffmanCode(distance, 15),
+	}
+
+	var bw bitWriter
+	bw.write(0x2f, 8)
+	bw.write(uint32(w-1), 14)
+	bw.write(uint32(h-1), 14)
+	if hasAlpha {
+		bw.write(1, 1)
+	} else {
+		bw.write(0, 1)
+	}
+	bw.write(0, 3) // version
+	bw.write(1, 1) // a transform follows
+	bw.write(2, 2) // subtract green
+	bw.write(0, 1) // no more transforms
+	bw.write(1, 1)
+	bw.write(vp8lCacheBits, 4)
+	bw.write(0, 1) // no meta prefix codes
+	for _, c := range codes {
+		c.writeHeader(&bw)
+	}
+
+	for _, t := range tokens {
+		switch {
+		case t.length > 0:
+			code, bits, extra := vp8lPrefix(t.length)
+			codes[0].emit(&bw, vp8lLiterals+code)
+			bw.write(extra, bits)
+			code, bits, extra = vp8lPrefix(vp8lDistance(t.dist, w))
+			codes[4].emit(&bw, code)
+			bw.write(extra, bits)
+		case t.cache >= 0:
+			codes[0].emit(&bw, vp8lLiterals+vp8lLengthCodes+t.cache)
+		default:
+			codes[0].emit(&bw, int(t.argb>>8&0xff))
+			codes[1].emit(&bw, int(t.argb>>16&0xff))
+			codes[2].emit(&bw, int(t.argb&0xff))
+			codes[3].emit(&bw, int(t.argb>>24))
+		}
+	}
+	return bw.flush()
+}
+
+// vp8lTokens greedily splits px into the longest backward references found
+// and literals, which become color cache hits where the cache holds them
+func vp8lTokens(px []uint32, w int) []vp8lToken {
+	type triple [3]uint32
+	head := make(map[triple]int)
+	prev := make([]int, len(px))
+	insert := func(i int) {
+		if i+vp8lMinMatch > len(px) {
+			return
+		}
+		key := triple{px[i], px[i+1], px[i+2]}
+		if j, ok := head[key]; ok {
+			prev[i] = j
+		} else {
+			prev[i] = -1
+		}
+		head[key] = i
+	}
+	matchLength := func(i, j int) int {
+		n := 0
+		for i+n < len(px) && n < vp8lMaxMatch && px[i+n] == px[j+n] {
+			n++
+		}
+		return n
+	}
+
+	var cache [1 << vp8lCacheBits]uint32
+	var cached [1 << vp8lCacheBits]bool
+	remember := func(c uint32) int {
+		k := int(c * 0x1e35a7bd >> (32 - vp8lCacheBits))
+		hit := cached[k] && cache[k] == c
+		cache[k], cached[k] = c, true
+		if hit {
+			return k
+		}
+		return -1
+	}
+
+	var tokens []vp8lToken
+	for i := 0; i < len(px); {
+		best, dist := 0, 0
+		// The pixel to the left and the one above have the cheapest distance codes
+		for _, d := range [2]int{1, w} {
+			if i >= d {
+				if n := matchLength(i, i-d); n > best {
+					best, dist = n, d
+				}
+			}
+		}
+		if i+vp8lMinMatch <= len(px) {
+			j, ok := head[triple{px[i], px[i+1], px[i+2]}]
+			for tries := 0; ok && j >= 0 && i-j <= vp8lMaxDistance && tries < vp8lChainDepth; tries++ {
+				if n := matchLength(i, j); n > best {
+					best, dist = n, i-j
+				}
+				j = prev[j]
+			}
+		}
+
+		if best < vp8lMinMatch {
+			best = 1
+			tokens = append(tokens, vp8lToken{argb: px[i], cache: remember(px[i])})
+		} else {
+			tokens = append(tokens, vp8lToken{cache: -1, length: best, dist: dist})
+			for k := i; k < i+best; k++ {
+				remember(px[k])
+			}
+		}
+		for k := i; k < i+best; k++ {
+			insert(k)
+		}
+		i += best
+	}
+	return tokens
+}
+
+// vp8lDistance returns the distance code of a backward reference dist
+// pixels back in an image w pixels wide
+func vp8lDistance(dist, w int) int {
+	switch dist {
+	case w:
+		return 1
+	case 1:
+		return 2
+	}
+	return dist + 120
+}
+
+// vp8lPrefix splits a length or distance code v of at least 1 into its
+// prefix code and the extra bits that follow it
+func vp8lPrefix(v int) (code int, bits uint, extra uint32) {
+	d := v - 1
+	if d < 4 {
+		return d, 0, 0
+	}
+	high := 0
+	for d>>(high+1) != 0 {
+		high++
+	}
+	second := d >> (high - 1) & 1
+	bits = uint(high - 1)
+	return 2*high + second, bits, uint32(d) & (1<<bits - 1)
+}
+
+// bitWriter packs values least significant bit first, as VP8L reads them
+type bitWriter struct {
+	buf []byte
+	acc uint64
+	n   uint
+}
+
+// write appends the low n bits of v
+func (b *bitWriter) write(v uint32, n uint) {
+	b.acc |= uint64(v) << b.n
+	b.n += n
+	for b.n >= 8 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc >>= 8
+		b.n -= 8
+	}
+}
+
+// flush pads the last byte with zeros and returns everything written
+func (b *bitWriter) flush() []byte {
+	if b.n > 0 {
+		b.buf = append(b.buf, byte(b.acc))
+		b.acc, b.n = 0, 0
+	}
+	return b.buf
+}
+
+// vp8lCodeLengthOrder is the order in which the lengths of the code length code are written
+var vp8lCodeLengthOrder = [19]int{17, 18, 0, 1, 2, 3, 4, 5, 16, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+
+// huffmanCode is a canonical Huffman code over an alphabet
+type huffmanCode struct {
+	lengths []int
+	// codes holds the bit reversed codes, ready to be written least significant bit first
+	codes []uint32
+	// used are the symbols with a code, in increasing order
+	used []int
+}
+
+// newHuffmanCode builds a canonical Huffman code for symbols occurring counts
+// times, with no code longer than limit bits
+func newHuffmanCode(counts []int, limit int) *huffmanCode {
+	c := &huffmanCode{lengths: make([]int, len(counts)), codes: make([]uint32, len(counts))}
+	for sym, n := range counts {
+		if n > 0 {
+			c.used = append(c.used, sym)
+		}
+	}
+	if len(c.used) == 0 {
+		return c
+	}
+	if len(c.used) == 1 {
+		// A lone symbol takes no bits, but still needs a length to be coded
+		c.lengths[c.used[0]] = 1
+		return c
+	}
+
+	// Halving the counts flattens the tree until it fits the limit
+	weights := slices.Clone(counts)
+	for !huffmanLengths(weights, c.used, c.lengths, limit) {
+		for _, sym := range c.used {
+			weights[sym] = (weights[sym] + 1) / 2
+		}
+	}
+
+	// Canonical codes count up through the symbols ordered by length
+	var next [16]uint32
+	code := uint32(0)
+	for l := 1; l <= limit; l++ {
+		for _, sym := range c.used {
+			if c.lengths[sym] == l {
+				next[l]++
+			}
+		}
+	}
+	start := [16]uint32{}
+	for l := 1; l <= limit; l++ {
+		code = (code + next[l-1]) << 1
+		start[l] = code
+	}
+	for _, sym := range c.used {
+		l := c.lengths[sym]
+		v := start[l]
+		start[l]++
+		for i := 0; i < l; i++ {
+			c.codes[sym] = c.codes[sym]<<1 | v>>i&1
+		}
+	}
+	return c
+}
+
+// huffmanLengths sets the code lengths of the used symbols from their
+// weights, reporting false if a code would be longer than limit
+func huffmanLengths(weights, used, lengths []int, limit int) bool {
+	type node struct{ weight, parent int }
+	nodes := make([]node, 0, 2*len(used))
+	for _, sym := range used {
+		nodes = append(nodes, node{weight: weights[sym], parent: -1})
+	}
+	// Leaves and merged nodes each sit in a queue of increasing weight
+	leaves := make([]int, len(used))
+	for i := range leaves {
+		leaves[i] = i
+	}
+	slices.SortStableFunc(leaves, func(a, b int) int { return nodes[a].weight - nodes[b].weight })
+	var merged []int
+	pop := func() int {
+		if len(merged) == 0 || len(leaves) > 0 && nodes[leaves[0]].weight <= nodes[merged[0]].weight {
+			n := leaves[0]
+			leaves = leaves[1:]
+			return n
+		}
+		n := merged[0]
+		merged = merged[1:]
+		return n
+	}
+	for len(leaves)+len(merged) > 1 {
+		a, b := pop(), pop()
+		nodes = append(nodes, node{weight: nodes[a].weight + nodes[b].weight, parent: -1})
+		nodes[a].parent, nodes[b].parent = len(nodes)-1, len(nodes)-1
+		merged = append(merged, len(nodes)-1)
+	}
+
+	for i, sym := range used {
+		depth := 0
+		for n := i; nodes[n].parent >= 0; n = nodes[n].parent {
+			depth++
+		}
+		if depth > limit {
+			return false
+		}
+		lengths[sym] = depth
+	}
+	return true
+}
+
+// emit writes the code of sym
+func (c *huffmanCode) emit(b *bitWriter, sym int) {
+	if len(c.used) > 1 {
+		b.write(c.codes[sym], uint(c.lengths[sym]))
+	}
+}
+
+// writeHeader writes the code lengths of c so a decoder can rebuild it
+func (c *huffmanCode) writeHeader(b *bitWriter) {
+	if len(c.used) <= 2 && (len(c.used) == 0 || c.used[len(c.used)-1] < 256) {
+		// A simple code lists its one or two symbols directly
+		used := c.used
+		if len(used) == 0 {
+			used = []int{0}
+		}
+		b.write(1, 1)
+		b.write(uint32(len(used)-1), 1)
+		if used[0] < 2 {
+			b.write(0, 1)
+			b.write(uint32(used[0]), 1)
+		} else {
+			b.write(1, 1)
+			b.write(uint32(used[0]), 8)
+		}
+		if len(used) == 2 {
+			b.write(uint32(used[1]), 8)
+		}
+		return
+	}
+
+	counts := make([]int, len(vp8lCodeLengthOrder))
+	for _, l := range c.lengths {
+		counts[l]++
+	}
+	lengthCode := newHuffmanCode(counts, 7)
+	n := len(vp8lCodeLengthOrder)
+	for n > 4 && lengthCode.lengths[vp8lCodeLengthOrder[n-1]] == 0 {
+		n--
+	}
+	b.write(0, 1)
+	b.write(uint32(n-4), 4)
+	for _, sym := range vp8lCodeLengthOrder[:n] {
+		b.write(uint32(lengthCode.lengths[sym]), 3)
+	}
+	b.write(0, 1) // lengths for the whole alphabet follow
+	for _, l := range c.lengths {
+		lengthCode.emit(b, l)
+	}
+}
@@ -0,0 +1,143 @@
+package wavatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+// decodeWebP encodes img at quality and decodes the result
+func decodeWebP(t *testing.T, img image.Image, quality float32) (image.Image, int) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := EncodeWebP(&buf, img, quality); err != nil {
+		t.Fatalf("Failed to encode: %v", err)
+	}
+	n := buf.Len()
+	got, err := webp.Decode(&buf)
+	if err != nil {
+		t.Fatalf("Failed to decode: %v", err)
+	}
+	return got, n
+}
+
+func TestEncodeWebPLossless(t *testing.T) {
+	hashes := []string{"test@example.com", "user1@example.com", "user7@example.com"}
+	for _, hash := range hashes {
+		img := New([]byte(hash))
+		got, size := decodeWebP(t, img, -1)
+		if _, stats, _ := DiffImage(img, got); stats.Changed != 0 {
+			t.Errorf("%s: expected a lossless round trip, %d pixels differ", hash, stats.Changed)
+		}
+
+		var p bytes.Buffer
+		if err := png.Encode(&p, img); err != nil {
+			t.Fatal(err)
+		}
+		if size >= p.Len() {
+			t.Errorf("%s: expected WebP smaller than the %d byte PNG, got %d bytes", hash, p.Len(), size)
+		}
+	}
+
+	// Transparency and odd sizes survive too
+	sticker, err := Generate([]byte("test@example.com"), WithTransparentBackground(), WithSize(33))
+	if err != nil {
+		t.Fatalf("Failed to generate avatar: %v", err)
+	}
+	got, _ := decodeWebP(t, sticker, -1)
+	if err := compareNRGBA(sticker, got, 0); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEncodeWebPNoise(t *testing.T) {
+	// Noise with few repeats exercises long codes rather than runs
+	r := rand.New(rand.NewPCG(3, 4))
+	img := image.NewNRGBA(image.Rect(0, 0, 61, 17))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(r.IntN(256))
+		if i%4 == 3 && r.IntN(4) > 0 {
+			img.Pix[i] = 255
+		}
+	}
+	got, _ := decodeWebP(t, img, -1)
+	if err := compareNRGBA(img, got, 0); err != nil {
+		t.Error(err)
+	}
+
+	// A single color is one literal followed by references to it
+	flat := color.NRGBA{R: 10, G: 20, B: 30, A: 255}
+	plain := image.NewNRGBA(image.Rect(0, 0, 100, 50))
+	draw.Draw(plain, plain.Rect, image.NewUniform(flat), image.Point{}, draw.Src)
+	got, _ = decodeWebP(t, plain, -1)
+	if c := color.NRGBAModel.Convert(got.At(99, 49)); c != flat {
+		t.Errorf("Expected %v, got %v", flat, c)
+	}
+}
+
+// compareNRGBA reports pixels of a and b whose unpremultiplied channels differ by more than tolerance
+func compareNRGBA(a, b image.Image, tolerance int) error {
+	if a.Bounds().Size() != b.Bounds().Size() {
+		return fmt.Errorf("expected size %v, got %v", a.Bounds().Size(), b.Bounds().Size())
+	}
+	ab, bb := a.Bounds(), b.Bounds()
+	for y := 0; y < ab.Dy(); y++ {
+		for x := 0; x < ab.Dx(); x++ {
+			ca := color.NRGBAModel.Convert(a.At(ab.Min.X+x, ab.Min.Y+y)).(color.NRGBA)
+			cb := color.NRGBAModel.Convert(b.At(bb.Min.X+x, bb.Min.Y+y)).(color.NRGBA)
+			if ca.A == 0 && cb.A == 0 {
+				continue
+			}
+			for i, d := range []int{int(ca.R) - int(cb.R), int(ca.G) - int(cb.G), int(ca.B) - int(cb.B), int(ca.A) - int(cb.A)} {
+				if d > tolerance || -d > tolerance {
+					return fmt.Errorf("pixel (%d,%d) channel %d: expected %v, got %v", x, y, i, ca, cb)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func TestEncodeWebPQuality(t *testing.T) {
+	img := New([]byte("test@example.com"))
+	_, lossless := decodeWebP(t, img, -1)
+	prev := 0
+	for _, quality := range []float32{100, 75, 50, 0} {
+		got, size := decodeWebP(t, img, quality)
+		// At most 5 bits are dropped, so a channel is off by at most 16
+		if err := compareNRGBA(img, got, 16); err != nil {
+			t.Errorf("Quality %v: %v", quality, err)
+		}
+		if quality == 100 && size != lossless {
+			t.Errorf("Expected quality 100 to be lossless, got %d bytes against %d", size, lossless)
+		}
+		if prev != 0 && size > prev {
+			t.Errorf("Quality %v: expected no larger than %d bytes, got %d", quality, prev, size)
+		}
+		prev = size
+	}
+	if prev >= lossless {
+		t.Errorf("Expected quality 0 smaller than the %d lossless bytes, got %d", lossless, prev)
+	}
+
+	for _, tt := range []struct {
+		img     image.Image
+		quality float32
+	}{
+		{img, 101},
+		{image.NewRGBA(image.Rect(0, 0, 0, 10)), -1},
+		{image.NewRGBA(image.Rect(0, 0, 1<<14+1, 1)), -1},
+	} {
+		if err := EncodeWebP(&bytes.Buffer{}, tt.img, tt.quality); err == nil {
+			t.Errorf("Expected an error for a %v image at quality %v", tt.img.Bounds(), tt.quality)
+		}
+	}
+}